@@ -0,0 +1,206 @@
+package benchmark
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/integrationtests/tools/simnet"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/perf"
+)
+
+// The benchmarks in this file run a client and a server on an in-memory network,
+// so they don't depend on the kernel's UDP stack.
+// The allocations reported are the allocations of both the client and the server.
+// To compare two versions of quic-go, run them multiple times, and use benchstat:
+//
+//	go test -run '^$' -bench . -count 10 ./benchmark/ > old.txt
+//	go test -run '^$' -bench . -count 10 ./benchmark/ > new.txt
+//	benchstat old.txt new.txt
+
+const (
+	smallRequestSize = 1000
+	bulkTransferSize = 10 << 20
+	datagramSize     = 1000
+	numParallel      = 100
+)
+
+// getServerConfig returns a config that doesn't perform a Retry.
+func getServerConfig() *quic.Config {
+	return &quic.Config{
+		AcceptToken:        func(_ net.Addr, _ *quic.Token) bool { return true },
+		MaxIncomingStreams: 1000,
+	}
+}
+
+func getClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		RootCAs:    testdata.GetRootCA(),
+		NextProtos: []string{perf.ALPN},
+	}
+}
+
+func newNetwork(b *testing.B, conditions ...simnet.Condition) *simnet.Network {
+	n := simnet.NewNetwork(&simnet.Opts{Conditions: conditions})
+	b.Cleanup(func() { n.Close() })
+	return n
+}
+
+func listen(b *testing.B, n *simnet.Network, conf *quic.Config) quic.Listener {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{perf.ALPN}
+	ln, err := quic.Listen(n.ServerConn(), tlsConf, conf)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+func runPerfServer(b *testing.B, n *simnet.Network) {
+	go perf.RunServer(listen(b, n, getServerConfig())) //nolint:errcheck // returns when the listener is closed
+}
+
+func dial(n *simnet.Network, conf *quic.Config) (quic.Connection, error) {
+	return quic.DialContext(context.Background(), n.ClientConn(), n.ServerConn().LocalAddr(), "localhost", getClientTLSConfig(), conf)
+}
+
+func dialAndClose(b *testing.B, n *simnet.Network, conf *quic.Config) quic.Connection {
+	conn, err := dial(n, conf)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.CloseWithError(0, "") })
+	return conn
+}
+
+func BenchmarkHandshake(b *testing.B) {
+	n := newNetwork(b)
+	runPerfServer(b, n)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conn, err := dial(n, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		conn.CloseWithError(0, "")
+	}
+}
+
+func BenchmarkSmallRequests(b *testing.B) {
+	n := newNetwork(b)
+	runPerfServer(b, n)
+	conn := dialAndClose(b, n, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := perf.Request(context.Background(), conn, smallRequestSize, smallRequestSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBulkTransfer(b *testing.B) {
+	for _, tc := range []struct {
+		name       string
+		conditions []simnet.Condition
+	}{
+		{name: "loss=0%"},
+		{name: "loss=1%", conditions: []simnet.Condition{simnet.DropRate(0.01)}},
+	} {
+		conditions := tc.conditions
+
+		b.Run(tc.name, func(b *testing.B) {
+			n := newNetwork(b, conditions...)
+			runPerfServer(b, n)
+			conn := dialAndClose(b, n, nil)
+
+			b.SetBytes(bulkTransferSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := perf.Request(context.Background(), conn, 0, bulkTransferSize); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDatagrams(b *testing.B) {
+	n := newNetwork(b)
+	serverConf := getServerConfig()
+	serverConf.EnableDatagrams = true
+	ln := listen(b, n, serverConf)
+	var received int64
+	go func() {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		for {
+			if _, err := conn.ReceiveMessage(); err != nil {
+				return
+			}
+			atomic.AddInt64(&received, 1)
+		}
+	}()
+	conn := dialAndClose(b, n, &quic.Config{EnableDatagrams: true})
+
+	msg := make([]byte, datagramSize)
+	b.SetBytes(datagramSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := conn.SendMessage(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	// Datagrams are not retransmitted. Wait until no more datagrams arrive.
+	last := atomic.LoadInt64(&received)
+	for last < int64(b.N) {
+		time.Sleep(50 * time.Millisecond)
+		r := atomic.LoadInt64(&received)
+		if r == last {
+			break
+		}
+		last = r
+	}
+	b.ReportMetric(float64(last)/float64(b.N)*100, "%delivered")
+}
+
+func BenchmarkParallelStreams(b *testing.B) {
+	n := newNetwork(b)
+	runPerfServer(b, n)
+	conn := dialAndClose(b, n, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		wg.Add(numParallel)
+		for j := 0; j < numParallel; j++ {
+			go func() {
+				defer wg.Done()
+				if _, err := perf.Request(context.Background(), conn, 0, smallRequestSize); err != nil {
+					b.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		if b.Failed() {
+			b.FailNow()
+		}
+	}
+}
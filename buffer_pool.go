@@ -1,7 +1,9 @@
 package quic
 
 import (
+	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
@@ -10,71 +12,106 @@ type packetBuffer struct {
 	Data []byte
 
 	// refCount counts how many packets Data is used in.
-	// It doesn't support concurrent use.
-	// It is > 1 when used for coalesced packet.
-	refCount int
+	// It is > 1 when used for coalesced packet,
+	// and when a batch buffer contains multiple packets.
+	// The packets of a batch are handled by different connections,
+	// so it is accessed atomically.
+	refCount int64
 }
 
 // Split increases the refCount.
 // It must be called when a packet buffer is used for more than one packet,
 // e.g. when splitting coalesced packets.
 func (b *packetBuffer) Split() {
-	b.refCount++
+	atomic.AddInt64(&b.refCount, 1)
 }
 
-// Decrement decrements the reference counter.
-// It doesn't put the buffer back into the pool.
-func (b *packetBuffer) Decrement() {
-	b.refCount--
-	if b.refCount < 0 {
+// Release decrements the refCount.
+// The packet buffer is put back into the pool once all packets using it were released.
+func (b *packetBuffer) Release() {
+	refCount := atomic.AddInt64(&b.refCount, -1)
+	if refCount < 0 {
 		panic("negative packetBuffer refCount")
 	}
-}
-
-// MaybeRelease puts the packet buffer back into the pool,
-// if the reference counter already reached 0.
-func (b *packetBuffer) MaybeRelease() {
-	// only put the packetBuffer back if it's not used any more
-	if b.refCount == 0 {
+	if refCount == 0 {
 		b.putBack()
 	}
 }
 
-// Release puts back the packet buffer into the pool.
-// It should be called when processing is definitely finished.
-func (b *packetBuffer) Release() {
-	b.Decrement()
-	if b.refCount != 0 {
-		panic("packetBuffer refCount not zero")
-	}
-	b.putBack()
-}
-
 // Len returns the length of Data
 func (b *packetBuffer) Len() protocol.ByteCount {
 	return protocol.ByteCount(len(b.Data))
 }
 
 func (b *packetBuffer) putBack() {
-	if cap(b.Data) != int(protocol.MaxPacketBufferSize) {
+	pool := poolForSize(protocol.ByteCount(cap(b.Data)))
+	if pool == nil || pool.size != protocol.ByteCount(cap(b.Data)) {
 		panic("putPacketBuffer called with packet of wrong size!")
 	}
-	bufferPool.Put(b)
+	atomic.AddInt64(&numBuffersInUse, -1)
+	pool.Put(b)
 }
 
-var bufferPool sync.Pool
+const (
+	// largePacketBufferSize is large enough for a packet sent on a path that supports jumbo frames (9000 bytes MTU).
+	largePacketBufferSize protocol.ByteCount = 9000
+	// batchBufferSize is large enough for a batch of packets read from the socket at once.
+	// It also fits a GSO / GRO batch, which the kernel limits to 64 KB.
+	batchBufferSize protocol.ByteCount = 1 << 16
+)
 
-func getPacketBuffer() *packetBuffer {
-	buf := bufferPool.Get().(*packetBuffer)
-	buf.refCount = 1
-	buf.Data = buf.Data[:0]
-	return buf
+type sizedBufferPool struct {
+	sync.Pool
+	size protocol.ByteCount
+}
+
+// The size classes, in ascending order.
+// A buffer is taken from the smallest size class that fits the requested size.
+var bufferPools = [...]*sizedBufferPool{
+	newSizedBufferPool(protocol.MaxPacketBufferSize),
+	newSizedBufferPool(largePacketBufferSize),
+	newSizedBufferPool(batchBufferSize),
 }
 
-func init() {
-	bufferPool.New = func() interface{} {
-		return &packetBuffer{
-			Data: make([]byte, 0, protocol.MaxPacketBufferSize),
+// numBuffersInUse counts the buffers that were taken from the pool, but not put back yet.
+// It is used to detect leaked buffers in tests.
+var numBuffersInUse int64
+
+func newSizedBufferPool(size protocol.ByteCount) *sizedBufferPool {
+	p := &sizedBufferPool{size: size}
+	p.New = func() interface{} {
+		return &packetBuffer{Data: make([]byte, 0, size)}
+	}
+	return p
+}
+
+// poolForSize returns the pool of the smallest size class that fits size.
+// It returns nil if size is larger than the largest size class.
+func poolForSize(size protocol.ByteCount) *sizedBufferPool {
+	for _, p := range bufferPools {
+		if size <= p.size {
+			return p
 		}
 	}
+	return nil
+}
+
+// getPacketBuffer returns a buffer that fits a single packet of up to protocol.MaxPacketBufferSize bytes.
+func getPacketBuffer() *packetBuffer {
+	return getPacketBufferWithSize(protocol.MaxPacketBufferSize)
+}
+
+// getPacketBufferWithSize returns a buffer with a capacity of at least size bytes.
+// The capacity of the buffer is the size of the smallest size class that fits size.
+// When multiple packets are processed from the same buffer, Split must be called for every additional packet.
+func getPacketBufferWithSize(size protocol.ByteCount) *packetBuffer {
+	pool := poolForSize(size)
+	if pool == nil {
+		panic(fmt.Sprintf("requested a packet buffer of %d bytes, larger than the largest size class (%d bytes)", size, batchBufferSize))
+	}
+	buf := pool.Get().(*packetBuffer)
+	atomic.StoreInt64(&buf.refCount, 1)
+	buf.Data = buf.Data[:0]
+	atomic.AddInt64(&numBuffersInUse, 1)
+	return buf
 }
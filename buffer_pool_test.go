@@ -1,20 +1,60 @@
 package quic
 
 import (
+	"sync"
+	"sync/atomic"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+// expectNoBufferLeaks checks that all buffers taken from the buffer pool
+// during a test were put back when the test completes.
+// It must be called in the body of a Describe or a Context.
+func expectNoBufferLeaks() {
+	var numBuffers int64
+	BeforeEach(func() { numBuffers = atomic.LoadInt64(&numBuffersInUse) })
+	AfterEach(func() {
+		Eventually(func() int64 { return atomic.LoadInt64(&numBuffersInUse) }).Should(Equal(numBuffers))
+	})
+}
+
 var _ = Describe("Buffer Pool", func() {
+	expectNoBufferLeaks()
+
 	It("returns buffers of cap", func() {
 		buf := getPacketBuffer()
 		Expect(buf.Data).To(HaveCap(int(protocol.MaxPacketBufferSize)))
+		buf.Release()
+	})
+
+	It("uses size classes", func() {
+		for _, tc := range []struct {
+			size, cap protocol.ByteCount
+		}{
+			{size: 1, cap: protocol.MaxPacketBufferSize},
+			{size: protocol.MaxPacketBufferSize, cap: protocol.MaxPacketBufferSize},
+			{size: protocol.MaxPacketBufferSize + 1, cap: largePacketBufferSize},
+			{size: largePacketBufferSize, cap: largePacketBufferSize},
+			{size: largePacketBufferSize + 1, cap: batchBufferSize},
+			{size: batchBufferSize, cap: batchBufferSize},
+		} {
+			buf := getPacketBufferWithSize(tc.size)
+			Expect(buf.Data).To(BeEmpty())
+			Expect(buf.Data).To(HaveCap(int(tc.cap)))
+			buf.Release()
+		}
+	})
+
+	It("panics if a buffer larger than the largest size class is requested", func() {
+		Expect(func() { getPacketBufferWithSize(batchBufferSize + 1) }).To(Panic())
 	})
 
 	It("releases buffers", func() {
 		buf := getPacketBuffer()
+		Expect(atomic.LoadInt64(&numBuffersInUse)).To(BeNumerically(">", 0))
 		buf.Release()
 	})
 
@@ -22,12 +62,17 @@ var _ = Describe("Buffer Pool", func() {
 		buf := getPacketBuffer()
 		buf.Data = append(buf.Data, []byte("foobar")...)
 		Expect(buf.Len()).To(BeEquivalentTo(6))
+		buf.Release()
 	})
 
 	It("panics if wrong-sized buffers are passed", func() {
 		buf := getPacketBuffer()
+		data := buf.Data
 		buf.Data = make([]byte, 10)
 		Expect(func() { buf.Release() }).To(Panic())
+		// put back the buffer, so that the leak check succeeds
+		buf.Data = data
+		buf.putBack()
 	})
 
 	It("panics if it is released twice", func() {
@@ -36,20 +81,36 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(func() { buf.Release() }).To(Panic())
 	})
 
-	It("panics if it is decremented too many times", func() {
-		buf := getPacketBuffer()
-		buf.Decrement()
-		Expect(func() { buf.Decrement() }).To(Panic())
-	})
-
 	It("waits until all parts have been released", func() {
 		buf := getPacketBuffer()
 		buf.Split()
 		buf.Split()
 		// now we have 3 parts
-		buf.Decrement()
-		buf.Decrement()
-		buf.Decrement()
-		Expect(func() { buf.Decrement() }).To(Panic())
+		for i := 0; i < 3; i++ {
+			numBuffers := atomic.LoadInt64(&numBuffersInUse)
+			buf.Release()
+			if i < 2 {
+				Expect(atomic.LoadInt64(&numBuffersInUse)).To(Equal(numBuffers))
+			} else {
+				Expect(atomic.LoadInt64(&numBuffersInUse)).To(Equal(numBuffers - 1))
+			}
+		}
+	})
+
+	It("releases batch buffers when all packets have been processed concurrently", func() {
+		const numPackets = 10
+		buf := getPacketBufferWithSize(batchBufferSize)
+		for i := 1; i < numPackets; i++ {
+			buf.Split()
+		}
+		var wg sync.WaitGroup
+		wg.Add(numPackets)
+		for i := 0; i < numPackets; i++ {
+			go func() {
+				defer wg.Done()
+				buf.Release()
+			}()
+		}
+		wg.Wait()
 	})
 })
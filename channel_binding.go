@@ -0,0 +1,15 @@
+package quic
+
+// The exporter label and length of the tls-exporter channel binding, see section 2 of RFC 9266.
+const (
+	tlsExporterChannelBindingLabel  = "EXPORTER-Channel-Binding"
+	tlsExporterChannelBindingLength = 32
+)
+
+// TLSExporterChannelBinding returns the tls-exporter channel binding (RFC 9266) of a QUIC connection.
+// Both endpoints of the connection derive the same value, and it is unique to the connection.
+// It can be used to bind application-level authentication to the QUIC connection.
+// It returns an error if the handshake hasn't completed yet.
+func TLSExporterChannelBinding(conn Connection) ([]byte, error) {
+	return conn.ExportKeyingMaterial(tlsExporterChannelBindingLabel, []byte{}, tlsExporterChannelBindingLength)
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"strings"
 
+	"github.com/lucas-clemente/quic-go/internal/happyeyeballs"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/logging"
@@ -51,6 +52,9 @@ var (
 // DialAddr establishes a new QUIC connection to a server.
 // It uses a new UDP connection and closes this connection when the QUIC connection is closed.
 // The hostname for SNI is taken from the given address.
+// If the host resolves to multiple IP addresses, connection attempts are raced as described in RFC 8305
+// ("Happy Eyeballs"): IPv6 addresses are tried first, and the attempts are started in a staggered manner.
+// As soon as one handshake completes, the other connection attempts are canceled.
 // The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
 func DialAddr(
 	addr string,
@@ -63,6 +67,7 @@ func DialAddr(
 // DialAddrEarly establishes a new 0-RTT QUIC connection to a server.
 // It uses a new UDP connection and closes this connection when the QUIC connection is closed.
 // The hostname for SNI is taken from the given address.
+// Like DialAddr, it races connection attempts if the host resolves to multiple IP addresses.
 // The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
 func DialAddrEarly(
 	addr string,
@@ -106,15 +111,29 @@ func dialAddrContext(
 	config *Config,
 	use0RTT bool,
 ) (quicConn, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, err
 	}
-	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	udpAddrs, err := happyeyeballs.Resolve(ctx, host, port)
 	if err != nil {
 		return nil, err
 	}
-	return dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, use0RTT, true)
+	// If the host has multiple addresses (e.g. both an IPv4 and an IPv6 address),
+	// race the connection attempts, and use the first connection that's established.
+	conn, err := happyeyeballs.Dial(ctx, udpAddrs, func(ctx context.Context, udpAddr *net.UDPAddr) (interface{}, error) {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+		if err != nil {
+			return nil, err
+		}
+		return dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, use0RTT, true)
+	}, func(conn interface{}) {
+		conn.(quicConn).CloseWithError(0, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conn.(quicConn), nil
 }
 
 // Dial establishes a new QUIC connection to a server using a net.PacketConn. If
@@ -256,7 +275,7 @@ func newClient(
 		}
 	}
 
-	srcConnID, err := generateConnectionID(config.ConnectionIDLength)
+	srcConnID, err := config.generateConnectionID()
 	if err != nil {
 		return nil, err
 	}
@@ -267,7 +286,7 @@ func newClient(
 	c := &client{
 		srcConnID:         srcConnID,
 		destConnID:        destConnID,
-		sconn:             newSendPconn(pconn, remoteAddr),
+		sconn:             newSendPconn(pconn, remoteAddr, config.DSCP),
 		createdPacketConn: createdPacketConn,
 		use0RTT:           use0RTT,
 		tlsConf:           tlsConf,
@@ -64,7 +64,7 @@ var _ = Describe("Client", func() {
 			srcConnID:  connID,
 			destConnID: connID,
 			version:    protocol.VersionTLS,
-			sconn:      newSendPconn(packetConn, addr),
+			sconn:      newSendPconn(packetConn, addr, 0),
 			tracer:     tracer,
 			logger:     utils.DefaultLogger,
 		}
@@ -141,7 +141,8 @@ var _ = Describe("Client", func() {
 			}
 			_, err := DialAddr("localhost:17890", tlsConf, &Config{HandshakeIdleTimeout: time.Millisecond})
 			Expect(err).ToNot(HaveOccurred())
-			Eventually(remoteAddrChan).Should(Receive(Equal("127.0.0.1:17890")))
+			// IPv6 addresses are dialed first, if localhost resolves to both ::1 and 127.0.0.1
+			Eventually(remoteAddrChan).Should(Receive(Or(Equal("127.0.0.1:17890"), Equal("[::1]:17890"))))
 		})
 
 		It("uses the tls.Config.ServerName as the hostname, if present", func() {
@@ -4,9 +4,9 @@ import (
 	"errors"
 	"time"
 
-	"github.com/lucas-clemente/quic-go/internal/utils"
-
+	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // Clone clones a Config
@@ -19,16 +19,49 @@ func (c *Config) handshakeTimeout() time.Duration {
 	return utils.MaxDuration(protocol.DefaultHandshakeTimeout, 2*c.HandshakeIdleTimeout)
 }
 
+func (c *Config) generateConnectionID() (protocol.ConnectionID, error) {
+	if c.ConnectionIDGenerator != nil {
+		return c.ConnectionIDGenerator.GenerateConnectionID()
+	}
+	return generateConnectionID(c.ConnectionIDLength)
+}
+
 func validateConfig(config *Config) error {
 	if config == nil {
 		return nil
 	}
+	if config.ConnectionIDGenerator != nil {
+		if l := config.ConnectionIDGenerator.ConnectionIDLen(); l != 0 && (l < 4 || l > 18) {
+			return errors.New("invalid connection ID length for Config.ConnectionIDGenerator")
+		}
+	}
+	if len(config.RetryOffloadKey) != 0 && len(config.RetryOffloadKey) != handshake.RetryOffloadTokenKeyLen {
+		return errors.New("invalid length for Config.RetryOffloadKey")
+	}
+	if config.CongestionControl > CongestionControlCubic {
+		return errors.New("invalid value for Config.CongestionControl")
+	}
 	if config.MaxIncomingStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingStreams")
 	}
 	if config.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if config.DSCP > 63 {
+		return errors.New("invalid value for Config.DSCP")
+	}
+	return nil
+}
+
+// validateServerConfig validates a config used by a server.
+// Contrary to a client, a server can't use zero-length connection IDs.
+func validateServerConfig(config *Config) error {
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	if config != nil && config.ConnectionIDGenerator != nil && config.ConnectionIDGenerator.ConnectionIDLen() == 0 {
+		return errors.New("invalid connection ID length for Config.ConnectionIDGenerator")
+	}
 	return nil
 }
 
@@ -36,7 +69,9 @@ func validateConfig(config *Config) error {
 // it may be called with nil
 func populateServerConfig(config *Config) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 {
+	if config.ConnectionIDGenerator != nil {
+		config.ConnectionIDLength = config.ConnectionIDGenerator.ConnectionIDLen()
+	} else if config.ConnectionIDLength == 0 {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
 	if config.AcceptToken == nil {
@@ -49,7 +84,9 @@ func populateServerConfig(config *Config) *Config {
 // it may be called with nil
 func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 && !createdPacketConn {
+	if config.ConnectionIDGenerator != nil {
+		config.ConnectionIDLength = config.ConnectionIDGenerator.ConnectionIDLen()
+	} else if config.ConnectionIDLength == 0 && !createdPacketConn {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
 	return config
@@ -87,6 +124,10 @@ func populateConfig(config *Config) *Config {
 	if maxConnectionReceiveWindow == 0 {
 		maxConnectionReceiveWindow = protocol.DefaultMaxReceiveConnectionFlowControlWindow
 	}
+	streamSendBufferSize := config.StreamSendBufferSize
+	if streamSendBufferSize == 0 {
+		streamSendBufferSize = uint64(protocol.DefaultStreamSendBufferSize)
+	}
 	maxIncomingStreams := config.MaxIncomingStreams
 	if maxIncomingStreams == 0 {
 		maxIncomingStreams = protocol.DefaultMaxIncomingStreams
@@ -105,20 +146,31 @@ func populateConfig(config *Config) *Config {
 		HandshakeIdleTimeout:             handshakeIdleTimeout,
 		MaxIdleTimeout:                   idleTimeout,
 		AcceptToken:                      config.AcceptToken,
+		RetryOffloadKey:                  config.RetryOffloadKey,
+		GetSessionTicketAppData:          config.GetSessionTicketAppData,
+		Allow0RTT:                        config.Allow0RTT,
 		KeepAlive:                        config.KeepAlive,
 		InitialStreamReceiveWindow:       initialStreamReceiveWindow,
 		MaxStreamReceiveWindow:           maxStreamReceiveWindow,
 		InitialConnectionReceiveWindow:   initialConnectionReceiveWindow,
 		MaxConnectionReceiveWindow:       maxConnectionReceiveWindow,
 		AllowConnectionWindowIncrease:    config.AllowConnectionWindowIncrease,
+		StreamSendBufferSize:             streamSendBufferSize,
+		CoalesceStreamWrites:             config.CoalesceStreamWrites,
+		Replay0RTT:                       config.Replay0RTT,
 		MaxIncomingStreams:               maxIncomingStreams,
 		MaxIncomingUniStreams:            maxIncomingUniStreams,
 		ConnectionIDLength:               config.ConnectionIDLength,
+		ConnectionIDGenerator:            config.ConnectionIDGenerator,
 		StatelessResetKey:                config.StatelessResetKey,
 		TokenStore:                       config.TokenStore,
 		EnableDatagrams:                  config.EnableDatagrams,
 		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
 		DisableVersionNegotiationPackets: config.DisableVersionNegotiationPackets,
+		CongestionControl:                config.CongestionControl,
+		ShareCongestionControl:           config.ShareCongestionControl,
+		EventLoopWorkers:                 config.EventLoopWorkers,
+		DSCP:                             config.DSCP,
 		Tracer:                           config.Tracer,
 	}
 }
@@ -13,6 +13,18 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type mockConnIDGenerator struct {
+	ConnID protocol.ConnectionID
+}
+
+func (g *mockConnIDGenerator) GenerateConnectionID() (protocol.ConnectionID, error) {
+	return g.ConnID, nil
+}
+
+func (g *mockConnIDGenerator) ConnectionIDLen() int {
+	return g.ConnID.Len()
+}
+
 var _ = Describe("Config", func() {
 	Context("validating", func() {
 		It("validates a nil config", func() {
@@ -27,9 +39,36 @@ var _ = Describe("Config", func() {
 			Expect(validateConfig(&Config{MaxIncomingStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingStreams"))
 		})
 
+		It("errors on invalid congestion control algorithms", func() {
+			Expect(validateConfig(&Config{CongestionControl: 42})).To(MatchError("invalid value for Config.CongestionControl"))
+		})
+
 		It("errors on too large values for MaxIncomingUniStreams", func() {
 			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
 		})
+
+		It("errors on invalid DSCP values", func() {
+			Expect(validateConfig(&Config{DSCP: 63})).To(Succeed())
+			Expect(validateConfig(&Config{DSCP: 64})).To(MatchError("invalid value for Config.DSCP"))
+		})
+
+		It("errors on invalid connection ID lengths of the ConnectionIDGenerator", func() {
+			conf := &Config{ConnectionIDGenerator: &mockConnIDGenerator{ConnID: protocol.ConnectionID{1, 2, 3}}}
+			Expect(validateConfig(conf)).To(MatchError("invalid connection ID length for Config.ConnectionIDGenerator"))
+		})
+
+		It("errors on zero-length connection IDs for servers", func() {
+			conf := &Config{ConnectionIDGenerator: &mockConnIDGenerator{}}
+			Expect(validateConfig(conf)).To(Succeed())
+			Expect(validateServerConfig(conf)).To(MatchError("invalid connection ID length for Config.ConnectionIDGenerator"))
+			Expect(validateServerConfig(nil)).To(Succeed())
+			Expect(validateServerConfig(&Config{ConnectionIDGenerator: &mockConnIDGenerator{ConnID: protocol.ConnectionID{1, 2, 3, 4}}})).To(Succeed())
+		})
+
+		It("errors on invalid lengths of the RetryOffloadKey", func() {
+			Expect(validateConfig(&Config{RetryOffloadKey: []byte("foobar")})).To(MatchError("invalid length for Config.RetryOffloadKey"))
+			Expect(validateConfig(&Config{RetryOffloadKey: make([]byte, 16)})).To(Succeed())
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -45,12 +84,14 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter", "AllowConnectionWindowIncrease":
+			case "AcceptToken", "GetLogWriter", "AllowConnectionWindowIncrease", "Replay0RTT", "GetSessionTicketAppData", "Allow0RTT":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
 			case "ConnectionIDLength":
 				f.Set(reflect.ValueOf(8))
+			case "ConnectionIDGenerator":
+				f.Set(reflect.ValueOf(&mockConnIDGenerator{ConnID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}}))
 			case "HandshakeIdleTimeout":
 				f.Set(reflect.ValueOf(time.Second))
 			case "MaxIdleTimeout":
@@ -65,10 +106,16 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(uint64(4321)))
 			case "MaxConnectionReceiveWindow":
 				f.Set(reflect.ValueOf(uint64(10)))
+			case "StreamSendBufferSize":
+				f.Set(reflect.ValueOf(uint64(5678)))
+			case "CoalesceStreamWrites":
+				f.Set(reflect.ValueOf(true))
 			case "MaxIncomingStreams":
 				f.Set(reflect.ValueOf(int64(11)))
 			case "MaxIncomingUniStreams":
 				f.Set(reflect.ValueOf(int64(12)))
+			case "RetryOffloadKey":
+				f.Set(reflect.ValueOf([]byte("0123456789abcdef")))
 			case "StatelessResetKey":
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
 			case "KeepAlive":
@@ -79,6 +126,14 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(true))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
+			case "CongestionControl":
+				f.Set(reflect.ValueOf(CongestionControlCubic))
+			case "ShareCongestionControl":
+				f.Set(reflect.ValueOf(true))
+			case "EventLoopWorkers":
+				f.Set(reflect.ValueOf(4))
+			case "DSCP":
+				f.Set(reflect.ValueOf(uint8(46)))
 			case "Tracer":
 				f.Set(reflect.ValueOf(mocklogging.NewMockTracer(mockCtrl)))
 			default:
@@ -100,16 +155,25 @@ var _ = Describe("Config", func() {
 
 	Context("cloning", func() {
 		It("clones function fields", func() {
-			var calledAcceptToken, calledAllowConnectionWindowIncrease bool
+			var calledAcceptToken, calledAllowConnectionWindowIncrease, calledReplay0RTT, calledGetSessionTicketAppData, calledAllow0RTT bool
 			c1 := &Config{
 				AcceptToken:                   func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
 				AllowConnectionWindowIncrease: func(Connection, uint64) bool { calledAllowConnectionWindowIncrease = true; return true },
+				Replay0RTT:                    func(StreamID) bool { calledReplay0RTT = true; return true },
+				GetSessionTicketAppData:       func(Connection) []byte { calledGetSessionTicketAppData = true; return nil },
+				Allow0RTT:                     func([]byte) bool { calledAllow0RTT = true; return true },
 			}
 			c2 := c1.Clone()
 			c2.AcceptToken(&net.UDPAddr{}, &Token{})
 			Expect(calledAcceptToken).To(BeTrue())
 			c2.AllowConnectionWindowIncrease(nil, 1234)
 			Expect(calledAllowConnectionWindowIncrease).To(BeTrue())
+			c2.Replay0RTT(4)
+			Expect(calledReplay0RTT).To(BeTrue())
+			c2.GetSessionTicketAppData(nil)
+			Expect(calledGetSessionTicketAppData).To(BeTrue())
+			c2.Allow0RTT(nil)
+			Expect(calledAllow0RTT).To(BeTrue())
 		})
 
 		It("clones non-function fields", func() {
@@ -155,6 +219,8 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxStreamReceiveWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveStreamFlowControlWindow))
 			Expect(c.InitialConnectionReceiveWindow).To(BeEquivalentTo(protocol.DefaultInitialMaxData))
 			Expect(c.MaxConnectionReceiveWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveConnectionFlowControlWindow))
+			Expect(c.StreamSendBufferSize).To(BeEquivalentTo(protocol.DefaultStreamSendBufferSize))
+			Expect(c.CoalesceStreamWrites).To(BeFalse())
 			Expect(c.MaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
 			Expect(c.DisableVersionNegotiationPackets).To(BeFalse())
@@ -176,5 +242,16 @@ var _ = Describe("Config", func() {
 			c := populateClientConfig(&Config{}, true)
 			Expect(c.ConnectionIDLength).To(BeZero())
 		})
+
+		It("uses the connection ID length of the ConnectionIDGenerator", func() {
+			gen := &mockConnIDGenerator{ConnID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}}
+			c := populateServerConfig(&Config{ConnectionIDGenerator: gen})
+			Expect(c.ConnectionIDLength).To(Equal(9))
+			connID, err := c.generateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID).To(Equal(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}))
+			c = populateClientConfig(&Config{ConnectionIDGenerator: gen}, true)
+			Expect(c.ConnectionIDLength).To(Equal(9))
+		})
 	})
 })
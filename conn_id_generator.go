@@ -2,6 +2,8 @@ package quic
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -10,9 +12,13 @@ import (
 )
 
 type connIDGenerator struct {
-	connIDLen  int
-	highestSeq uint64
+	connIDLen      int
+	highestSeq     uint64
+	generateConnID func() (protocol.ConnectionID, error)
 
+	// mutex protects activeSrcConnIDs.
+	// It is only modified by the run loop, but read by ActiveConnectionIDs.
+	mutex                   sync.Mutex
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID protocol.ConnectionID
 
@@ -35,10 +41,12 @@ func newConnIDGenerator(
 	retireConnectionID func(protocol.ConnectionID),
 	replaceWithClosed func(protocol.ConnectionID, packetHandler),
 	queueControlFrame func(wire.Frame),
+	generateConnID func() (protocol.ConnectionID, error),
 	version protocol.VersionNumber,
 ) *connIDGenerator {
 	m := &connIDGenerator{
 		connIDLen:              initialConnectionID.Len(),
+		generateConnID:         generateConnID,
 		activeSrcConnIDs:       make(map[uint64]protocol.ConnectionID),
 		addConnectionID:        addConnectionID,
 		getStatelessResetToken: getStatelessResetToken,
@@ -90,7 +98,9 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 		}
 	}
 	m.retireConnectionID(connID)
+	m.mutex.Lock()
 	delete(m.activeSrcConnIDs, seq)
+	m.mutex.Unlock()
 	// Don't issue a replacement for the initial connection ID.
 	if seq == 0 {
 		return nil
@@ -99,11 +109,13 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 }
 
 func (m *connIDGenerator) issueNewConnID() error {
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	connID, err := m.generateConnID()
 	if err != nil {
 		return err
 	}
+	m.mutex.Lock()
 	m.activeSrcConnIDs[m.highestSeq+1] = connID
+	m.mutex.Unlock()
 	m.addConnectionID(connID)
 	m.queueControlFrame(&wire.NewConnectionIDFrame{
 		SequenceNumber:      m.highestSeq + 1,
@@ -114,6 +126,25 @@ func (m *connIDGenerator) issueNewConnID() error {
 	return nil
 }
 
+// ActiveConnectionIDs returns the connection IDs that the peer can use to address this connection,
+// ordered by their sequence number.
+// It is safe to call it concurrently with the run loop.
+func (m *connIDGenerator) ActiveConnectionIDs() []protocol.ConnectionID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	seqs := make([]uint64, 0, len(m.activeSrcConnIDs))
+	for seq := range m.activeSrcConnIDs {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	connIDs := make([]protocol.ConnectionID, 0, len(seqs))
+	for _, seq := range seqs {
+		connIDs = append(connIDs, m.activeSrcConnIDs[seq])
+	}
+	return connIDs
+}
+
 func (m *connIDGenerator) SetHandshakeComplete() {
 	if m.initialClientDestConnID != nil {
 		m.retireConnectionID(m.initialClientDestConnID)
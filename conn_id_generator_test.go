@@ -42,6 +42,7 @@ var _ = Describe("Connection ID Generator", func() {
 			func(c protocol.ConnectionID) { retiredConnIDs = append(retiredConnIDs, c) },
 			func(c protocol.ConnectionID, h packetHandler) { replacedWithClosed[string(c)] = h },
 			func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+			func() (protocol.ConnectionID, error) { return protocol.GenerateConnectionID(initialConnID.Len()) },
 			protocol.VersionDraft29,
 		)
 	})
@@ -132,6 +133,20 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(nf.ConnectionID.Len()).To(Equal(7))
 	})
 
+	It("returns the active connection IDs", func() {
+		Expect(g.ActiveConnectionIDs()).To(Equal([]protocol.ConnectionID{initialConnID}))
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(3))
+		Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
+		Expect(g.Retire(2, protocol.ConnectionID{})).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(4))
+		connIDs := g.ActiveConnectionIDs()
+		Expect(connIDs).To(HaveLen(3))
+		Expect(connIDs[0]).To(Equal(queuedFrames[0].(*wire.NewConnectionIDFrame).ConnectionID))
+		Expect(connIDs[1]).To(Equal(queuedFrames[2].(*wire.NewConnectionIDFrame).ConnectionID))
+		Expect(connIDs[2]).To(Equal(queuedFrames[3].(*wire.NewConnectionIDFrame).ConnectionID))
+	})
+
 	It("retires the initial connection ID", func() {
 		Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
 		Expect(removedConnIDs).To(BeEmpty())
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -15,9 +16,13 @@ type connIDManager struct {
 	handshakeComplete         bool
 	activeSequenceNumber      uint64
 	highestRetired            uint64
-	activeConnectionID        protocol.ConnectionID
 	activeStatelessResetToken *protocol.StatelessResetToken
 
+	// mutex protects activeConnectionID.
+	// It is only modified by the run loop, but read by ActiveConnectionID.
+	mutex              sync.Mutex
+	activeConnectionID protocol.ConnectionID
+
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
 	// hide the packet loss rate from on-path observers.
@@ -145,7 +150,9 @@ func (h *connIDManager) updateConnectionID() {
 
 	front := h.queue.Remove(h.queue.Front())
 	h.activeSequenceNumber = front.SequenceNumber
+	h.mutex.Lock()
 	h.activeConnectionID = front.ConnectionID
+	h.mutex.Unlock()
 	h.activeStatelessResetToken = &front.StatelessResetToken
 	h.packetsSinceLastChange = 0
 	h.packetsPerConnectionID = protocol.PacketsPerConnectionID/2 + uint32(h.rand.Int31n(protocol.PacketsPerConnectionID))
@@ -164,7 +171,9 @@ func (h *connIDManager) ChangeInitialConnID(newConnID protocol.ConnectionID) {
 	if h.activeSequenceNumber != 0 {
 		panic("expected first connection ID to have sequence number 0")
 	}
+	h.mutex.Lock()
 	h.activeConnectionID = newConnID
+	h.mutex.Unlock()
 }
 
 // is called when the server provides a stateless reset token in the transport parameters
@@ -202,6 +211,15 @@ func (h *connIDManager) Get() protocol.ConnectionID {
 	return h.activeConnectionID
 }
 
+// ActiveConnectionID returns the connection ID currently used to address the peer.
+// Unlike Get, it doesn't switch to a new connection ID,
+// and it is safe to call it concurrently with the run loop.
+func (h *connIDManager) ActiveConnectionID() protocol.ConnectionID {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.activeConnectionID
+}
+
 func (h *connIDManager) SetHandshakeComplete() {
 	h.handshakeComplete = true
 }
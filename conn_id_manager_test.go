@@ -247,6 +247,18 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
 	})
 
+	It("returns the active connection ID without initiating a connection ID update", func() {
+		m.SetHandshakeComplete()
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ConnectionID{1, 2, 3, 4},
+			StatelessResetToken: protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+		})).To(Succeed())
+		Expect(m.ActiveConnectionID()).To(Equal(initialConnID))
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(m.ActiveConnectionID()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+	})
+
 	It("waits until handshake completion before initiating a connection ID update", func() {
 		Expect(m.Get()).To(Equal(initialConnID))
 		Expect(m.Add(&wire.NewConnectionIDFrame{
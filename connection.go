@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/logutils"
@@ -48,6 +49,7 @@ type streamManager interface {
 	CloseWithError(error)
 	ResetFor0RTT()
 	UseResetMaps()
+	Drop0RTTReplayData()
 }
 
 type cryptoStreamHandler interface {
@@ -55,7 +57,7 @@ type cryptoStreamHandler interface {
 	ChangeConnectionID(protocol.ConnectionID)
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	SetHandshakeConfirmed()
-	GetSessionTicket() ([]byte, error)
+	GetSessionTicket(appData []byte) ([]byte, error)
 	io.Closer
 	ConnectionState() handshake.ConnectionState
 }
@@ -130,6 +132,19 @@ func (e *errCloseForRecreating) Error() string {
 var connTracingID uint64        // to be accessed atomically
 func nextConnTracingID() uint64 { return atomic.AddUint64(&connTracingID, 1) }
 
+// The connectionTimer is implemented by the utils.Timer and the loopTimer.
+type connectionTimer interface {
+	Chan() <-chan time.Time
+	Reset(time.Time)
+	SetRead()
+	Stop()
+}
+
+var (
+	_ connectionTimer = &utils.Timer{}
+	_ connectionTimer = &loopTimer{}
+)
+
 // A Connection is a QUIC connection
 type connection struct {
 	// Destination connection ID used during the handshake.
@@ -153,6 +168,8 @@ type connection struct {
 	connIDGenerator *connIDGenerator
 
 	rttStats *utils.RTTStats
+	// only set if Config.ShareCongestionControl is set
+	congestionGroupMember *congestion.GroupMember
 
 	cryptoStreamManager   *cryptoStreamManager
 	sentPacketHandler     ackhandler.SentPacketHandler
@@ -163,6 +180,7 @@ type connection struct {
 	connFlowController    flowcontrol.ConnectionFlowController
 	tokenStoreKey         string                    // only set for the client
 	tokenGenerator        *handshake.TokenGenerator // only set for the server
+	enable0RTT            bool                      // only set for the server
 
 	unpacker      unpacker
 	frameParser   wire.FrameParser
@@ -172,8 +190,13 @@ type connection struct {
 	oneRTTStream        cryptoStream // only set for the server
 	cryptoStreamHandler cryptoStreamHandler
 
-	receivedPackets  chan *receivedPacket
-	sendingScheduled chan struct{}
+	receivedPackets    chan *receivedPacket
+	sendingScheduled   chan struct{}
+	sendQueueAvailable <-chan struct{}
+
+	// loopTask is set if the connection is driven by an event loop.
+	// In that case, the connection doesn't run a goroutine of its own.
+	loopTask *loopTask
 
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
@@ -209,13 +232,19 @@ type connection struct {
 
 	peerParams *wire.TransportParameters
 
-	timer *utils.Timer
+	// connStateMutex protects connState.
+	// connState holds the values of the ConnectionState that are updated by the run loop.
+	connStateMutex sync.Mutex
+	connState      ConnectionState
+
+	timer connectionTimer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
 	// It is reset as soon as we receive a packet from the peer.
 	keepAlivePingSent bool
 	keepAliveInterval time.Duration
 
 	datagramQueue *datagramQueue
+	pinger        *pinger
 
 	logID  string
 	tracer logging.ConnectionTracer
@@ -242,6 +271,7 @@ var newConnection = func(
 	tlsConf *tls.Config,
 	tokenGenerator *handshake.TokenGenerator,
 	enable0RTT bool,
+	loop *eventLoop,
 	tracer logging.ConnectionTracer,
 	tracingID uint64,
 	logger utils.Logger,
@@ -253,6 +283,7 @@ var newConnection = func(
 		handshakeDestConnID:   destConnID,
 		srcConnIDLen:          srcConnID.Len(),
 		tokenGenerator:        tokenGenerator,
+		enable0RTT:            enable0RTT,
 		oneRTTStream:          newCryptoStream(),
 		perspective:           protocol.PerspectiveServer,
 		handshakeCompleteChan: make(chan struct{}),
@@ -280,15 +311,22 @@ var newConnection = func(
 		runner.Retire,
 		runner.ReplaceWithClosed,
 		s.queueControlFrame,
+		s.config.generateConnectionID,
 		s.version,
 	)
 	s.preSetup()
+	if loop != nil {
+		s.loopTask = loop.NewTask(s.handleEvents)
+		s.sendQueue = newDirectSender(s.conn, s.destroyImpl)
+	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		0,
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		s.perspective,
+		s.config.CongestionControl == CongestionControlNewReno,
+		s.joinCongestionGroup(),
 		s.tracer,
 		s.logger,
 		s.version,
@@ -332,10 +370,12 @@ var newConnection = func(
 			onHandshakeComplete: func() {
 				runner.Retire(clientDestConnID)
 				close(s.handshakeCompleteChan)
+				s.notifyEventLoop()
 			},
 		},
 		tlsConf,
 		enable0RTT,
+		s.config.Allow0RTT,
 		s.rttStats,
 		tracer,
 		logger,
@@ -407,6 +447,7 @@ var newClientConnection = func(
 		runner.Retire,
 		runner.ReplaceWithClosed,
 		s.queueControlFrame,
+		s.config.generateConnectionID,
 		s.version,
 	)
 	s.preSetup()
@@ -416,6 +457,8 @@ var newClientConnection = func(
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		s.perspective,
+		s.config.CongestionControl == CongestionControlNewReno,
+		s.joinCongestionGroup(),
 		s.tracer,
 		s.logger,
 		s.version,
@@ -518,6 +561,9 @@ func (s *connection) preSetup() {
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		s.config.Replay0RTT,
+		protocol.ByteCount(s.config.StreamSendBufferSize),
+		s.config.CoalesceStreamWrites,
 		s.perspective,
 		s.version,
 	)
@@ -526,6 +572,9 @@ func (s *connection) preSetup() {
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
+	s.connState.Version = s.version
+	s.connState.PathMTU = getMaxPacketSize(s.conn.RemoteAddr())
+	s.connState.PeerAddressValidated = s.perspective == protocol.PerspectiveClient
 
 	now := time.Now()
 	s.lastPacketReceivedTime = now
@@ -535,10 +584,20 @@ func (s *connection) preSetup() {
 	if s.config.EnableDatagrams {
 		s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
 	}
+	s.pinger = newPinger(s.queueTrackedControlFrame, s.rttStats)
 }
 
 // run the connection main loop
+// If the connection is driven by an event loop, run returns immediately,
+// and the run loop is executed by the event loop's workers.
 func (s *connection) run() error {
+	if s.loopTask != nil {
+		s.timer = s.loopTask.NewTimer()
+		go s.cryptoStreamHandler.RunHandshake()
+		s.loopTask.Start()
+		return nil
+	}
+
 	defer s.ctxCancel()
 
 	s.timer = utils.NewTimer()
@@ -564,137 +623,199 @@ func (s *connection) run() error {
 		}
 	}
 
-	var (
-		closeErr           closeError
-		sendQueueAvailable <-chan struct{}
-	)
-
-runLoop:
 	for {
-		// Close immediately if requested
-		select {
-		case closeErr = <-s.closeChan:
-			break runLoop
-		case <-s.handshakeCompleteChan:
-			s.handleHandshakeComplete()
-		default:
+		if closeErr, closed, _ := s.runIteration(true); closed {
+			return s.finishRun(closeErr)
 		}
+	}
+}
 
-		s.maybeResetTimer()
+// handleEvents is run by the event loop every time the connection is scheduled.
+func (s *connection) handleEvents() loopTaskResult {
+	closeErr, closed, idle := s.runIteration(false)
+	if closed {
+		s.finishRun(closeErr)
+		s.ctxCancel()
+		return loopTaskDone
+	}
+	if idle {
+		return loopTaskWait
+	}
+	return loopTaskContinue
+}
 
-		var processedUndecryptablePacket bool
-		if len(s.undecryptablePacketsToProcess) > 0 {
-			queue := s.undecryptablePacketsToProcess
-			s.undecryptablePacketsToProcess = nil
-			for _, p := range queue {
-				if processed := s.handlePacketImpl(p); processed {
-					processedUndecryptablePacket = true
-				}
-				// Don't set timers and send packets if the packet made us close the connection.
-				select {
-				case closeErr = <-s.closeChan:
-					break runLoop
-				default:
-				}
+// runIteration runs a single iteration of the run loop.
+// If wait is true, it blocks until an event occurs.
+// Otherwise, it returns immediately if there are no events to handle, and reports that the connection is idle.
+func (s *connection) runIteration(wait bool) (closeErr closeError, closed, idle bool) {
+	// Close immediately if requested
+	select {
+	case closeErr = <-s.closeChan:
+		return closeErr, true, false
+	case <-s.handshakeCompleteChan:
+		s.handleHandshakeComplete()
+	default:
+	}
+
+	s.maybeResetTimer()
+
+	var processedUndecryptablePacket bool
+	if len(s.undecryptablePacketsToProcess) > 0 {
+		queue := s.undecryptablePacketsToProcess
+		s.undecryptablePacketsToProcess = nil
+		for _, p := range queue {
+			if processed := s.handlePacketImpl(p); processed {
+				processedUndecryptablePacket = true
+			}
+			// Don't set timers and send packets if the packet made us close the connection.
+			select {
+			case closeErr = <-s.closeChan:
+				return closeErr, true, false
+			default:
 			}
 		}
-		// If we processed any undecryptable packets, jump to the resetting of the timers directly.
-		if !processedUndecryptablePacket {
+	}
+	// If we processed any undecryptable packets, jump to the resetting of the timers directly.
+	if !processedUndecryptablePacket {
+		if !wait && !s.hasPendingEvents() {
+			return closeError{}, false, true
+		}
+		select {
+		case closeErr = <-s.closeChan:
+			return closeErr, true, false
+		case <-s.timer.Chan():
+			s.timer.SetRead()
+			// We do all the interesting stuff after the switch statement, so
+			// nothing to see here.
+		case <-s.sendingScheduled:
+			// We do all the interesting stuff after the switch statement, so
+			// nothing to see here.
+		case <-s.sendQueueAvailable:
+		case firstPacket := <-s.receivedPackets:
+			wasProcessed := s.handlePacketImpl(firstPacket)
+			// Don't set timers and send packets if the packet made us close the connection.
 			select {
 			case closeErr = <-s.closeChan:
-				break runLoop
-			case <-s.timer.Chan():
-				s.timer.SetRead()
-				// We do all the interesting stuff after the switch statement, so
-				// nothing to see here.
-			case <-s.sendingScheduled:
-				// We do all the interesting stuff after the switch statement, so
-				// nothing to see here.
-			case <-sendQueueAvailable:
-			case firstPacket := <-s.receivedPackets:
-				wasProcessed := s.handlePacketImpl(firstPacket)
-				// Don't set timers and send packets if the packet made us close the connection.
-				select {
-				case closeErr = <-s.closeChan:
-					break runLoop
-				default:
-				}
-				if s.handshakeComplete {
-					// Now process all packets in the receivedPackets channel.
-					// Limit the number of packets to the length of the receivedPackets channel,
-					// so we eventually get a chance to send out an ACK when receiving a lot of packets.
-					numPackets := len(s.receivedPackets)
-				receiveLoop:
-					for i := 0; i < numPackets; i++ {
+				return closeErr, true, false
+			default:
+			}
+			if s.handshakeComplete {
+				// Now process all packets in the receivedPackets channel.
+				// Limit the number of packets to the length of the receivedPackets channel,
+				// so we eventually get a chance to send out an ACK when receiving a lot of packets.
+				numPackets := len(s.receivedPackets)
+			receiveLoop:
+				for i := 0; i < numPackets; i++ {
+					select {
+					case p := <-s.receivedPackets:
+						if processed := s.handlePacketImpl(p); processed {
+							wasProcessed = true
+						}
 						select {
-						case p := <-s.receivedPackets:
-							if processed := s.handlePacketImpl(p); processed {
-								wasProcessed = true
-							}
-							select {
-							case closeErr = <-s.closeChan:
-								break runLoop
-							default:
-							}
+						case closeErr = <-s.closeChan:
+							return closeErr, true, false
 						default:
-							break receiveLoop
 						}
+					default:
+						break receiveLoop
 					}
 				}
-				// Only reset the timers if this packet was actually processed.
-				// This avoids modifying any state when handling undecryptable packets,
-				// which could be injected by an attacker.
-				if !wasProcessed {
-					continue
-				}
-			case <-s.handshakeCompleteChan:
-				s.handleHandshakeComplete()
-			}
-		}
-
-		now := time.Now()
-		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
-			// This could cause packets to be retransmitted.
-			// Check it before trying to send packets.
-			if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
-				s.closeLocal(err)
 			}
-		}
-
-		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
-			// send a PING frame since there is no activity in the connection
-			s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
-			s.framer.QueueControlFrame(&wire.PingFrame{})
-			s.keepAlivePingSent = true
-		} else if !s.handshakeComplete && now.Sub(s.creationTime) >= s.config.handshakeTimeout() {
-			s.destroyImpl(qerr.ErrHandshakeTimeout)
-			continue
-		} else {
-			idleTimeoutStartTime := s.idleTimeoutStartTime()
-			if (!s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.config.HandshakeIdleTimeout) ||
-				(s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.idleTimeout) {
-				s.destroyImpl(qerr.ErrIdleTimeout)
-				continue
+			// Only reset the timers if this packet was actually processed.
+			// This avoids modifying any state when handling undecryptable packets,
+			// which could be injected by an attacker.
+			if !wasProcessed {
+				return closeError{}, false, false
 			}
+		case <-s.handshakeCompleteChan:
+			s.handleHandshakeComplete()
 		}
+	}
 
-		if s.sendQueue.WouldBlock() {
-			// The send queue is still busy sending out packets.
-			// Wait until there's space to enqueue new packets.
-			sendQueueAvailable = s.sendQueue.Available()
-			continue
-		}
-		if err := s.sendPackets(); err != nil {
+	now := time.Now()
+	if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
+		// This could cause packets to be retransmitted.
+		// Check it before trying to send packets.
+		if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
 			s.closeLocal(err)
 		}
-		if s.sendQueue.WouldBlock() {
-			sendQueueAvailable = s.sendQueue.Available()
-		} else {
-			sendQueueAvailable = nil
+	}
+
+	if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
+		// send a PING frame since there is no activity in the connection
+		s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
+		s.framer.QueueControlFrame(&wire.PingFrame{})
+		s.keepAlivePingSent = true
+	} else if !s.handshakeComplete && now.Sub(s.creationTime) >= s.config.handshakeTimeout() {
+		s.destroyImpl(qerr.ErrHandshakeTimeout)
+		return closeError{}, false, false
+	} else {
+		idleTimeoutStartTime := s.idleTimeoutStartTime()
+		if (!s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.config.HandshakeIdleTimeout) ||
+			(s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.idleTimeout) {
+			s.destroyImpl(qerr.ErrIdleTimeout)
+			return closeError{}, false, false
 		}
 	}
 
+	if s.sendQueue.WouldBlock() {
+		// The send queue is still busy sending out packets.
+		// Wait until there's space to enqueue new packets.
+		s.sendQueueAvailable = s.sendQueue.Available()
+		return closeError{}, false, false
+	}
+	if err := s.sendPackets(); err != nil {
+		s.closeLocal(err)
+	}
+	if s.sendQueue.WouldBlock() {
+		s.sendQueueAvailable = s.sendQueue.Available()
+	} else {
+		s.sendQueueAvailable = nil
+	}
+	return closeError{}, false, false
+}
+
+// hasPendingEvents says if there are any events the run loop needs to handle.
+// It is only used for connections driven by an event loop.
+func (s *connection) hasPendingEvents() bool {
+	if len(s.closeChan) > 0 || len(s.receivedPackets) > 0 || len(s.sendingScheduled) > 0 ||
+		len(s.timer.Chan()) > 0 || len(s.sendQueueAvailable) > 0 {
+		return true
+	}
+	select {
+	case <-s.handshakeCompleteChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// joinCongestionGroup joins the group of connections to the same peer that share a congestion controller.
+// It returns nil if Config.ShareCongestionControl is not set.
+func (s *connection) joinCongestionGroup() congestion.SendAlgorithmWithDebugInfos {
+	if !s.config.ShareCongestionControl {
+		return nil
+	}
+	peer := s.conn.RemoteAddr().String()
+	if addr, ok := s.conn.RemoteAddr().(*net.UDPAddr); ok {
+		peer = addr.IP.String()
+	}
+	s.congestionGroupMember = congestion.JoinGroup(
+		peer,
+		congestion.DefaultClock{},
+		s.rttStats,
+		getMaxPacketSize(s.conn.RemoteAddr()),
+		s.config.CongestionControl == CongestionControlNewReno,
+	)
+	return s.congestionGroupMember
+}
+
+// finishRun cleans up after the run loop terminated.
+func (s *connection) finishRun(closeErr closeError) error {
 	s.handleCloseError(&closeErr)
+	if s.congestionGroupMember != nil {
+		s.congestionGroupMember.Leave()
+	}
 	if e := (&errCloseForRecreating{}); !errors.As(closeErr.err, &e) && s.tracer != nil {
 		s.tracer.Close()
 	}
@@ -705,6 +826,13 @@ runLoop:
 	return closeErr.err
 }
 
+// notifyEventLoop schedules the connection on the event loop, if it is driven by one.
+func (s *connection) notifyEventLoop() {
+	if s.loopTask != nil {
+		s.loopTask.Schedule()
+	}
+}
+
 // blocks until the early connection can be used
 func (s *connection) earlyConnReady() <-chan struct{} {
 	return s.earlyConnReadyChan
@@ -723,10 +851,23 @@ func (s *connection) supportsDatagrams() bool {
 }
 
 func (s *connection) ConnectionState() ConnectionState {
-	return ConnectionState{
-		TLS:               s.cryptoStreamHandler.ConnectionState(),
-		SupportsDatagrams: s.supportsDatagrams(),
+	s.connStateMutex.Lock()
+	cs := s.connState
+	s.connStateMutex.Unlock()
+	cs.TLS = s.cryptoStreamHandler.ConnectionState()
+	cs.Used0RTT = cs.TLS.Used0RTT
+	cs.DidResume = cs.TLS.DidResume
+	cs.LocalConnectionIDs = s.connIDGenerator.ActiveConnectionIDs()
+	cs.PeerConnectionID = s.connIDManager.ActiveConnectionID()
+	return cs
+}
+
+func (s *connection) ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error) {
+	cs := s.cryptoStreamHandler.ConnectionState()
+	if !cs.HandshakeComplete {
+		return nil, errors.New("cannot export keying material before the handshake completed")
 	}
+	return cs.ExportKeyingMaterial(label, context, length)
 }
 
 // Time when the next keep-alive packet should be sent.
@@ -776,19 +917,25 @@ func (s *connection) handleHandshakeComplete() {
 	defer s.handshakeCtxCancel()
 	// Once the handshake completes, we have derived 1-RTT keys.
 	// There's no point in queueing undecryptable packets for later decryption any more.
-	s.undecryptablePackets = nil
+	s.dropUndecryptablePackets()
 
 	s.connIDManager.SetHandshakeComplete()
 	s.connIDGenerator.SetHandshakeComplete()
 
 	if s.perspective == protocol.PerspectiveClient {
+		s.streamsMap.Drop0RTTReplayData()
 		s.applyTransportParameters()
 		return
 	}
 
 	s.handleHandshakeConfirmed()
 
-	ticket, err := s.cryptoStreamHandler.GetSessionTicket()
+	// The application data is only used for deciding whether to accept 0-RTT.
+	var appData []byte
+	if s.enable0RTT && s.config.GetSessionTicketAppData != nil {
+		appData = s.config.GetSessionTicketAppData(s)
+	}
+	ticket, err := s.cryptoStreamHandler.GetSessionTicket(appData)
 	if err != nil {
 		s.closeLocal(err)
 	}
@@ -824,6 +971,9 @@ func (s *connection) handleHandshakeConfirmed() {
 			func(size protocol.ByteCount) {
 				s.sentPacketHandler.SetMaxDatagramSize(size)
 				s.packer.SetMaxPacketSize(size)
+				s.connStateMutex.Lock()
+				s.connState.PathMTU = size
+				s.connStateMutex.Unlock()
 			},
 		)
 	}
@@ -834,6 +984,7 @@ func (s *connection) handlePacketImpl(rp *receivedPacket) bool {
 
 	if wire.IsVersionNegotiationPacket(rp.data) {
 		s.handleVersionNegotiationPacket(rp)
+		rp.buffer.Release()
 		return false
 	}
 
@@ -878,9 +1029,8 @@ func (s *connection) handlePacketImpl(rp *receivedPacket) bool {
 		}
 		lastConnID = hdr.DestConnectionID
 
-		if counter > 0 {
-			p.buffer.Split()
-		}
+		// Every packet holds a reference to the buffer, which is released once the packet was handled.
+		p.buffer.Split()
 		counter++
 
 		// only log if this actually a coalesced packet
@@ -893,7 +1043,8 @@ func (s *connection) handlePacketImpl(rp *receivedPacket) bool {
 		}
 		data = rest
 	}
-	p.buffer.MaybeRelease()
+	// Release the reference held while parsing the coalesced packets.
+	rp.buffer.Release()
 	return processed
 }
 
@@ -903,7 +1054,7 @@ func (s *connection) handleSinglePacket(p *receivedPacket, hdr *wire.Header) boo
 	defer func() {
 		// Put back the packet buffer if the packet wasn't queued for later decryption.
 		if !wasQueued {
-			p.buffer.Decrement()
+			p.buffer.Release()
 		}
 	}()
 
@@ -1202,6 +1353,13 @@ func (s *connection) handleUnpackedPacket(
 		}
 	}
 
+	// Receiving a Handshake packet proves that the client received our Initial packets.
+	if s.perspective == protocol.PerspectiveServer && packet.encryptionLevel == protocol.EncryptionHandshake {
+		s.connStateMutex.Lock()
+		s.connState.PeerAddressValidated = true
+		s.connStateMutex.Unlock()
+	}
+
 	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, ecn, packet.encryptionLevel, rcvTime, isAckEliciting)
 }
 
@@ -1258,6 +1416,7 @@ func (s *connection) handlePacket(p *receivedPacket) {
 	// the channel size, protocol.MaxConnUnprocessedPackets
 	select {
 	case s.receivedPackets <- p:
+		s.notifyEventLoop()
 	default:
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
@@ -1397,6 +1556,9 @@ func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.Encr
 	if err != nil {
 		return err
 	}
+	if encLevel == protocol.Encryption1RTT {
+		s.pinger.AckProcessed(utils.MinDuration(frame.DelayTime, s.rttStats.MaxAckDelay()))
+	}
 	if !acked1RTTPacket {
 		return nil
 	}
@@ -1426,6 +1588,7 @@ func (s *connection) closeLocal(e error) {
 			s.logger.Errorf("Closing connection with error: %s", e)
 		}
 		s.closeChan <- closeError{err: e, immediate: false, remote: false}
+		s.notifyEventLoop()
 	})
 }
 
@@ -1443,6 +1606,7 @@ func (s *connection) destroyImpl(e error) {
 			s.logger.Errorf("Destroying connection with error: %s", e)
 		}
 		s.closeChan <- closeError{err: e, immediate: true, remote: false}
+		s.notifyEventLoop()
 	})
 }
 
@@ -1450,6 +1614,7 @@ func (s *connection) closeRemote(e error) {
 	s.closeOnce.Do(func() {
 		s.logger.Errorf("Peer closed connection with error: %s", e)
 		s.closeChan <- closeError{err: e, immediate: true, remote: true}
+		s.notifyEventLoop()
 	})
 }
 
@@ -1506,6 +1671,8 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	if s.datagramQueue != nil {
 		s.datagramQueue.CloseWithError(e)
 	}
+	s.pinger.CloseWithError(e)
+	s.dropUndecryptablePackets()
 
 	if s.tracer != nil && !errors.As(e, &recreateErr) {
 		s.tracer.ClosedConnection(e)
@@ -1558,6 +1725,9 @@ func (s *connection) restoreTransportParameters(params *wire.TransportParameters
 	}
 
 	s.peerParams = params
+	s.connStateMutex.Lock()
+	s.connState.SupportsDatagrams = s.supportsDatagrams()
+	s.connStateMutex.Unlock()
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.streamsMap.UpdateLimits(params)
@@ -1571,6 +1741,9 @@ func (s *connection) handleTransportParameters(params *wire.TransportParameters)
 		})
 	}
 	s.peerParams = params
+	s.connStateMutex.Lock()
+	s.connState.PeerTransportParameters = params
+	s.connStateMutex.Unlock()
 	// On the client side we have to wait for handshake completion.
 	// During a 0-RTT connection, we are only allowed to use the new transport parameters for 1-RTT packets.
 	if s.perspective == protocol.PerspectiveServer {
@@ -1621,6 +1794,12 @@ func (s *connection) applyTransportParameters() {
 	s.keepAliveInterval = utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval)
 	s.streamsMap.UpdateLimits(params)
 	s.packer.HandleTransportParameters(params)
+	s.connStateMutex.Lock()
+	s.connState.SupportsDatagrams = s.supportsDatagrams()
+	if params.MaxUDPPayloadSize != 0 {
+		s.connState.PathMTU = utils.MinByteCount(s.connState.PathMTU, params.MaxUDPPayloadSize)
+	}
+	s.connStateMutex.Unlock()
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.rttStats.SetMaxAckDelay(params.MaxAckDelay)
@@ -1824,7 +2003,12 @@ func (s *connection) sendConnectionClose(e error) ([]byte, error) {
 		return nil, err
 	}
 	s.logCoalescedPacket(packet)
-	return packet.buffer.Data, s.conn.Write(packet.buffer.Data)
+	// The closed connection retransmits the CONNECTION_CLOSE for a while,
+	// copy it so that the packet buffer can be released right away.
+	data := make([]byte, packet.buffer.Len())
+	copy(data, packet.buffer.Data)
+	packet.buffer.Release()
+	return data, s.conn.Write(data)
 }
 
 func (s *connection) logPacketContents(p *packetContents) {
@@ -1921,6 +2105,7 @@ func (s *connection) newFlowController(id protocol.StreamID) flowcontrol.StreamF
 func (s *connection) scheduleSending() {
 	select {
 	case s.sendingScheduled <- struct{}{}:
+		s.notifyEventLoop()
 	default:
 	}
 }
@@ -1943,11 +2128,28 @@ func (s *connection) tryQueueingUndecryptablePacket(p *receivedPacket, hdr *wire
 	s.undecryptablePackets = append(s.undecryptablePackets, p)
 }
 
+// dropUndecryptablePackets drops all queued undecryptable packets,
+// and releases the reference they hold on their packet buffers.
+func (s *connection) dropUndecryptablePackets() {
+	for _, queue := range [][]*receivedPacket{s.undecryptablePackets, s.undecryptablePacketsToProcess} {
+		for _, p := range queue {
+			p.buffer.Release()
+		}
+	}
+	s.undecryptablePackets = nil
+	s.undecryptablePacketsToProcess = nil
+}
+
 func (s *connection) queueControlFrame(f wire.Frame) {
 	s.framer.QueueControlFrame(f)
 	s.scheduleSending()
 }
 
+func (s *connection) queueTrackedControlFrame(f ackhandler.Frame) {
+	s.framer.QueueTrackedControlFrame(f)
+	s.scheduleSending()
+}
+
 func (s *connection) onHasStreamWindowUpdate(id protocol.StreamID) {
 	s.windowUpdateQueue.AddStream(id)
 	s.scheduleSending()
@@ -1970,19 +2172,43 @@ func (s *connection) onStreamCompleted(id protocol.StreamID) {
 }
 
 func (s *connection) SendMessage(p []byte) error {
+	f, err := s.newDatagramFrame(p)
+	if err != nil {
+		return err
+	}
+	return s.datagramQueue.AddAndWait(f)
+}
+
+func (s *connection) SendMessages(msgs [][]byte) error {
+	frames := make([]*wire.DatagramFrame, 0, len(msgs))
+	for _, p := range msgs {
+		f, err := s.newDatagramFrame(p)
+		if err != nil {
+			return err
+		}
+		frames = append(frames, f)
+	}
+	return s.datagramQueue.AddAndWait(frames...)
+}
+
+func (s *connection) newDatagramFrame(p []byte) (*wire.DatagramFrame, error) {
 	f := &wire.DatagramFrame{DataLenPresent: true}
 	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
-		return errors.New("message too large")
+		return nil, errors.New("message too large")
 	}
 	f.Data = make([]byte, len(p))
 	copy(f.Data, p)
-	return s.datagramQueue.AddAndWait(f)
+	return f, nil
 }
 
 func (s *connection) ReceiveMessage() ([]byte, error) {
 	return s.datagramQueue.Receive()
 }
 
+func (s *connection) Ping(ctx context.Context) (time.Duration, error) {
+	return s.pinger.Ping(ctx)
+}
+
 func (s *connection) LocalAddr() net.Addr {
 	return s.conn.LocalAddr()
 }
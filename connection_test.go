@@ -44,6 +44,8 @@ func areClosedConnsRunning() bool {
 }
 
 var _ = Describe("Connection", func() {
+	expectNoBufferLeaks()
+
 	var (
 		conn          *connection
 		connRunner    *MockConnRunner
@@ -107,6 +109,7 @@ var _ = Describe("Connection", func() {
 			nil, // tls.Config
 			tokenGenerator,
 			false,
+			nil,
 			tracer,
 			1234,
 			utils.DefaultLogger,
@@ -1102,6 +1105,7 @@ var _ = Describe("Connection", func() {
 			tracer.EXPECT().BufferedPacket(logging.PacketTypeHandshake)
 			Expect(conn.handlePacketImpl(packet)).To(BeFalse())
 			Expect(conn.undecryptablePackets).To(Equal([]*receivedPacket{packet}))
+			conn.dropUndecryptablePackets()
 		})
 
 		Context("updating the remote address", func() {
@@ -1185,6 +1189,7 @@ var _ = Describe("Connection", func() {
 					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), gomock.Any()),
 				)
 				packet1.data = append(packet1.data, packet2.data...)
+				packet2.buffer.Release()
 				Expect(conn.handlePacketImpl(packet1)).To(BeTrue())
 			})
 
@@ -1208,10 +1213,12 @@ var _ = Describe("Connection", func() {
 					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), gomock.Any()),
 				)
 				packet1.data = append(packet1.data, packet2.data...)
+				packet2.buffer.Release()
 				Expect(conn.handlePacketImpl(packet1)).To(BeTrue())
 
 				Expect(conn.undecryptablePackets).To(HaveLen(1))
 				Expect(conn.undecryptablePackets[0].data).To(HaveLen(hdrLen1 + 456 - 3))
+				conn.dropUndecryptablePackets()
 			})
 
 			It("ignores coalesced packet parts if the destination connection IDs don't match", func() {
@@ -1233,6 +1240,7 @@ var _ = Describe("Connection", func() {
 					tracer.EXPECT().DroppedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), logging.PacketDropUnknownConnectionID),
 				)
 				packet1.data = append(packet1.data, packet2.data...)
+				packet2.buffer.Release()
 				Expect(conn.handlePacketImpl(packet1)).To(BeTrue())
 			})
 		})
@@ -1290,7 +1298,7 @@ var _ = Describe("Connection", func() {
 			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
 			sent := make(chan struct{})
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { packet.Release(); close(sent) })
 			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []logging.Frame{})
 			conn.scheduleSending()
 			Eventually(sent).Should(BeClosed())
@@ -1336,7 +1344,7 @@ var _ = Describe("Connection", func() {
 			conn.connFlowController = fc
 			runConn()
 			sent := make(chan struct{})
-			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+			sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { packet.Release(); close(sent) })
 			tracer.EXPECT().SentPacket(p.header, p.length, nil, []logging.Frame{})
 			conn.scheduleSending()
 			Eventually(sent).Should(BeClosed())
@@ -1392,7 +1400,7 @@ var _ = Describe("Connection", func() {
 					conn.sentPacketHandler = sph
 					runConn()
 					sent := make(chan struct{})
-					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { packet.Release(); close(sent) })
 					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
 					conn.scheduleSending()
 					Eventually(sent).Should(BeClosed())
@@ -1413,7 +1421,7 @@ var _ = Describe("Connection", func() {
 					conn.sentPacketHandler = sph
 					runConn()
 					sent := make(chan struct{})
-					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { packet.Release(); close(sent) })
 					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
 					conn.scheduleSending()
 					Eventually(sent).Should(BeClosed())
@@ -1466,7 +1474,7 @@ var _ = Describe("Connection", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
 			packer.EXPECT().PackPacket().Return(getPacket(11), nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).Times(2)
+			sender.EXPECT().Send(gomock.Any()).Do(func(p *packetBuffer) { p.Release() }).Times(2)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1483,7 +1491,7 @@ var _ = Describe("Connection", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any())
+			sender.EXPECT().Send(gomock.Any()).Do(func(p *packetBuffer) { p.Release() })
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1500,7 +1508,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
 			packer.EXPECT().MaybePackAckPacket(gomock.Any()).Return(getPacket(10), nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any())
+			sender.EXPECT().Send(gomock.Any()).Do(func(p *packetBuffer) { p.Release() })
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1519,7 +1527,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAck)
 			packer.EXPECT().PackPacket().Return(getPacket(100), nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any())
+			sender.EXPECT().Send(gomock.Any()).Do(func(p *packetBuffer) { p.Release() })
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1546,7 +1554,7 @@ var _ = Describe("Connection", func() {
 			)
 			written := make(chan struct{}, 2)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} }).Times(2)
+			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { p.Release(); written <- struct{}{} }).Times(2)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1569,7 +1577,7 @@ var _ = Describe("Connection", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(1002), nil)
 			written := make(chan struct{}, 3)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} }).Times(3)
+			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { p.Release(); written <- struct{}{} }).Times(3)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1598,7 +1606,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(1000), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { p.Release(); close(written) })
 			available <- struct{}{}
 			Eventually(written).Should(BeClosed())
 		})
@@ -1621,7 +1629,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(1000), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { p.Release(); close(written) })
 
 			conn.scheduleSending()
 			time.Sleep(scaleDuration(50 * time.Millisecond))
@@ -1637,7 +1645,7 @@ var _ = Describe("Connection", func() {
 			written := make(chan struct{}, 1)
 			sender.EXPECT().WouldBlock()
 			sender.EXPECT().WouldBlock().Return(true).Times(2)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} })
+			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { p.Release(); written <- struct{}{} })
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1656,7 +1664,7 @@ var _ = Describe("Connection", func() {
 			sender.EXPECT().WouldBlock().AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(1001), nil)
 			packer.EXPECT().PackPacket().Return(nil, nil)
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} })
+			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { p.Release(); written <- struct{}{} })
 			available <- struct{}{}
 			Eventually(written).Should(Receive())
 
@@ -1690,7 +1698,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode().Return(ackhandler.SendNone)
 			written := make(chan struct{}, 1)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { written <- struct{}{} })
+			sender.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *packetBuffer) { p.Release(); written <- struct{}{} })
 			mtuDiscoverer.EXPECT().ShouldSendProbe(gomock.Any()).Return(true)
 			ping := ackhandler.Frame{Frame: &wire.PingFrame{}}
 			mtuDiscoverer.EXPECT().GetPing().Return(ping, protocol.ByteCount(1234))
@@ -1750,7 +1758,7 @@ var _ = Describe("Connection", func() {
 			time.Sleep(50 * time.Millisecond)
 			// only EXPECT calls after scheduleSending is called
 			written := make(chan struct{})
-			sender.EXPECT().Send(gomock.Any()).Do(func(*packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any()).Do(func(p *packetBuffer) { p.Release(); close(written) })
 			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			conn.scheduleSending()
 			Eventually(written).Should(BeClosed())
@@ -1774,7 +1782,7 @@ var _ = Describe("Connection", func() {
 			conn.receivedPacketHandler = rph
 
 			written := make(chan struct{})
-			sender.EXPECT().Send(gomock.Any()).Do(func(*packetBuffer) { close(written) })
+			sender.EXPECT().Send(gomock.Any()).Do(func(p *packetBuffer) { p.Release(); close(written) })
 			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			go func() {
 				defer GinkgoRecover()
@@ -1882,7 +1890,7 @@ var _ = Describe("Connection", func() {
 			<-finishHandshake
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
-			cryptoSetup.EXPECT().GetSessionTicket()
+			cryptoSetup.EXPECT().GetSessionTicket(gomock.Any())
 			close(conn.handshakeCompleteChan)
 			conn.run()
 		}()
@@ -1902,6 +1910,29 @@ var _ = Describe("Connection", func() {
 		Eventually(conn.Context().Done()).Should(BeClosed())
 	})
 
+	It("stores the application data in the session ticket", func() {
+		conn.enable0RTT = true
+		conn.config.GetSessionTicketAppData = func(c Connection) []byte {
+			Expect(c).To(BeIdenticalTo(conn))
+			return []byte("foobar")
+		}
+		connRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().GetSessionTicket([]byte("foobar"))
+		conn.handleHandshakeComplete()
+	})
+
+	It("doesn't ask for application data if 0-RTT is disabled", func() {
+		conn.config.GetSessionTicketAppData = func(Connection) []byte {
+			Fail("didn't expect GetSessionTicketAppData to be called")
+			return nil
+		}
+		connRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().GetSessionTicket(nil)
+		conn.handleHandshakeComplete()
+	})
+
 	It("sends a connection ticket when the handshake completes", func() {
 		const size = protocol.MaxPostHandshakeCryptoFrameSize * 3 / 2
 		packer.EXPECT().PackCoalescedPacket().AnyTimes()
@@ -1912,7 +1943,7 @@ var _ = Describe("Connection", func() {
 			<-finishHandshake
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
-			cryptoSetup.EXPECT().GetSessionTicket().Return(make([]byte, size), nil)
+			cryptoSetup.EXPECT().GetSessionTicket(gomock.Any()).Return(make([]byte, size), nil)
 			close(conn.handshakeCompleteChan)
 			conn.run()
 		}()
@@ -1998,7 +2029,7 @@ var _ = Describe("Connection", func() {
 			defer GinkgoRecover()
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
-			cryptoSetup.EXPECT().GetSessionTicket()
+			cryptoSetup.EXPECT().GetSessionTicket(gomock.Any())
 			mconn.EXPECT().Write(gomock.Any())
 			close(conn.handshakeCompleteChan)
 			conn.run()
@@ -2058,6 +2089,12 @@ var _ = Describe("Connection", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("doesn't export keying material before the handshake completes", func() {
+		cryptoSetup.EXPECT().ConnectionState()
+		_, err := conn.ExportKeyingMaterial("label", nil, 32)
+		Expect(err).To(MatchError("cannot export keying material before the handshake completed"))
+	})
+
 	Context("transport parameters", func() {
 		It("processes transport parameters received from the client", func() {
 			params := &wire.TransportParameters{
@@ -2079,6 +2116,53 @@ var _ = Describe("Connection", func() {
 			conn.handleTransportParameters(params)
 			Expect(conn.earlyConnReady()).To(BeClosed())
 		})
+
+		It("reports the connection state", func() {
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{Used0RTT: true}).AnyTimes()
+			state := conn.ConnectionState()
+			Expect(state.Version).To(Equal(protocol.VersionTLS))
+			Expect(state.Used0RTT).To(BeTrue())
+			Expect(state.PeerTransportParameters).To(BeNil())
+			Expect(state.SupportsDatagrams).To(BeFalse())
+			Expect(state.PathMTU).To(Equal(getMaxPacketSize(remoteAddr)))
+			Expect(state.LocalConnectionIDs).To(Equal([]protocol.ConnectionID{srcConnID}))
+			Expect(state.PeerConnectionID).To(Equal(destConnID))
+			Expect(state.PeerAddressValidated).To(BeFalse())
+
+			params := &wire.TransportParameters{
+				MaxUDPPayloadSize:         1234,
+				MaxDatagramFrameSize:      1000,
+				InitialSourceConnectionID: destConnID,
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			conn.handleTransportParameters(params)
+			state = conn.ConnectionState()
+			Expect(state.PeerTransportParameters).To(Equal(params))
+			Expect(state.SupportsDatagrams).To(BeTrue())
+			Expect(state.PathMTU).To(BeEquivalentTo(1234))
+			Expect(state.PeerAddressValidated).To(BeFalse())
+
+			// receiving a Handshake packet validates the client's address
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: srcConnID,
+					SrcConnectionID:  destConnID,
+				},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(hdr, gomock.Any(), gomock.Any())
+			Expect(conn.handleUnpackedPacket(&unpackedPacket{
+				hdr:             hdr,
+				encryptionLevel: protocol.EncryptionHandshake,
+				data:            []byte{0}, // one PADDING frame
+			}, protocol.ECNNon, time.Now(), 100)).To(Succeed())
+			Expect(conn.ConnectionState().PeerAddressValidated).To(BeTrue())
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -2269,7 +2353,7 @@ var _ = Describe("Connection", func() {
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
-				cryptoSetup.EXPECT().GetSessionTicket().MaxTimes(1)
+				cryptoSetup.EXPECT().GetSessionTicket(gomock.Any()).MaxTimes(1)
 				err := conn.run()
 				nerr, ok := err.(net.Error)
 				Expect(ok).To(BeTrue())
@@ -2298,7 +2382,7 @@ var _ = Describe("Connection", func() {
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
-				cryptoSetup.EXPECT().GetSessionTicket().MaxTimes(1)
+				cryptoSetup.EXPECT().GetSessionTicket(gomock.Any()).MaxTimes(1)
 				cryptoSetup.EXPECT().SetHandshakeConfirmed().MaxTimes(1)
 				close(conn.handshakeCompleteChan)
 				err := conn.run()
@@ -2409,6 +2493,8 @@ var _ = Describe("Connection", func() {
 })
 
 var _ = Describe("Client Connection", func() {
+	expectNoBufferLeaks()
+
 	var (
 		conn        *connection
 		connRunner  *MockConnRunner
@@ -2545,7 +2631,8 @@ var _ = Describe("Client Connection", func() {
 			SrcConnectionID:  destConnID,
 		}
 		tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
-		Expect(conn.handleSinglePacket(&receivedPacket{buffer: getPacketBuffer()}, hdr)).To(BeTrue())
+		p := &receivedPacket{buffer: getPacketBuffer()}
+		Expect(conn.handleSinglePacket(p, hdr)).To(BeTrue())
 	})
 
 	It("handles HANDSHAKE_DONE frames", func() {
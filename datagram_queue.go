@@ -7,7 +7,10 @@ import (
 )
 
 type datagramQueue struct {
-	sendQueue chan *wire.DatagramFrame
+	sendQueue chan []*wire.DatagramFrame
+	// The batch of DATAGRAM frames that is currently being dequeued.
+	// Only accessed by the packer.
+	nextBatch []*wire.DatagramFrame
 	rcvQueue  chan []byte
 
 	closeErr error
@@ -23,7 +26,7 @@ type datagramQueue struct {
 func newDatagramQueue(hasData func(), logger utils.Logger) *datagramQueue {
 	return &datagramQueue{
 		hasData:   hasData,
-		sendQueue: make(chan *wire.DatagramFrame, 1),
+		sendQueue: make(chan []*wire.DatagramFrame, 1),
 		rcvQueue:  make(chan []byte, protocol.DatagramRcvQueueLen),
 		dequeued:  make(chan struct{}),
 		closed:    make(chan struct{}),
@@ -31,11 +34,15 @@ func newDatagramQueue(hasData func(), logger utils.Logger) *datagramQueue {
 	}
 }
 
-// AddAndWait queues a new DATAGRAM frame for sending.
-// It blocks until the frame has been dequeued.
-func (h *datagramQueue) AddAndWait(f *wire.DatagramFrame) error {
+// AddAndWait queues a batch of DATAGRAM frames for sending.
+// The frames of a batch are dequeued in order, and are not interleaved with frames of other batches.
+// It blocks until all frames of the batch have been dequeued.
+func (h *datagramQueue) AddAndWait(frames ...*wire.DatagramFrame) error {
+	if len(frames) == 0 {
+		return nil
+	}
 	select {
-	case h.sendQueue <- f:
+	case h.sendQueue <- frames:
 		h.hasData()
 	case <-h.closed:
 		return h.closeErr
@@ -49,14 +56,29 @@ func (h *datagramQueue) AddAndWait(f *wire.DatagramFrame) error {
 	}
 }
 
-// Get dequeues a DATAGRAM frame for sending.
-func (h *datagramQueue) Get() *wire.DatagramFrame {
-	select {
-	case f := <-h.sendQueue:
+// Peek gets the next DATAGRAM frame for sending, without dequeueing it.
+// It returns nil if there's no frame to send.
+func (h *datagramQueue) Peek() *wire.DatagramFrame {
+	if len(h.nextBatch) == 0 {
+		select {
+		case h.nextBatch = <-h.sendQueue:
+		default:
+			return nil
+		}
+	}
+	return h.nextBatch[0]
+}
+
+// Pop dequeues the DATAGRAM frame returned by the last call to Peek.
+// Once the last frame of a batch is dequeued, AddAndWait returns.
+func (h *datagramQueue) Pop() {
+	if len(h.nextBatch) == 0 {
+		panic("datagramQueue BUG: Pop called for nil frame")
+	}
+	h.nextBatch = h.nextBatch[1:]
+	if len(h.nextBatch) == 0 {
+		h.nextBatch = nil
 		h.dequeued <- struct{}{}
-		return f
-	default:
-		return nil
 	}
 }
 
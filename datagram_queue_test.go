@@ -2,6 +2,7 @@ package quic
 
 import (
 	"errors"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -23,7 +24,7 @@ var _ = Describe("Datagram Queue", func() {
 
 	Context("sending", func() {
 		It("returns nil when there's no datagram to send", func() {
-			Expect(queue.Get()).To(BeNil())
+			Expect(queue.Peek()).To(BeNil())
 		})
 
 		It("queues a datagram", func() {
@@ -36,11 +37,73 @@ var _ = Describe("Datagram Queue", func() {
 
 			Eventually(queued).Should(HaveLen(1))
 			Consistently(done).ShouldNot(BeClosed())
-			f := queue.Get()
+			f := queue.Peek()
 			Expect(f).ToNot(BeNil())
 			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(queue.Peek()).To(Equal(f))
+			queue.Pop()
 			Eventually(done).Should(BeClosed())
-			Expect(queue.Get()).To(BeNil())
+			Expect(queue.Peek()).To(BeNil())
+		})
+
+		It("queues a batch of datagrams", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				Expect(queue.AddAndWait(
+					&wire.DatagramFrame{Data: []byte("foo")},
+					&wire.DatagramFrame{Data: []byte("bar")},
+					&wire.DatagramFrame{Data: []byte("baz")},
+				)).To(Succeed())
+			}()
+
+			Eventually(queued).Should(HaveLen(1))
+			for _, data := range []string{"foo", "bar", "baz"} {
+				Consistently(done, scaleDuration(20*time.Millisecond)).ShouldNot(BeClosed())
+				f := queue.Peek()
+				Expect(f).ToNot(BeNil())
+				Expect(f.Data).To(Equal([]byte(data)))
+				queue.Pop()
+			}
+			Eventually(done).Should(BeClosed())
+			Expect(queue.Peek()).To(BeNil())
+			Expect(queued).To(HaveLen(1))
+		})
+
+		It("doesn't interleave batches", func() {
+			done := make(chan struct{}, 2)
+			go func() {
+				defer GinkgoRecover()
+				Expect(queue.AddAndWait(
+					&wire.DatagramFrame{Data: []byte("foo")},
+					&wire.DatagramFrame{Data: []byte("bar")},
+				)).To(Succeed())
+				done <- struct{}{}
+			}()
+			Eventually(queued).Should(HaveLen(1))
+			go func() {
+				defer GinkgoRecover()
+				Expect(queue.AddAndWait(&wire.DatagramFrame{Data: []byte("baz")})).To(Succeed())
+				done <- struct{}{}
+			}()
+
+			Expect(queue.Peek().Data).To(Equal([]byte("foo")))
+			queue.Pop()
+			Eventually(queued).Should(HaveLen(2))
+			Expect(queue.Peek().Data).To(Equal([]byte("bar")))
+			queue.Pop()
+			Eventually(done).Should(Receive())
+			Expect(queue.Peek().Data).To(Equal([]byte("baz")))
+			queue.Pop()
+			Eventually(done).Should(Receive())
+			Expect(queue.Peek()).To(BeNil())
+		})
+
+		It("doesn't queue empty batches", func() {
+			Expect(queue.AddAndWait()).To(Succeed())
+			Expect(queued).To(BeEmpty())
+			Expect(queue.Peek()).To(BeNil())
 		})
 
 		It("closes", func() {
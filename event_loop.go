@@ -0,0 +1,290 @@
+package quic
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// An eventLoop drives a large number of tasks (connections) using a small number of goroutines.
+// Tasks don't run a goroutine of their own. Instead, they are scheduled whenever an event occurs
+// (e.g. a packet is received, a timer expires or the application queues data for sending),
+// and then run by one of the workers.
+// Workers are started on demand, and return as soon as there are no tasks left to run,
+// such that idle tasks don't consume any goroutines.
+// The timers of all tasks are kept in a single heap, backed by a single time.Timer.
+type eventLoop struct {
+	maxWorkers int
+
+	mutex      sync.Mutex
+	queue      []*loopTask
+	numWorkers int
+
+	timers        timerHeap
+	timer         *time.Timer
+	timerDeadline time.Time // the time when timer fires, zero if it is not armed
+	closed        bool
+}
+
+func newEventLoop(maxWorkers int) *eventLoop {
+	return &eventLoop{maxWorkers: maxWorkers}
+}
+
+type loopTaskResult uint8
+
+const (
+	// loopTaskWait means that the task has no more events to process.
+	// It will be run again when it is scheduled.
+	loopTaskWait loopTaskResult = iota
+	// loopTaskContinue means that the task might have more events to process.
+	// It is run again after all other tasks that are currently queued.
+	loopTaskContinue
+	// loopTaskDone means that the task finished.
+	// It will never be run again.
+	loopTaskDone
+)
+
+type loopTaskState uint8
+
+const (
+	loopTaskNotStarted loopTaskState = iota
+	loopTaskIdle
+	loopTaskQueued
+	loopTaskRunning
+	// the task was scheduled while it was running
+	loopTaskRunAgain
+	loopTaskFinished
+)
+
+// A loopTask is run on the event loop.
+// The event loop guarantees that a task is never run on multiple workers concurrently.
+type loopTask struct {
+	loop  *eventLoop
+	run   func() loopTaskResult
+	state loopTaskState // guarded by loop.mutex
+}
+
+// NewTask creates a new task.
+// The task won't be run before Start is called.
+func (l *eventLoop) NewTask(run func() loopTaskResult) *loopTask {
+	return &loopTask{loop: l, run: run}
+}
+
+// Start starts the task.
+// It schedules the task once, events that occurred before are handled in this run.
+func (t *loopTask) Start() {
+	t.loop.mutex.Lock()
+	defer t.loop.mutex.Unlock()
+
+	if t.state != loopTaskNotStarted {
+		return
+	}
+	t.state = loopTaskIdle
+	t.scheduleLocked()
+}
+
+// Schedule schedules the task to be run.
+// It is safe to call Schedule from any goroutine, including from the task itself.
+func (t *loopTask) Schedule() {
+	t.loop.mutex.Lock()
+	defer t.loop.mutex.Unlock()
+
+	t.scheduleLocked()
+}
+
+func (t *loopTask) scheduleLocked() {
+	switch t.state {
+	case loopTaskIdle:
+		t.state = loopTaskQueued
+		t.loop.enqueueLocked(t)
+	case loopTaskRunning:
+		t.state = loopTaskRunAgain
+	}
+}
+
+// NewTimer creates a new timer for this task.
+// When the timer expires, a value is sent on its channel, and the task is scheduled.
+func (t *loopTask) NewTimer() *loopTimer {
+	return &loopTimer{
+		task:  t,
+		c:     make(chan time.Time, 1),
+		index: -1,
+	}
+}
+
+func (l *eventLoop) enqueueLocked(t *loopTask) {
+	l.queue = append(l.queue, t)
+	if l.numWorkers < l.maxWorkers {
+		l.numWorkers++
+		go l.runWorker()
+	}
+}
+
+func (l *eventLoop) runWorker() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for len(l.queue) > 0 {
+		t := l.queue[0]
+		l.queue[0] = nil
+		l.queue = l.queue[1:]
+		t.state = loopTaskRunning
+		l.mutex.Unlock()
+		res := t.run()
+		l.mutex.Lock()
+		switch {
+		case res == loopTaskDone:
+			t.state = loopTaskFinished
+		case res == loopTaskContinue || t.state == loopTaskRunAgain:
+			t.state = loopTaskQueued
+			l.queue = append(l.queue, t)
+		default:
+			t.state = loopTaskIdle
+		}
+	}
+	l.numWorkers--
+}
+
+// Close stops the timer of the event loop.
+// It is called when all tasks have finished. Timers that are reset afterwards never fire.
+func (l *eventLoop) Close() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.closed = true
+	l.stopTimerLocked()
+}
+
+func (l *eventLoop) fireTimers() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return
+	}
+	l.timerDeadline = time.Time{}
+	now := time.Now()
+	for len(l.timers) > 0 && !l.timers[0].deadline.After(now) {
+		t := heap.Pop(&l.timers).(*loopTimer)
+		select {
+		case t.c <- now:
+		default:
+		}
+		t.task.scheduleLocked()
+	}
+	l.resetTimerLocked()
+}
+
+func (l *eventLoop) resetTimerLocked() {
+	if l.closed {
+		return
+	}
+	if len(l.timers) == 0 {
+		l.stopTimerLocked()
+		return
+	}
+	deadline := l.timers[0].deadline
+	if deadline.Equal(l.timerDeadline) {
+		return
+	}
+	l.timerDeadline = deadline
+	if l.timer == nil {
+		l.timer = time.AfterFunc(time.Until(deadline), l.fireTimers)
+		return
+	}
+	l.timer.Reset(time.Until(deadline))
+}
+
+func (l *eventLoop) stopTimerLocked() {
+	if l.timer != nil && !l.timerDeadline.IsZero() {
+		l.timer.Stop()
+	}
+	l.timerDeadline = time.Time{}
+}
+
+// A loopTimer is a timer managed by the event loop.
+// It has the same semantics as the utils.Timer.
+type loopTimer struct {
+	task     *loopTask
+	c        chan time.Time
+	read     bool
+	deadline time.Time // guarded by the event loop's mutex
+	index    int       // the index in the timer heap, -1 if the timer is not in the heap
+}
+
+// Chan returns the channel that a value is sent on when the timer expires.
+func (t *loopTimer) Chan() <-chan time.Time {
+	return t.c
+}
+
+// Reset the timer, no matter whether the value was read or not.
+// A zero deadline stops the timer.
+func (t *loopTimer) Reset(deadline time.Time) {
+	l := t.task.loop
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if deadline.Equal(t.deadline) && !t.read {
+		// No need to reset the timer
+		return
+	}
+	// drain the value if it was not read yet
+	select {
+	case <-t.c:
+	default:
+	}
+	t.deadline = deadline
+	t.read = false
+	switch {
+	case deadline.IsZero():
+		if t.index >= 0 {
+			heap.Remove(&l.timers, t.index)
+			l.resetTimerLocked()
+		}
+		return
+	case t.index >= 0:
+		heap.Fix(&l.timers, t.index)
+	default:
+		heap.Push(&l.timers, t)
+	}
+	l.resetTimerLocked()
+}
+
+// SetRead should be called after the value from the chan was read.
+func (t *loopTimer) SetRead() {
+	t.read = true
+}
+
+// Stop stops the timer.
+func (t *loopTimer) Stop() {
+	t.Reset(time.Time{})
+}
+
+type timerHeap []*loopTimer
+
+var _ heap.Interface = &timerHeap{}
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x interface{}) {
+	t := x.(*loopTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
@@ -0,0 +1,212 @@
+package quic
+
+import (
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Event Loop", func() {
+	It("runs a task when it is started", func() {
+		loop := newEventLoop(1)
+		ran := make(chan struct{}, 10)
+		task := loop.NewTask(func() loopTaskResult {
+			ran <- struct{}{}
+			return loopTaskWait
+		})
+		task.Schedule()
+		Consistently(ran).ShouldNot(Receive())
+		task.Start()
+		Eventually(ran).Should(Receive())
+		Consistently(ran).ShouldNot(Receive())
+		task.Schedule()
+		Eventually(ran).Should(Receive())
+		Consistently(ran).ShouldNot(Receive())
+	})
+
+	It("runs a task again if it was scheduled while running", func() {
+		loop := newEventLoop(4)
+		var counter, running int32
+		unblock := make(chan struct{})
+		var task *loopTask
+		task = loop.NewTask(func() loopTaskResult {
+			Expect(atomic.AddInt32(&running, 1)).To(BeEquivalentTo(1))
+			defer atomic.AddInt32(&running, -1)
+			if atomic.AddInt32(&counter, 1) == 1 {
+				task.Schedule()
+				task.Schedule()
+				<-unblock
+			}
+			return loopTaskWait
+		})
+		task.Start()
+		Eventually(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(1))
+		close(unblock)
+		Eventually(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(2))
+		Consistently(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(2))
+	})
+
+	It("runs a task until it has no more events to process", func() {
+		loop := newEventLoop(1)
+		var counter int32
+		task := loop.NewTask(func() loopTaskResult {
+			if atomic.AddInt32(&counter, 1) < 5 {
+				return loopTaskContinue
+			}
+			return loopTaskWait
+		})
+		task.Start()
+		Eventually(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(5))
+		Consistently(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(5))
+	})
+
+	It("doesn't run a task after it finished", func() {
+		loop := newEventLoop(1)
+		var counter int32
+		task := loop.NewTask(func() loopTaskResult {
+			atomic.AddInt32(&counter, 1)
+			return loopTaskDone
+		})
+		task.Start()
+		Eventually(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(1))
+		task.Schedule()
+		Consistently(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(1))
+	})
+
+	It("limits the number of workers", func() {
+		loop := newEventLoop(2)
+		var running, maxRunning, counter int32
+		for i := 0; i < 10; i++ {
+			loop.NewTask(func() loopTaskResult {
+				r := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				atomic.AddInt32(&counter, 1)
+				return loopTaskWait
+			}).Start()
+		}
+		Eventually(func() int32 { return atomic.LoadInt32(&counter) }).Should(BeEquivalentTo(10))
+		Expect(atomic.LoadInt32(&maxRunning)).To(BeEquivalentTo(2))
+		// all workers return once there are no more tasks to run
+		Eventually(func() int {
+			loop.mutex.Lock()
+			defer loop.mutex.Unlock()
+			return loop.numWorkers
+		}).Should(BeZero())
+	})
+
+	Context("timers", func() {
+		var (
+			loop *eventLoop
+			task *loopTask
+			ran  chan struct{}
+		)
+
+		BeforeEach(func() {
+			loop = newEventLoop(1)
+			r := make(chan struct{}, 100)
+			ran = r
+			task = loop.NewTask(func() loopTaskResult {
+				r <- struct{}{}
+				return loopTaskWait
+			})
+			task.Start()
+			Eventually(ran).Should(Receive())
+		})
+
+		It("fires and schedules the task", func() {
+			t := task.NewTimer()
+			t.Reset(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+			Consistently(ran, scaleDuration(10*time.Millisecond)).ShouldNot(Receive())
+			Eventually(ran).Should(Receive())
+			Expect(t.Chan()).To(Receive())
+		})
+
+		It("fires timers in order", func() {
+			t1 := task.NewTimer()
+			t2 := task.NewTimer()
+			t3 := task.NewTimer()
+			now := time.Now()
+			t1.Reset(now.Add(scaleDuration(250 * time.Millisecond)))
+			t2.Reset(now.Add(scaleDuration(50 * time.Millisecond)))
+			t3.Reset(now.Add(scaleDuration(150 * time.Millisecond)))
+			Eventually(t2.Chan(), scaleDuration(time.Second), time.Millisecond).Should(Receive())
+			Expect(t1.Chan()).ToNot(Receive())
+			Expect(t3.Chan()).ToNot(Receive())
+			Eventually(t3.Chan(), scaleDuration(time.Second), time.Millisecond).Should(Receive())
+			Expect(t1.Chan()).ToNot(Receive())
+			Eventually(t1.Chan(), scaleDuration(time.Second), time.Millisecond).Should(Receive())
+		})
+
+		It("resets a timer", func() {
+			t := task.NewTimer()
+			t.Reset(time.Now().Add(scaleDuration(10 * time.Millisecond)))
+			t.Reset(time.Now().Add(scaleDuration(50 * time.Millisecond)))
+			Consistently(t.Chan(), scaleDuration(30*time.Millisecond)).ShouldNot(Receive())
+			Eventually(t.Chan()).Should(Receive())
+		})
+
+		It("drains the channel when a timer is reset", func() {
+			t := task.NewTimer()
+			t.Reset(time.Now().Add(5 * time.Millisecond))
+			Eventually(ran).Should(Receive())
+			Expect(t.Chan()).To(HaveLen(1))
+			t.Reset(time.Now().Add(time.Hour))
+			Expect(t.Chan()).To(BeEmpty())
+		})
+
+		It("doesn't drain the channel when reset to the same deadline", func() {
+			t := task.NewTimer()
+			deadline := time.Now().Add(5 * time.Millisecond)
+			t.Reset(deadline)
+			Eventually(ran).Should(Receive())
+			t.Reset(deadline)
+			Expect(t.Chan()).To(Receive())
+		})
+
+		It("stops a timer", func() {
+			t := task.NewTimer()
+			t.Reset(time.Now().Add(10 * time.Millisecond))
+			t.Stop()
+			Consistently(t.Chan(), 30*time.Millisecond).ShouldNot(Receive())
+			Expect(ran).ToNot(Receive())
+		})
+
+		It("stops the timer when the last timer is stopped", func() {
+			t1 := task.NewTimer()
+			t2 := task.NewTimer()
+			t1.Reset(time.Now().Add(time.Hour))
+			t2.Reset(time.Now().Add(2 * time.Hour))
+			loop.mutex.Lock()
+			Expect(loop.timerDeadline).ToNot(BeZero())
+			loop.mutex.Unlock()
+			t1.Stop()
+			loop.mutex.Lock()
+			Expect(loop.timerDeadline).To(BeTemporally("~", time.Now().Add(2*time.Hour), time.Second))
+			loop.mutex.Unlock()
+			t2.Stop()
+			loop.mutex.Lock()
+			Expect(loop.timerDeadline).To(BeZero())
+			loop.mutex.Unlock()
+		})
+
+		It("doesn't fire timers after it was closed", func() {
+			t := task.NewTimer()
+			t.Reset(time.Now().Add(scaleDuration(10 * time.Millisecond)))
+			loop.Close()
+			t2 := task.NewTimer()
+			t2.Reset(time.Now().Add(scaleDuration(10 * time.Millisecond)))
+			Consistently(t.Chan(), scaleDuration(30*time.Millisecond)).ShouldNot(Receive())
+			Expect(t2.Chan()).ToNot(Receive())
+			Expect(ran).ToNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/quicpacket"
+)
+
+// retryTokenValidity is the time a client has to use a Retry token.
+const retryTokenValidity = 10 * time.Second
+
+// The minimum size of a datagram carrying a client's Initial packet, see section 14.1 of RFC 9000.
+const minInitialDatagramSize = 1200
+
+const maxPacketSize = 1500
+
+// The balancer forwards packets to the backends.
+// It is stateless: The backend is determined by the connection ID of each packet.
+// Connection IDs chosen by the backends encode the server ID (see quicpacket.ServerIDGenerator),
+// and the backend for new connections is determined by hashing the destination connection ID
+// chosen by the client.
+type balancer struct {
+	conn     *net.UDPConn // the socket the clients send packets to
+	internal *net.UDPConn // the socket used to talk to the backends
+
+	backends    []*net.UDPAddr
+	configID    uint8
+	serverIDLen int
+	connIDLen   int
+
+	// retry is used to offload the sending of Retry packets from the backends.
+	// It is nil if Retry offload is disabled.
+	retry *quicpacket.RetryTokenProtector
+}
+
+func (b *balancer) run() error {
+	errChan := make(chan error, 2)
+	go func() { errChan <- b.runClientSide() }()
+	go func() { errChan <- b.runBackendSide() }()
+	return <-errChan
+}
+
+func (b *balancer) runClientSide() error {
+	data := make([]byte, maxPacketSize)
+	buf := make([]byte, 0, maxEncapHeaderLen+maxPacketSize)
+	for {
+		n, addr, err := b.conn.ReadFromUDP(data)
+		if err != nil {
+			return err
+		}
+		backend, err := b.handleClientPacket(data[:n], addr)
+		if err != nil {
+			log.Printf("Dropping packet from %s: %s", addr, err)
+			continue
+		}
+		if backend == nil {
+			continue
+		}
+		if _, err := b.internal.WriteToUDP(encapsulate(buf[:0], addr, data[:n]), backend); err != nil {
+			log.Printf("Error forwarding packet to %s: %s", backend, err)
+		}
+	}
+}
+
+func (b *balancer) runBackendSide() error {
+	buf := make([]byte, maxEncapHeaderLen+maxPacketSize)
+	for {
+		n, addr, err := b.internal.ReadFromUDP(buf)
+		if err != nil {
+			return err
+		}
+		if !b.isBackend(addr) {
+			log.Printf("Dropping packet from unknown backend %s", addr)
+			continue
+		}
+		clientAddr, data, err := decapsulate(buf[:n])
+		if err != nil {
+			log.Printf("Dropping packet from backend %s: %s", addr, err)
+			continue
+		}
+		if _, err := b.conn.WriteToUDP(data, clientAddr); err != nil {
+			log.Printf("Error sending packet to %s: %s", clientAddr, err)
+		}
+	}
+}
+
+func (b *balancer) isBackend(addr *net.UDPAddr) bool {
+	for _, backend := range b.backends {
+		if backend.IP.Equal(addr.IP) && backend.Port == addr.Port {
+			return true
+		}
+	}
+	return false
+}
+
+// handleClientPacket determines the backend a packet is forwarded to.
+// If the packet was handled by the load balancer itself (e.g. by sending a Retry), it returns nil.
+func (b *balancer) handleClientPacket(data []byte, addr *net.UDPAddr) (*net.UDPAddr, error) {
+	if !quicpacket.IsLongHeaderPacket(data) {
+		connID, err := quicpacket.ParseConnectionID(data, b.connIDLen)
+		if err != nil {
+			return nil, err
+		}
+		backend, ok := b.routeByServerID(connID)
+		if !ok {
+			return nil, errors.New("connection ID doesn't encode a server ID")
+		}
+		return backend, nil
+	}
+
+	hdr, _, _, err := quicpacket.ParsePacket(data, b.connIDLen)
+	if err != nil && err != quicpacket.ErrUnsupportedVersion {
+		return nil, err
+	}
+	if b.retry != nil && hdr.Type == quicpacket.PacketTypeInitial {
+		if len(data) < minInitialDatagramSize {
+			return nil, errors.New("Initial packet too small") //nolint:stylecheck
+		}
+		if len(hdr.Token) == 0 {
+			return nil, b.sendRetry(hdr, addr)
+		}
+		// Tokens issued by the backends (sent in NEW_TOKEN frames) can be told apart from the load balancer's tokens.
+		// They are forwarded to the backends, which will validate them.
+		if quicpacket.IsRetryToken(hdr.Token) {
+			if err := b.validateToken(hdr.Token, addr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if backend, ok := b.routeByServerID(hdr.DestConnectionID); ok {
+		return backend, nil
+	}
+	// This is a packet for a new connection, the connection ID was chosen by the client.
+	// All packets carrying the same connection ID are routed to the same backend.
+	h := fnv.New32a()
+	h.Write(hdr.DestConnectionID)
+	return b.backends[h.Sum32()%uint32(len(b.backends))], nil
+}
+
+func (b *balancer) routeByServerID(connID quicpacket.ConnectionID) (*net.UDPAddr, bool) {
+	serverID, err := quicpacket.ParseServerID(connID, b.configID, b.serverIDLen)
+	if err != nil {
+		return nil, false
+	}
+	var id uint64
+	for _, v := range serverID {
+		id = id<<8 | uint64(v)
+	}
+	if id >= uint64(len(b.backends)) {
+		return nil, false
+	}
+	return b.backends[id], true
+}
+
+func (b *balancer) sendRetry(hdr *quicpacket.Header, addr *net.UDPAddr) error {
+	srcConnID := make(quicpacket.ConnectionID, b.connIDLen)
+	if _, err := rand.Read(srcConnID); err != nil {
+		return err
+	}
+	// Use the reserved config ID, so that the connection ID will never be mistaken for one encoding a server ID.
+	srcConnID[0] |= 0xe0
+	token, err := b.retry.NewToken(addr, hdr.DestConnectionID, srcConnID)
+	if err != nil {
+		return err
+	}
+	retry, err := quicpacket.ComposeRetry(hdr.Version, hdr.SrcConnectionID, srcConnID, hdr.DestConnectionID, token)
+	if err != nil {
+		return err
+	}
+	_, err = b.conn.WriteToUDP(retry, addr)
+	return err
+}
+
+// validateToken checks Retry tokens issued by the load balancer.
+func (b *balancer) validateToken(token []byte, addr *net.UDPAddr) error {
+	t, err := b.retry.DecodeToken(token)
+	if err != nil {
+		return fmt.Errorf("invalid Retry token: %w", err)
+	}
+	if !t.ClientIP.Equal(addr.IP) {
+		return errors.New("Retry token issued for a different IP address") //nolint:stylecheck
+	}
+	if time.Since(t.SentTime) > retryTokenValidity {
+		return errors.New("Retry token expired") //nolint:stylecheck
+	}
+	return nil
+}
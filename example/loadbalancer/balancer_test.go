@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quicpacket"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// composeInitial composes an Initial packet, padded to size bytes.
+func composeInitial(destConnID, srcConnID protocol.ConnectionID, token []byte, size int) []byte {
+	hdr := &wire.ExtendedHeader{
+		Header: wire.Header{
+			IsLongHeader:     true,
+			Type:             protocol.PacketTypeInitial,
+			DestConnectionID: destConnID,
+			SrcConnectionID:  srcConnID,
+			Token:            token,
+			Version:          protocol.Version1,
+		},
+		PacketNumber:    1,
+		PacketNumberLen: protocol.PacketNumberLen4,
+	}
+	hdrLen := int(hdr.GetLength(protocol.Version1))
+	hdr.Length = protocol.ByteCount(size - hdrLen + int(hdr.PacketNumberLen))
+	buf := &bytes.Buffer{}
+	Expect(hdr.Write(buf, protocol.Version1)).To(Succeed())
+	Expect(buf.Len()).To(Equal(hdrLen))
+	buf.Write(make([]byte, size-buf.Len()))
+	return buf.Bytes()
+}
+
+func composeShortHeaderPacket(connID protocol.ConnectionID) []byte {
+	return append(append([]byte{0x40}, connID...), []byte("foobar")...)
+}
+
+func listenLocal() *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	Expect(err).ToNot(HaveOccurred())
+	return conn
+}
+
+var _ = Describe("Balancer", func() {
+	const (
+		configID    = 1
+		serverIDLen = 1
+		connIDLen   = 8
+	)
+
+	var (
+		b          *balancer
+		backends   []*net.UDPConn
+		clientConn *net.UDPConn
+		clientAddr *net.UDPAddr
+	)
+
+	generateConnID := func(serverID uint8) protocol.ConnectionID {
+		connID, err := (&quicpacket.ServerIDGenerator{ServerID: []byte{serverID}, ConfigID: configID, ConnIDLen: connIDLen}).GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		return connID
+	}
+
+	BeforeEach(func() {
+		backends = []*net.UDPConn{listenLocal(), listenLocal()}
+		clientConn = listenLocal()
+		clientAddr = clientConn.LocalAddr().(*net.UDPAddr)
+		b = &balancer{
+			conn:        listenLocal(),
+			internal:    listenLocal(),
+			configID:    configID,
+			serverIDLen: serverIDLen,
+			connIDLen:   connIDLen,
+		}
+		for _, conn := range backends {
+			b.backends = append(b.backends, conn.LocalAddr().(*net.UDPAddr))
+		}
+	})
+
+	AfterEach(func() {
+		for _, conn := range backends {
+			conn.Close()
+		}
+		clientConn.Close()
+		b.conn.Close()
+		b.internal.Close()
+	})
+
+	Context("routing", func() {
+		It("routes short header packets by the server ID", func() {
+			for i := range backends {
+				backend, err := b.handleClientPacket(composeShortHeaderPacket(generateConnID(uint8(i))), clientAddr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(backend).To(Equal(b.backends[i]))
+			}
+		})
+
+		It("drops short header packets that don't encode a valid server ID", func() {
+			_, err := b.handleClientPacket(composeShortHeaderPacket(generateConnID(42)), clientAddr)
+			Expect(err).To(MatchError("connection ID doesn't encode a server ID"))
+			connID := generateConnID(0)
+			connID[0] |= 0xe0 // reserved config ID
+			_, err = b.handleClientPacket(composeShortHeaderPacket(connID), clientAddr)
+			Expect(err).To(MatchError("connection ID doesn't encode a server ID"))
+		})
+
+		It("routes long header packets by the server ID", func() {
+			backend, err := b.handleClientPacket(composeInitial(generateConnID(1), protocol.ConnectionID{1, 2, 3, 4}, nil, 1200), clientAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(backend).To(Equal(b.backends[1]))
+		})
+
+		It("routes all packets of a new connection to the same backend", func() {
+			seen := make(map[*net.UDPAddr]struct{})
+			for i := 0; i < 20; i++ {
+				destConnID := protocol.ConnectionID{uint8(i), 0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x42}
+				backend, err := b.handleClientPacket(composeInitial(destConnID, nil, nil, 1200), clientAddr)
+				Expect(err).ToNot(HaveOccurred())
+				seen[backend] = struct{}{}
+				for j := 0; j < 3; j++ {
+					backend2, err := b.handleClientPacket(composeInitial(destConnID, nil, nil, 1200), clientAddr)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(backend2).To(Equal(backend))
+				}
+			}
+			Expect(seen).To(HaveLen(len(backends)))
+		})
+	})
+
+	Context("Retry offload", func() {
+		destConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+
+		BeforeEach(func() {
+			var err error
+			b.retry, err = quicpacket.NewRetryTokenProtector([]byte("0123456789abcdef"), 0)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("sends a Retry for Initials without a token", func() {
+			backend, err := b.handleClientPacket(composeInitial(destConnID, protocol.ConnectionID{1, 2, 3, 4}, nil, 1200), clientAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(backend).To(BeNil())
+			data := make([]byte, maxPacketSize)
+			Expect(clientConn.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+			n, addr, err := clientConn.ReadFromUDP(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal(b.conn.LocalAddr().String()))
+			hdr, _, _, err := quicpacket.ParsePacket(data[:n], 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.Type).To(Equal(quicpacket.PacketTypeRetry))
+			Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+			valid, err := quicpacket.VerifyRetryIntegrityTag(data[:n], destConnID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(valid).To(BeTrue())
+			Expect(quicpacket.IsRetryToken(hdr.Token)).To(BeTrue())
+			token, err := b.retry.DecodeToken(hdr.Token)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token.OriginalDestConnectionID).To(Equal(destConnID))
+			Expect(token.RetrySrcConnectionID).To(Equal(hdr.SrcConnectionID))
+			Expect(token.ClientIP.Equal(clientAddr.IP)).To(BeTrue())
+			// the Retry's source connection ID must not be routed by a server ID
+			_, ok := b.routeByServerID(hdr.SrcConnectionID)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("drops Initials that are too small", func() {
+			_, err := b.handleClientPacket(composeInitial(destConnID, nil, nil, 1199), clientAddr)
+			Expect(err).To(MatchError("Initial packet too small"))
+		})
+
+		It("forwards Initials with a valid Retry token", func() {
+			token, err := b.retry.NewToken(clientAddr, destConnID, protocol.ConnectionID{0xe0, 1, 2, 3, 4, 5, 6, 7})
+			Expect(err).ToNot(HaveOccurred())
+			backend, err := b.handleClientPacket(composeInitial(protocol.ConnectionID{0xe0, 1, 2, 3, 4, 5, 6, 7}, nil, token, 1200), clientAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(backend).ToNot(BeNil())
+		})
+
+		It("rejects Retry tokens issued for a different IP address", func() {
+			token, err := b.retry.NewToken(&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}, destConnID, protocol.ConnectionID{0xe0, 1, 2, 3, 4, 5, 6, 7})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = b.handleClientPacket(composeInitial(protocol.ConnectionID{0xe0, 1, 2, 3, 4, 5, 6, 7}, nil, token, 1200), clientAddr)
+			Expect(err).To(MatchError("Retry token issued for a different IP address"))
+		})
+
+		It("rejects forged Retry tokens", func() {
+			token, err := b.retry.NewToken(clientAddr, destConnID, protocol.ConnectionID{0xe0, 1, 2, 3, 4, 5, 6, 7})
+			Expect(err).ToNot(HaveOccurred())
+			token[len(token)-1] ^= 0xff
+			_, err = b.handleClientPacket(composeInitial(destConnID, nil, token, 1200), clientAddr)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid Retry token"))
+			_, err = b.handleClientPacket(composeInitial(destConnID, nil, []byte("foobar"), 1200), clientAddr)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid Retry token"))
+		})
+
+		It("forwards tokens issued by the backends", func() {
+			token := append([]byte{0x80}, make([]byte, 50)...)
+			backend, err := b.handleClientPacket(composeInitial(destConnID, nil, token, 1200), clientAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(backend).ToNot(BeNil())
+		})
+	})
+
+	Context("forwarding", func() {
+		var done chan struct{}
+
+		JustBeforeEach(func() {
+			done = make(chan struct{})
+			go func() {
+				defer close(done)
+				b.run()
+			}()
+		})
+
+		AfterEach(func() {
+			b.conn.Close()
+			b.internal.Close()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("forwards packets between clients and backends", func() {
+			packet := composeShortHeaderPacket(generateConnID(1))
+			_, err := clientConn.WriteTo(packet, b.conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			data := make([]byte, maxEncapHeaderLen+maxPacketSize)
+			Expect(backends[1].SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+			n, addr, err := backends[1].ReadFromUDP(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal(b.internal.LocalAddr().String()))
+			from, payload, err := decapsulate(data[:n])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(from.String()).To(Equal(clientAddr.String()))
+			Expect(payload).To(Equal(packet))
+
+			_, err = backends[1].WriteTo(encapsulate(nil, clientAddr, []byte("response")), b.internal.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clientConn.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+			n, addr, err = clientConn.ReadFromUDP(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal(b.conn.LocalAddr().String()))
+			Expect(data[:n]).To(Equal([]byte("response")))
+		})
+
+		It("drops packets from unknown backends", func() {
+			other := listenLocal()
+			defer other.Close()
+			_, err := other.WriteTo(encapsulate(nil, clientAddr, []byte("foobar")), b.internal.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clientConn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).To(Succeed())
+			_, _, err = clientConn.ReadFromUDP(make([]byte, maxPacketSize))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(net.Error).Timeout()).To(BeTrue())
+		})
+
+		Context("Retry offload", func() {
+			var (
+				ln     quic.Listener
+				tokens chan *quic.Token
+			)
+
+			BeforeEach(func() {
+				key := []byte("0123456789abcdef")
+				var err error
+				b.retry, err = quicpacket.NewRetryTokenProtector(key, 0)
+				Expect(err).ToNot(HaveOccurred())
+				// route all new connections to the QUIC server
+				b.backends = b.backends[:1]
+				tokens = make(chan *quic.Token, 10)
+				tlsConf := testdata.GetTLSConfig()
+				tlsConf.NextProtos = []string{"loadbalancer"}
+				ln, err = quic.Listen(
+					&encapConn{PacketConn: backends[0], lbAddr: b.internal.LocalAddr()},
+					tlsConf,
+					&quic.Config{
+						ConnectionIDGenerator: &quicpacket.ServerIDGenerator{ServerID: []byte{0}, ConfigID: configID, ConnIDLen: connIDLen},
+						RetryOffloadKey:       key,
+						AcceptToken: func(_ net.Addr, token *quic.Token) bool {
+							tokens <- token
+							return token != nil
+						},
+						DisablePathMTUDiscovery: true,
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				ln.Close()
+			})
+
+			It("offloads the Retry for a QUIC server", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				conn, err := quic.DialContext(
+					ctx,
+					clientConn,
+					b.conn.LocalAddr(),
+					"localhost",
+					&tls.Config{RootCAs: testdata.GetRootCA(), NextProtos: []string{"loadbalancer"}},
+					&quic.Config{DisablePathMTUDiscovery: true},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.CloseWithError(0, "")
+				var token *quic.Token
+				Expect(tokens).To(Receive(&token))
+				Expect(token).ToNot(BeNil())
+				Expect(token.IsRetryToken).To(BeTrue())
+				Expect(tokens).ToNot(Receive())
+			})
+		})
+	})
+})
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// Packets are exchanged between the load balancer and the backends using a simple encapsulation:
+// The original UDP datagram is prefixed with the address of the client.
+// The first byte is the length of the IP address (4 or 16), followed by the IP address and the port (2 bytes).
+// This is used in both directions: Packets forwarded to a backend carry the address of the client that sent them,
+// and packets sent by a backend carry the address of the client they're destined to.
+const maxEncapHeaderLen = 1 + net.IPv6len + 2
+
+func encapsulate(b []byte, addr *net.UDPAddr, data []byte) []byte {
+	ip := addr.IP.To4()
+	if ip == nil {
+		ip = addr.IP.To16()
+	}
+	b = append(b, uint8(len(ip)))
+	b = append(b, ip...)
+	b = append(b, uint8(addr.Port>>8), uint8(addr.Port))
+	return append(b, data...)
+}
+
+func decapsulate(b []byte) (*net.UDPAddr, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("empty packet")
+	}
+	ipLen := int(b[0])
+	if ipLen != net.IPv4len && ipLen != net.IPv6len {
+		return nil, nil, errors.New("invalid IP address length")
+	}
+	if len(b) < 1+ipLen+2 {
+		return nil, nil, errors.New("packet too short")
+	}
+	ip := make(net.IP, ipLen)
+	copy(ip, b[1:1+ipLen])
+	port := binary.BigEndian.Uint16(b[1+ipLen:])
+	return &net.UDPAddr{IP: ip, Port: int(port)}, b[1+ipLen+2:], nil
+}
+
+// An encapConn is used by the backends.
+// It reads encapsulated packets from the load balancer, and encapsulates the packets it sends.
+// For the QUIC server using it, it looks as if it was directly talking to the clients.
+type encapConn struct {
+	net.PacketConn
+	lbAddr net.Addr
+}
+
+var _ net.PacketConn = &encapConn{}
+
+func (c *encapConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	buf := make([]byte, len(p)+maxEncapHeaderLen)
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(buf)
+		if err != nil {
+			return 0, nil, err
+		}
+		if addr.String() != c.lbAddr.String() {
+			continue
+		}
+		clientAddr, data, err := decapsulate(buf[:n])
+		if err != nil {
+			continue
+		}
+		return copy(p, data), clientAddr, nil
+	}
+}
+
+func (c *encapConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, errors.New("expected a UDP address")
+	}
+	if _, err := c.PacketConn.WriteTo(encapsulate(make([]byte, 0, maxEncapHeaderLen+len(p)), udpAddr, p), c.lbAddr); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
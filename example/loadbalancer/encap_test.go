@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encapsulation", func() {
+	It("encapsulates and decapsulates packets from IPv4 addresses", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+		b := encapsulate(nil, addr, []byte("foobar"))
+		Expect(b).To(HaveLen(1 + net.IPv4len + 2 + 6))
+		Expect(b[0]).To(BeEquivalentTo(net.IPv4len))
+		a, data, err := decapsulate(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(a.IP.Equal(addr.IP)).To(BeTrue())
+		Expect(a.Port).To(Equal(1337))
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+
+	It("encapsulates and decapsulates packets from IPv6 addresses", func() {
+		addr := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4242}
+		b := encapsulate(nil, addr, []byte("foobar"))
+		Expect(b).To(HaveLen(maxEncapHeaderLen + 6))
+		a, data, err := decapsulate(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(a.IP).To(Equal(addr.IP))
+		Expect(a.Port).To(Equal(4242))
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+
+	It("appends to the buffer", func() {
+		b := encapsulate(make([]byte, 0, 100), &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, []byte("foo"))
+		Expect(cap(b)).To(Equal(100))
+	})
+
+	It("errors on empty packets", func() {
+		_, _, err := decapsulate(nil)
+		Expect(err).To(MatchError("empty packet"))
+	})
+
+	It("errors on invalid IP address lengths", func() {
+		_, _, err := decapsulate([]byte{5, 1, 2, 3, 4, 5, 0, 1})
+		Expect(err).To(MatchError("invalid IP address length"))
+	})
+
+	It("errors on packets that are too short", func() {
+		b := encapsulate(nil, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, nil)
+		for i := 1; i < len(b); i++ {
+			_, _, err := decapsulate(b[:i])
+			Expect(err).To(MatchError("packet too short"))
+		}
+		_, data, err := decapsulate(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(BeEmpty())
+	})
+
+	Context("encapsulating connection", func() {
+		var (
+			lb, other *net.UDPConn
+			conn      *encapConn
+		)
+		clientAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+
+		BeforeEach(func() {
+			var err error
+			lb, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			other, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			conn = &encapConn{PacketConn: c, lbAddr: lb.LocalAddr()}
+		})
+
+		AfterEach(func() {
+			lb.Close()
+			other.Close()
+			conn.Close()
+		})
+
+		It("reads packets from the load balancer", func() {
+			_, err := other.WriteTo(encapsulate(nil, clientAddr, []byte("not from the load balancer")), conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = lb.WriteTo([]byte{42}, conn.LocalAddr()) // not a valid encapsulated packet
+			Expect(err).ToNot(HaveOccurred())
+			_, err = lb.WriteTo(encapsulate(nil, clientAddr, []byte("foobar")), conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 100)
+			Expect(conn.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+			n, addr, err := conn.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+			Expect(addr.String()).To(Equal(clientAddr.String()))
+		})
+
+		It("sends packets to the load balancer", func() {
+			n, err := conn.WriteTo([]byte("foobar"), clientAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			b := make([]byte, 100)
+			Expect(lb.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+			n, _, err = lb.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			addr, data, err := decapsulate(b[:n])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal(clientAddr.String()))
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("refuses to send packets to non-UDP addresses", func() {
+			_, err := conn.WriteTo([]byte("foobar"), &net.TCPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234})
+			Expect(err).To(MatchError("expected a UDP address"))
+		})
+	})
+})
@@ -0,0 +1,147 @@
+// The loadbalancer command is a stateless, QUIC-aware UDP load balancer.
+//
+// Packets for new connections are routed by hashing the destination connection ID chosen by the client.
+// Packets for established connections are routed by the server ID that the backends encode into
+// their connection IDs (using the plaintext algorithm of the QUIC-LB draft).
+// Optionally, the load balancer performs address validation on behalf of the backends, by sending
+// Retry packets with tokens protected by a shared key. The backends need to be configured with the same key
+// (quic.Config.RetryOffloadKey) to accept these tokens.
+//
+// Packets are forwarded between load balancer and backends using a simple encapsulation that carries the
+// client's address, see encap.go. The load balancer sends the backends' packets to the clients from its own address.
+// Preserving the client's source address when forwarding to the backends (as it would be done using IP_TRANSPARENT
+// and direct server return) requires elevated privileges and network configuration, and is not implemented here.
+//
+// To test a multi-instance deployment locally, run two backends and the load balancer:
+//
+//	go run ./example/loadbalancer -backend -server-id 0 -listen localhost:6001 -lb localhost:6000
+//	go run ./example/loadbalancer -backend -server-id 1 -listen localhost:6002 -lb localhost:6000
+//	go run ./example/loadbalancer -listen localhost:6121 -internal localhost:6000 -backends localhost:6001,localhost:6002
+//
+// and use the example client to send requests to https://localhost:6121.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/quicpacket"
+)
+
+func main() {
+	listen := flag.String("listen", "localhost:6121", "address to listen on")
+	internal := flag.String("internal", "localhost:6000", "address used to communicate with the backends")
+	backends := flag.String("backends", "", "comma-separated list of backend addresses. The index in the list is the server ID")
+	configID := flag.Uint("config-id", 0, "config ID encoded in the connection IDs")
+	serverIDLen := flag.Int("server-id-len", 1, "length of the server ID, in bytes")
+	connIDLen := flag.Int("conn-id-len", 8, "length of the connection IDs used by the backends")
+	retryKey := flag.String("retry-key", "", "hex-encoded 16 byte key shared with the backends, used to protect Retry tokens. Retry offload is disabled if empty")
+	backend := flag.Bool("backend", false, "run a backend HTTP/3 server")
+	serverID := flag.Uint("server-id", 0, "server ID of the backend")
+	lb := flag.String("lb", "localhost:6000", "internal address of the load balancer (only used with -backend)")
+	flag.Parse()
+
+	if *configID > quicpacket.MaxConfigID {
+		log.Fatalf("invalid config ID: %d", *configID)
+	}
+	if *serverIDLen < 1 || *serverIDLen >= *connIDLen {
+		log.Fatalf("invalid server ID length: %d", *serverIDLen)
+	}
+
+	var key []byte
+	if len(*retryKey) > 0 {
+		var err error
+		key, err = hex.DecodeString(*retryKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *backend {
+		if err := runBackend(*listen, *lb, *serverID, *serverIDLen, uint8(*configID), *connIDLen, key); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	b := &balancer{
+		configID:    uint8(*configID),
+		serverIDLen: *serverIDLen,
+		connIDLen:   *connIDLen,
+	}
+	if len(*backends) == 0 {
+		log.Fatal("no backends configured")
+	}
+	for _, s := range strings.Split(*backends, ",") {
+		addr, err := net.ResolveUDPAddr("udp", s)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b.backends = append(b.backends, addr)
+	}
+	if key != nil {
+		var err error
+		b.retry, err = quicpacket.NewRetryTokenProtector(key, 0)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	var err error
+	if b.conn, err = listenUDP(*listen); err != nil {
+		log.Fatal(err)
+	}
+	if b.internal, err = listenUDP(*internal); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Load balancing %s to %s", b.conn.LocalAddr(), *backends)
+	log.Fatal(b.run())
+}
+
+func listenUDP(addr string) (*net.UDPConn, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenUDP("udp", udpAddr)
+}
+
+func runBackend(listen, lb string, serverID uint, serverIDLen int, configID uint8, connIDLen int, retryKey []byte) error {
+	lbAddr, err := net.ResolveUDPAddr("udp", lb)
+	if err != nil {
+		return err
+	}
+	conn, err := listenUDP(listen)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, serverIDLen)
+	for i, v := 0, serverID; i < serverIDLen; i, v = i+1, v>>8 {
+		id[serverIDLen-1-i] = uint8(v)
+	}
+	server := http3.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "Hello from backend %d\n", serverID)
+		}),
+		TLSConfig: testdata.GetTLSConfig(),
+		QuicConfig: &quic.Config{
+			ConnectionIDGenerator: &quicpacket.ServerIDGenerator{
+				ServerID:  id,
+				ConfigID:  configID,
+				ConnIDLen: connIDLen,
+			},
+			RetryOffloadKey: retryKey,
+			// Encapsulation adds some overhead to every packet.
+			DisablePathMTUDiscovery: true,
+		},
+	}
+	log.Printf("Backend %d listening on %s", serverID, conn.LocalAddr())
+	return server.Serve(&encapConn{PacketConn: conn, lbAddr: lbAddr})
+}
@@ -0,0 +1,138 @@
+// The perf command runs a client or a server of the QUIC performance measurement protocol (see the perf package).
+// Since the protocol is implemented by multiple QUIC stacks, it can be used to compare quic-go with other stacks,
+// and to compare different versions of quic-go.
+//
+// Start a server:
+//
+//	go run ./example/perf -server -addr localhost:4433
+//
+// Measure the throughput of a 100 MB download:
+//
+//	go run ./example/perf -addr localhost:4433 -download 100000000
+//
+// Measure the request latency of 1000 small requests, 10 at a time:
+//
+//	go run ./example/perf -addr localhost:4433 -requests 1000 -parallel 10 -download 1000
+//
+// Measure the number of handshakes per second:
+//
+//	go run ./example/perf -addr localhost:4433 -conns 1000 -parallel 10 -download 1
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/perf"
+)
+
+func main() {
+	server := flag.Bool("server", false, "run the server")
+	addr := flag.String("addr", "localhost:4433", "address to listen on (server) / to connect to (client)")
+	version := flag.String("version", "", "QUIC version: 1 or draft29. If not set, all supported versions are used")
+	cc := flag.String("cc", "reno", "congestion control algorithm: reno or cubic")
+	upload := flag.Uint64("upload", 0, "bytes to upload with every request")
+	download := flag.Uint64("download", 0, "bytes to download with every request")
+	conns := flag.Int("conns", 1, "number of connections")
+	requests := flag.Int("requests", 1, "number of requests per connection")
+	parallel := flag.Int("parallel", 1, "number of concurrent connections, and of concurrent requests per connection")
+	insecure := flag.Bool("insecure", false, "skip certificate verification")
+	flag.Parse()
+
+	quicConf := &quic.Config{
+		// allow enough streams for the parallel requests
+		MaxIncomingStreams: 1000,
+	}
+	switch *version {
+	case "":
+	case "1":
+		quicConf.Versions = []quic.VersionNumber{quic.Version1}
+	case "draft29":
+		quicConf.Versions = []quic.VersionNumber{quic.VersionDraft29}
+	default:
+		log.Fatalf("unknown QUIC version: %s", *version)
+	}
+	switch *cc {
+	case "reno":
+		quicConf.CongestionControl = quic.CongestionControlNewReno
+	case "cubic":
+		quicConf.CongestionControl = quic.CongestionControlCubic
+	default:
+		log.Fatalf("unknown congestion control algorithm: %s", *cc)
+	}
+
+	if *server {
+		if err := runServer(*addr, quicConf); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	tlsConf := &tls.Config{
+		RootCAs:            testdata.GetRootCA(),
+		InsecureSkipVerify: *insecure,
+		NextProtos:         []string{perf.ALPN},
+	}
+	res, err := perf.Run(context.Background(), &perf.ClientConfig{
+		Dial: func(ctx context.Context) (quic.Connection, error) {
+			return quic.DialAddrContext(ctx, *addr, tlsConf, quicConf)
+		},
+		Connections:           *conns,
+		RequestsPerConnection: *requests,
+		Parallel:              *parallel,
+		UploadBytes:           *upload,
+		DownloadBytes:         *download,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	printResults(res)
+}
+
+func runServer(addr string, quicConf *quic.Config) error {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{perf.ALPN}
+	ln, err := quic.ListenAddr(addr, tlsConf, quicConf)
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening on %s", ln.Addr())
+	return perf.RunServer(ln)
+}
+
+func printResults(res *perf.Results) {
+	fmt.Printf("duration:        %s\n", res.Duration)
+	fmt.Printf("handshakes:      %d (%.1f/s)\n", res.Handshakes, res.HandshakesPerSecond())
+	fmt.Printf("requests:        %d (%.1f/s)\n", len(res.RequestLatencies), res.RequestsPerSecond())
+	fmt.Printf("upload:          %d bytes (%s)\n", res.BytesUploaded, formatThroughput(res.UploadThroughput()))
+	fmt.Printf("download:        %d bytes (%s)\n", res.BytesDownloaded, formatThroughput(res.DownloadThroughput()))
+	printLatencies("handshake latency", res.HandshakeLatencies)
+	printLatencies("request latency", res.RequestLatencies)
+}
+
+func printLatencies(name string, latencies []time.Duration) {
+	fmt.Printf("%s: p50 %s, p90 %s, p99 %s, max %s\n",
+		name,
+		perf.Percentile(latencies, 50),
+		perf.Percentile(latencies, 90),
+		perf.Percentile(latencies, 99),
+		perf.Percentile(latencies, 100),
+	)
+}
+
+func formatThroughput(bps float64) string {
+	switch {
+	case bps >= 1e9:
+		return fmt.Sprintf("%.2f Gbit/s", bps/1e9)
+	case bps >= 1e6:
+		return fmt.Sprintf("%.2f Mbit/s", bps/1e6)
+	default:
+		return fmt.Sprintf("%.2f kbit/s", bps/1e3)
+	}
+}
@@ -2,31 +2,50 @@ package quic
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
+// maxMergedEntrySize is the maximum size of an entry created by merging adjacent small entries.
+const maxMergedEntrySize = protocol.MaxPacketBufferSize
+
 type frameSorterEntry struct {
 	Data   []byte
 	DoneCb func()
+	// Set if Data was allocated by the frameSorter.
+	// Data can then be appended to when merging entries.
+	owned bool
+}
+
+// mergeable says if the entry can be merged with an adjacent entry.
+// Small entries are merged, such that the frame sorter doesn't keep many small buffers.
+func (e *frameSorterEntry) mergeable() bool {
+	return e.owned || len(e.Data) < protocol.MinStreamFrameBufferSize
 }
 
 type frameSorter struct {
-	queue   map[protocol.ByteCount]frameSorterEntry
-	readPos protocol.ByteCount
-	gaps    *utils.ByteIntervalList
+	queue map[protocol.ByteCount]frameSorterEntry
+	// The start offsets of the mergeable entries in the queue, indexed by their end offset.
+	// It is used to find the entry that data received directly after it can be merged with.
+	mergeable map[protocol.ByteCount]protocol.ByteCount
+	readPos   protocol.ByteCount
+	// The gaps in the received data, sorted by their offset.
+	// The last gap always extends to protocol.MaxByteCount.
+	// Gaps are found using binary search, so the cost of handling a frame
+	// only grows logarithmically with the number of gaps.
+	gaps []utils.ByteInterval
 }
 
 var errDuplicateStreamData = errors.New("duplicate stream data")
 
 func newFrameSorter() *frameSorter {
-	s := frameSorter{
-		gaps:  utils.NewByteIntervalList(),
-		queue: make(map[protocol.ByteCount]frameSorterEntry),
+	return &frameSorter{
+		gaps:      []utils.ByteInterval{{Start: 0, End: protocol.MaxByteCount}},
+		queue:     make(map[protocol.ByteCount]frameSorterEntry),
+		mergeable: make(map[protocol.ByteCount]protocol.ByteCount),
 	}
-	s.gaps.PushFront(utils.ByteInterval{Start: 0, End: protocol.MaxByteCount})
-	return &s
 }
 
 func (s *frameSorter) Push(data []byte, offset protocol.ByteCount, doneCb func()) error {
@@ -48,24 +67,26 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 	start := offset
 	end := offset + protocol.ByteCount(len(data))
 
-	if end <= s.gaps.Front().Value.Start {
+	if end <= s.gaps[0].Start {
 		return errDuplicateStreamData
 	}
 
-	startGap, startsInGap := s.findStartGap(start)
-	endGap, endsInGap := s.findEndGap(startGap, end)
+	startGapIndex, startsInGap := s.findStartGap(start)
+	endGapIndex, endsInGap := s.findEndGap(startGapIndex, end)
+	startGap := s.gaps[startGapIndex]
+	endGap := s.gaps[endGapIndex]
 
-	startGapEqualsEndGap := startGap == endGap
+	startGapEqualsEndGap := startGapIndex == endGapIndex
 
-	if (startGapEqualsEndGap && end <= startGap.Value.Start) ||
-		(!startGapEqualsEndGap && startGap.Value.End >= endGap.Value.Start && end <= startGap.Value.Start) {
+	if (startGapEqualsEndGap && end <= startGap.Start) ||
+		(!startGapEqualsEndGap && startGap.End >= endGap.Start && end <= startGap.Start) {
 		return errDuplicateStreamData
 	}
 
-	startGapNext := startGap.Next()
-	startGapEnd := startGap.Value.End // save it, in case startGap is modified
-	endGapStart := endGap.Value.Start // save it, in case endGap is modified
-	endGapEnd := endGap.Value.End     // save it, in case endGap is modified
+	// The gaps from startGapIndex to endGapIndex are replaced by (up to) two new gaps.
+	var newGaps [2]utils.ByteInterval
+	var numNewGaps int
+
 	var adjustedStartGapEnd bool
 	var wasCut bool
 
@@ -79,7 +100,7 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
 		if end-pos > oldEntryLen || (hasReplacedAtLeastOne && end-pos == oldEntryLen) {
 			// The existing frame is shorter than the new frame. Replace it.
-			delete(s.queue, pos)
+			s.removeEntry(pos)
 			pos += oldEntryLen
 			hasReplacedAtLeastOne = true
 			if oldEntry.DoneCb != nil {
@@ -100,119 +121,191 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 
 	if !startsInGap && !hasReplacedAtLeastOne {
 		// cut the frame, such that it starts at the start of the gap
-		data = data[startGap.Value.Start-start:]
-		start = startGap.Value.Start
+		data = data[startGap.Start-start:]
+		start = startGap.Start
 		wasCut = true
 	}
-	if start <= startGap.Value.Start {
-		if end >= startGap.Value.End {
+	keepStartGap := true
+	newStartGap := startGap
+	if start <= startGap.Start {
+		if end >= startGap.End {
 			// The frame covers the whole startGap. Delete the gap.
-			s.gaps.Remove(startGap)
+			keepStartGap = false
 		} else {
-			startGap.Value.Start = end
+			newStartGap.Start = end
 		}
 	} else if !hasReplacedAtLeastOne {
-		startGap.Value.End = start
+		newStartGap.End = start
 		adjustedStartGapEnd = true
 	}
+	if keepStartGap {
+		newGaps[numNewGaps] = newStartGap
+		numNewGaps++
+	}
 
 	if !startGapEqualsEndGap {
-		s.deleteConsecutive(startGapEnd)
-		var nextGap *utils.ByteIntervalElement
-		for gap := startGapNext; gap.Value.End < endGapStart; gap = nextGap {
-			nextGap = gap.Next()
-			s.deleteConsecutive(gap.Value.End)
-			s.gaps.Remove(gap)
+		s.deleteConsecutive(startGap.End)
+		for i := startGapIndex + 1; i < endGapIndex; i++ {
+			s.deleteConsecutive(s.gaps[i].End)
 		}
 	}
 
-	if !endsInGap && start != endGapEnd && end > endGapEnd {
+	if !endsInGap && start != endGap.End && end > endGap.End {
 		// cut the frame, such that it ends at the end of the gap
-		data = data[:endGapEnd-start]
-		end = endGapEnd
+		data = data[:endGap.End-start]
+		end = endGap.End
 		wasCut = true
 	}
-	if end == endGapEnd {
-		if !startGapEqualsEndGap {
-			// The frame covers the whole endGap. Delete the gap.
-			s.gaps.Remove(endGap)
-		}
-	} else {
+	if end != endGap.End {
 		if startGapEqualsEndGap && adjustedStartGapEnd {
 			// The frame split the existing gap into two.
-			s.gaps.InsertAfter(utils.ByteInterval{Start: end, End: startGapEnd}, startGap)
+			newGaps[numNewGaps] = utils.ByteInterval{Start: end, End: startGap.End}
+			numNewGaps++
 		} else if !startGapEqualsEndGap {
-			endGap.Value.Start = end
+			newGaps[numNewGaps] = utils.ByteInterval{Start: end, End: endGap.End}
+			numNewGaps++
 		}
 	}
+	s.replaceGaps(startGapIndex, endGapIndex, newGaps[:numNewGaps])
 
+	var owned bool
 	if wasCut && len(data) < protocol.MinStreamFrameBufferSize {
 		newData := make([]byte, len(data))
 		copy(newData, data)
 		data = newData
+		owned = true
 		if doneCb != nil {
 			doneCb()
 			doneCb = nil
 		}
 	}
 
-	if s.gaps.Len() > protocol.MaxStreamFrameSorterGaps {
+	if len(s.gaps) > protocol.MaxStreamFrameSorterGaps {
 		return errors.New("too many gaps in received data")
 	}
 
-	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb}
+	s.insertEntry(start, frameSorterEntry{Data: data, DoneCb: doneCb, owned: owned})
 	return nil
 }
 
-func (s *frameSorter) findStartGap(offset protocol.ByteCount) (*utils.ByteIntervalElement, bool) {
-	for gap := s.gaps.Front(); gap != nil; gap = gap.Next() {
-		if offset >= gap.Value.Start && offset <= gap.Value.End {
-			return gap, true
-		}
-		if offset < gap.Value.Start {
-			return gap, false
+// replaceGaps replaces the gaps from index first to index last (inclusive) with newGaps.
+// The gaps are modified in place, since the slice of gaps can be large.
+func (s *frameSorter) replaceGaps(first, last int, newGaps []utils.ByteInterval) {
+	diff := len(newGaps) - (last - first + 1)
+	switch {
+	case diff == 0:
+	case first == 0 && diff < 0:
+		// Removing gaps at the front is the common case when receiving data in order.
+		s.gaps = s.gaps[-diff:]
+	case diff < 0:
+		copy(s.gaps[last+1+diff:], s.gaps[last+1:])
+		s.gaps = s.gaps[:len(s.gaps)+diff]
+	default:
+		for i := 0; i < diff; i++ {
+			s.gaps = append(s.gaps, utils.ByteInterval{})
 		}
+		copy(s.gaps[last+1+diff:], s.gaps[last+1:])
 	}
-	panic("no gap found")
+	copy(s.gaps[first:], newGaps)
 }
 
-func (s *frameSorter) findEndGap(startGap *utils.ByteIntervalElement, offset protocol.ByteCount) (*utils.ByteIntervalElement, bool) {
-	for gap := startGap; gap != nil; gap = gap.Next() {
-		if offset >= gap.Value.Start && offset < gap.Value.End {
-			return gap, true
-		}
-		if offset < gap.Value.Start {
-			return gap.Prev(), false
-		}
+// findStartGap returns the index of the gap that contains offset, or of the first gap after offset.
+func (s *frameSorter) findStartGap(offset protocol.ByteCount) (int, bool) {
+	i := sort.Search(len(s.gaps), func(i int) bool { return offset <= s.gaps[i].End })
+	if i == len(s.gaps) {
+		panic("no gap found")
+	}
+	return i, offset >= s.gaps[i].Start
+}
+
+// findEndGap returns the index of the gap that contains offset, or of the last gap before offset.
+func (s *frameSorter) findEndGap(startGapIndex int, offset protocol.ByteCount) (int, bool) {
+	i := startGapIndex + sort.Search(len(s.gaps)-startGapIndex, func(i int) bool { return offset < s.gaps[startGapIndex+i].End })
+	if i == len(s.gaps) {
+		panic("no gap found")
 	}
-	panic("no gap found")
+	if offset >= s.gaps[i].Start {
+		return i, true
+	}
+	return i - 1, false
 }
 
 // deleteConsecutive deletes consecutive frames from the queue, starting at pos
 func (s *frameSorter) deleteConsecutive(pos protocol.ByteCount) {
 	for {
-		oldEntry, ok := s.queue[pos]
+		oldEntry, ok := s.removeEntry(pos)
 		if !ok {
 			break
 		}
-		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
-		delete(s.queue, pos)
 		if oldEntry.DoneCb != nil {
 			oldEntry.DoneCb()
 		}
-		pos += oldEntryLen
+		pos += protocol.ByteCount(len(oldEntry.Data))
+	}
+}
+
+// insertEntry inserts an entry into the queue.
+// If both the entry and the entry directly before or after it are small, they are merged.
+func (s *frameSorter) insertEntry(start protocol.ByteCount, e frameSorterEntry) {
+	if e.mergeable() {
+		if prevStart, ok := s.mergeable[start]; ok {
+			if prev := s.queue[prevStart]; len(prev.Data)+len(e.Data) <= int(maxMergedEntrySize) {
+				s.removeEntry(prevStart)
+				e = mergeEntries(prev, e)
+				start = prevStart
+			}
+		}
+		end := start + protocol.ByteCount(len(e.Data))
+		if next, ok := s.queue[end]; ok && next.mergeable() && len(e.Data)+len(next.Data) <= int(maxMergedEntrySize) {
+			s.removeEntry(end)
+			e = mergeEntries(e, next)
+		}
+	}
+	s.queue[start] = e
+	if e.mergeable() {
+		s.mergeable[start+protocol.ByteCount(len(e.Data))] = start
+	}
+}
+
+// removeEntry removes the entry starting at pos from the queue.
+func (s *frameSorter) removeEntry(pos protocol.ByteCount) (frameSorterEntry, bool) {
+	e, ok := s.queue[pos]
+	if !ok {
+		return frameSorterEntry{}, false
+	}
+	delete(s.queue, pos)
+	if e.mergeable() {
+		delete(s.mergeable, pos+protocol.ByteCount(len(e.Data)))
+	}
+	return e, true
+}
+
+// mergeEntries merges two adjacent entries into a single entry.
+// The data is copied into a buffer owned by the frameSorter, and the callbacks of the entries are called.
+func mergeEntries(first, second frameSorterEntry) frameSorterEntry {
+	data := first.Data
+	if !first.owned {
+		data = make([]byte, len(first.Data), len(first.Data)+len(second.Data))
+		copy(data, first.Data)
+	}
+	data = append(data, second.Data...)
+	if first.DoneCb != nil {
+		first.DoneCb()
+	}
+	if second.DoneCb != nil {
+		second.DoneCb()
 	}
+	return frameSorterEntry{Data: data, owned: true}
 }
 
 func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
-	entry, ok := s.queue[s.readPos]
+	entry, ok := s.removeEntry(s.readPos)
 	if !ok {
 		return s.readPos, nil, nil
 	}
-	delete(s.queue, s.readPos)
 	offset := s.readPos
 	s.readPos += protocol.ByteCount(len(entry.Data))
-	if s.gaps.Front().Value.End <= s.readPos {
+	if s.gaps[0].End <= s.readPos {
 		panic("frame sorter BUG: read position higher than a gap")
 	}
 	return offset, entry.Data, entry.DoneCb
@@ -14,6 +14,9 @@ type framer interface {
 	HasData() bool
 
 	QueueControlFrame(wire.Frame)
+	// QueueTrackedControlFrame queues a control frame.
+	// The OnAcked and OnLost callbacks are invoked when the packet containing the frame is acknowledged or lost.
+	QueueTrackedControlFrame(ackhandler.Frame)
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 
 	AddActiveStream(protocol.StreamID)
@@ -32,7 +35,7 @@ type framerI struct {
 	streamQueue   []protocol.StreamID
 
 	controlFrameMutex sync.Mutex
-	controlFrames     []wire.Frame
+	controlFrames     []ackhandler.Frame
 }
 
 var _ framer = &framerI{}
@@ -62,6 +65,10 @@ func (f *framerI) HasData() bool {
 }
 
 func (f *framerI) QueueControlFrame(frame wire.Frame) {
+	f.QueueTrackedControlFrame(ackhandler.Frame{Frame: frame})
+}
+
+func (f *framerI) QueueTrackedControlFrame(frame ackhandler.Frame) {
 	f.controlFrameMutex.Lock()
 	f.controlFrames = append(f.controlFrames, frame)
 	f.controlFrameMutex.Unlock()
@@ -76,7 +83,7 @@ func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol
 		if length+frameLen > maxLen {
 			break
 		}
-		frames = append(frames, ackhandler.Frame{Frame: frame})
+		frames = append(frames, frame)
 		length += frameLen
 		f.controlFrames = f.controlFrames[:len(f.controlFrames)-1]
 	}
@@ -155,7 +162,7 @@ func (f *framerI) Handle0RTTRejection() error {
 	}
 	var j int
 	for i, frame := range f.controlFrames {
-		switch frame.(type) {
+		switch frame.Frame.(type) {
 		case *wire.MaxDataFrame, *wire.MaxStreamDataFrame, *wire.MaxStreamsFrame:
 			return errors.New("didn't expect MAX_DATA / MAX_STREAM_DATA / MAX_STREAMS frame to be sent in 0-RTT")
 		case *wire.DataBlockedFrame, *wire.StreamDataBlockedFrame, *wire.StreamsBlockedFrame:
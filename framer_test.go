@@ -50,6 +50,20 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(mdf.Length(version) + msf.Length(version)))
 		})
 
+		It("adds control frames with callbacks", func() {
+			var acked bool
+			framer.QueueTrackedControlFrame(ackhandler.Frame{
+				Frame:   &wire.PingFrame{},
+				OnAcked: func(wire.Frame) { acked = true },
+			})
+			frames, length := framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(&wire.PingFrame{}))
+			Expect(length).To(Equal(protocol.ByteCount(1)))
+			frames[0].OnAcked(frames[0].Frame)
+			Expect(acked).To(BeTrue())
+		})
+
 		It("says if it has data", func() {
 			Expect(framer.HasData()).To(BeFalse())
 			f := &wire.MaxDataFrame{MaximumData: 0x42}
@@ -106,6 +106,7 @@ func main() {
 		runner,
 		config,
 		false,
+		nil,
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
@@ -146,7 +147,7 @@ messageLoop:
 		}
 	}
 
-	ticket, err := server.GetSessionTicket()
+	ticket, err := server.GetSessionTicket(nil)
 	if err != nil {
 		log.Fatal(err)
 	}
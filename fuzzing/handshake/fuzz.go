@@ -400,6 +400,7 @@ func runHandshake(runConfig [confLen]byte, messageConfig uint8, clientConf *tls.
 		runner,
 		serverConf,
 		enable0RTTServer,
+		nil,
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
@@ -484,7 +485,7 @@ messageLoop:
 	}
 
 	if sendSessionTicket && !serverConf.SessionTicketsDisabled {
-		ticket, err := server.GetSessionTicket()
+		ticket, err := server.GetSessionTicket(nil)
 		if err != nil {
 			panic(err)
 		}
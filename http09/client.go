@@ -0,0 +1,340 @@
+package http09
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// MethodGet0RTT allows a GET request to be sent using 0-RTT.
+// Note that 0-RTT data doesn't provide replay protection.
+const MethodGet0RTT = "GET_0RTT"
+
+// RoundTripper performs HTTP/0.9 roundtrips over QUIC.
+// It reuses a QUIC connection for all requests to the same host,
+// and establishes a new connection when the cached connection is closed.
+type RoundTripper struct {
+	mutex sync.Mutex
+
+	// TLSClientConfig specifies the TLS configuration to use.
+	// The ALPN is set to NextProtoH09.
+	TLSClientConfig *tls.Config
+
+	// QuicConfig is the quic.Config used for dialing new connections.
+	// If nil, reasonable default values will be used.
+	QuicConfig *quic.Config
+
+	// Dial specifies an optional dial function for creating QUIC
+	// connections for requests.
+	// If Dial is nil, quic.DialAddrEarlyContext will be used.
+	Dial func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error)
+
+	clients map[string]*client
+}
+
+// RoundTripOpt are options for the RoundTripper.RoundTripOpt method.
+type RoundTripOpt struct {
+	// OnlyCachedConn controls whether the RoundTripper may create a new QUIC connection.
+	// If set true and no cached connection is available, RoundTripOpt will return ErrNoCachedConn.
+	OnlyCachedConn bool
+}
+
+var (
+	_ http.RoundTripper = &RoundTripper{}
+	_ io.Closer         = &RoundTripper{}
+)
+
+// ErrNoCachedConn is returned when RoundTripOpt.OnlyCachedConn is set
+var ErrNoCachedConn = errors.New("http09: no cached connection was available")
+
+// RoundTripOpt is like RoundTrip, but takes options.
+// It only supports GET requests.
+// The request is canceled when the request's context is canceled.
+func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
+	if req.URL == nil {
+		return nil, errors.New("http09: nil Request.URL")
+	}
+	if req.URL.Host == "" {
+		return nil, errors.New("http09: no Host in request URL")
+	}
+	if req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("http09: unsupported protocol scheme: %s", req.URL.Scheme)
+	}
+	if req.Method != "" && req.Method != http.MethodGet && req.Method != MethodGet0RTT {
+		return nil, errors.New("http09: only GET requests supported")
+	}
+
+	hostname := authorityAddr("https", hostnameFromRequest(req))
+	cl, isNew, err := r.getClient(hostname, opt.OnlyCachedConn)
+	if err != nil {
+		return nil, err
+	}
+	if isNew {
+		cl.dial(req.Context())
+	}
+	return cl.RoundTrip(req)
+}
+
+// RoundTrip performs a HTTP/0.9 request.
+func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.RoundTripOpt(req, RoundTripOpt{})
+}
+
+// getClient returns the client for hostname.
+// If isNew is true, the caller is responsible for dialing the connection.
+func (r *RoundTripper) getClient(hostname string, onlyCached bool) (cl *client, isNew bool, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.clients == nil {
+		r.clients = make(map[string]*client)
+	}
+	cl, ok := r.clients[hostname]
+	if ok && !cl.isUsable() {
+		ok = false
+	}
+	if !ok {
+		if onlyCached {
+			return nil, false, ErrNoCachedConn
+		}
+		tlsConf := &tls.Config{}
+		if r.TLSClientConfig != nil {
+			tlsConf = r.TLSClientConfig.Clone()
+		}
+		tlsConf.NextProtos = []string{NextProtoH09}
+		cl = newClient(hostname, tlsConf, r.QuicConfig, r.Dial)
+		r.clients[hostname] = cl
+		isNew = true
+	}
+	return cl, isNew, nil
+}
+
+// Close closes the QUIC connections that this RoundTripper has used.
+func (r *RoundTripper) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, c := range r.clients {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	r.clients = nil
+	return nil
+}
+
+type client struct {
+	hostname string
+	tlsConf  *tls.Config
+	quicConf *quic.Config
+	dialFunc func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error)
+
+	dialed  chan struct{} // closed once dialing completed, either successfully or not
+	conn    quic.EarlyConnection
+	dialErr error
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+func newClient(
+	hostname string,
+	tlsConf *tls.Config,
+	quicConf *quic.Config,
+	dial func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error),
+) *client {
+	if dial == nil {
+		dial = quic.DialAddrEarlyContext
+	}
+	return &client{
+		hostname: hostname,
+		tlsConf:  tlsConf,
+		quicConf: quicConf,
+		dialFunc: dial,
+		dialed:   make(chan struct{}),
+	}
+}
+
+func (c *client) dial(ctx context.Context) {
+	conn, err := c.dialFunc(ctx, c.hostname, c.tlsConf, c.quicConf)
+	c.mutex.Lock()
+	if err == nil && c.closed {
+		conn.CloseWithError(0, "")
+		err = net.ErrClosed
+	}
+	c.conn = conn
+	c.dialErr = err
+	c.mutex.Unlock()
+	close(c.dialed)
+}
+
+// isUsable says if the client can be used for new requests.
+// This is the case while dialing, and as long as the connection is alive.
+func (c *client) isUsable() bool {
+	select {
+	case <-c.dialed:
+	default:
+		return true
+	}
+	if c.dialErr != nil {
+		return false
+	}
+	select {
+	case <-c.conn.Context().Done():
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-c.dialed:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if c.dialErr != nil {
+		return nil, c.dialErr
+	}
+	// Immediately send out this request, if this is a 0-RTT request.
+	if req.Method != MethodGet0RTT {
+		select {
+		case <-c.conn.HandshakeComplete().Done():
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	str, err := c.conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Request Cancellation:
+	// This go routine keeps running even after RoundTrip() returns.
+	// It is shut down when the application is done processing the body.
+	reqDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			str.CancelWrite(errorRequestCanceled)
+			str.CancelRead(errorRequestCanceled)
+		case <-reqDone:
+		}
+	}()
+
+	if err := c.sendRequest(req, str); err != nil {
+		close(reqDone)
+		str.CancelWrite(errorRequestCanceled)
+		str.CancelRead(errorRequestCanceled)
+		return nil, err
+	}
+	return &http.Response{
+		Proto:      "HTTP/0.9",
+		ProtoMajor: 0,
+		ProtoMinor: 9,
+		Header:     http.Header{},
+		Request:    req,
+		Body:       &responseBody{str: str, ctx: ctx, reqDone: reqDone},
+	}, nil
+}
+
+func (c *client) sendRequest(req *http.Request, str quic.Stream) error {
+	if _, err := str.Write([]byte("GET " + req.URL.RequestURI() + "\r\n")); err != nil {
+		return err
+	}
+	return str.Close()
+}
+
+func (c *client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.CloseWithError(0, "")
+}
+
+// The body of a http.Response.
+type responseBody struct {
+	str quic.Stream
+	ctx context.Context
+
+	// The channel is closed when the user is done with this response:
+	// either when Read() errors, or when Close() is called.
+	reqDone       chan<- struct{}
+	reqDoneClosed bool
+}
+
+var _ io.ReadCloser = &responseBody{}
+
+func (r *responseBody) Read(b []byte) (int, error) {
+	n, err := r.str.Read(b)
+	if err != nil {
+		r.requestDone()
+		// If the request was canceled, return the context's error instead of the stream error.
+		if ctxErr := r.ctx.Err(); ctxErr != nil {
+			return n, ctxErr
+		}
+	}
+	return n, err
+}
+
+func (r *responseBody) requestDone() {
+	if r.reqDoneClosed {
+		return
+	}
+	close(r.reqDone)
+	r.reqDoneClosed = true
+}
+
+func (r *responseBody) Close() error {
+	r.requestDone()
+	// If the EOF was read, CancelRead() is a no-op.
+	r.str.CancelRead(errorRequestCanceled)
+	return nil
+}
+
+func hostnameFromRequest(req *http.Request) string {
+	if req.URL != nil {
+		return req.URL.Host
+	}
+	return ""
+}
+
+// authorityAddr returns a given authority (a host/IP, or host:port / ip:port)
+// and returns a host:port. The port 443 is added if needed.
+func authorityAddr(scheme string, authority string) (addr string) {
+	host, port, err := net.SplitHostPort(authority)
+	if err != nil { // authority didn't have a port
+		port = "443"
+		if scheme == "http" {
+			port = "80"
+		}
+		host = authority
+	}
+	if a, err := idna.ToASCII(host); err == nil {
+		host = a
+	}
+	// IPv6 address literal, without a port:
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		return host + ":" + port
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,408 @@
+package http09
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP 0.9 integration tests", func() {
+	var (
+		server *Server
+		mux    *http.ServeMux
+		saddr  net.Addr
+		done   chan struct{}
+	)
+
+	getServerTLSConfig := func() *tls.Config {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{NextProtoH09}
+		return tlsConf
+	}
+
+	newRoundTripper := func() *RoundTripper {
+		return &RoundTripper{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+
+	newRequest := func(path string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, fmt.Sprintf("https://%s%s", saddr, path), nil)
+	}
+
+	get := func(rt http.RoundTripper, path string) []byte {
+		rsp, err := rt.RoundTrip(newRequest(path))
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		defer rsp.Body.Close()
+		data, err := io.ReadAll(rsp.Body)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return data
+	}
+
+	BeforeEach(func() {
+		// This is the same handler interface that is used by the http3 package.
+		mux = http.NewServeMux()
+		mux.HandleFunc("/helloworld", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("Hello World!"))
+		})
+		server = &Server{
+			TLSConfig: testdata.GetTLSConfig(),
+			Handler:   mux,
+		}
+		ln, err := quic.ListenAddrEarly("127.0.0.1:0", getServerTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		saddr = ln.Addr()
+		done = make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = server.ServeListener(ln)
+		}()
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("performs request", func() {
+		rt := newRoundTripper()
+		defer rt.Close()
+		Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+	})
+
+	It("allows setting of headers", func() {
+		mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("foo", "bar")
+			w.WriteHeader(1337)
+			_, _ = w.Write([]byte("done"))
+		})
+
+		rt := newRoundTripper()
+		defer rt.Close()
+		Expect(get(rt, "/headers")).To(Equal([]byte("done")))
+	})
+
+	It("passes the request to the handler", func() {
+		reqChan := make(chan *http.Request, 1)
+		mux.HandleFunc("/request", func(w http.ResponseWriter, r *http.Request) {
+			reqChan <- r
+		})
+
+		rt := newRoundTripper()
+		defer rt.Close()
+		get(rt, "/request?foo=bar")
+		var req *http.Request
+		Eventually(reqChan).Should(Receive(&req))
+		Expect(req.Method).To(Equal(http.MethodGet))
+		Expect(req.Proto).To(Equal("HTTP/0.9"))
+		Expect(req.URL.Path).To(Equal("/request"))
+		Expect(req.URL.Query().Get("foo")).To(Equal("bar"))
+		Expect(req.RequestURI).To(Equal("/request?foo=bar"))
+		Expect(req.RemoteAddr).ToNot(BeEmpty())
+		Expect(req.Context().Value(ServerContextKey)).To(Equal(server))
+		Expect(req.Context().Value(http.LocalAddrContextKey)).To(Equal(saddr))
+		Expect(req.Body).ToNot(BeNil())
+	})
+
+	It("supports flushing", func() {
+		mux.HandleFunc("/flush", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("foo"))
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte("bar"))
+		})
+
+		rt := newRoundTripper()
+		defer rt.Close()
+		Expect(get(rt, "/flush")).To(Equal([]byte("foobar")))
+	})
+
+	It("recovers from panics in the handler", func() {
+		mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+			panic("foobar")
+		})
+
+		rt := newRoundTripper()
+		defer rt.Close()
+		Expect(get(rt, "/panic")).To(Equal([]byte("500")))
+	})
+
+	It("rejects requests that are not GET requests", func() {
+		rt := newRoundTripper()
+		defer rt.Close()
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("https://%s/helloworld", saddr), nil)
+		_, err := rt.RoundTrip(req)
+		Expect(err).To(MatchError("http09: only GET requests supported"))
+	})
+
+	It("resets the stream if the request is too large", func() {
+		tlsConf := &tls.Config{InsecureSkipVerify: true, NextProtos: []string{NextProtoH09}}
+		conn, err := quic.DialAddr(saddr.String(), tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		// don't close the stream, the server needs to reset it as soon as the limit is exceeded
+		_, err = str.Write(append([]byte("GET /"), bytes.Repeat([]byte{'a'}, maxRequestSize)...))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.ReadAll(str)
+		var streamErr *quic.StreamError
+		Expect(errors.As(err, &streamErr)).To(BeTrue())
+		Expect(streamErr.ErrorCode).To(BeEquivalentTo(errorInvalidRequest))
+	})
+
+	It("uses a custom dial function", func() {
+		var dialed string
+		rt := &RoundTripper{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+				dialed = addr
+				return quic.DialAddrEarlyContext(ctx, addr, tlsCfg, cfg)
+			},
+		}
+		defer rt.Close()
+		Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+		Expect(dialed).To(Equal(saddr.String()))
+	})
+
+	Context("connection reuse", func() {
+		var (
+			rt       *RoundTripper
+			numDials int32
+			conns    chan quic.EarlyConnection
+		)
+
+		BeforeEach(func() {
+			numDials = 0
+			conns = make(chan quic.EarlyConnection, 10)
+			rt = &RoundTripper{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+					atomic.AddInt32(&numDials, 1)
+					conn, err := quic.DialAddrEarlyContext(ctx, addr, tlsCfg, cfg)
+					if err == nil {
+						conns <- conn
+					}
+					return conn, err
+				},
+			}
+		})
+
+		AfterEach(func() { rt.Close() })
+
+		It("reuses the connection", func() {
+			for i := 0; i < 3; i++ {
+				Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+			}
+			Expect(atomic.LoadInt32(&numDials)).To(BeEquivalentTo(1))
+		})
+
+		It("dials a new connection when the cached connection is closed", func() {
+			Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+			var conn quic.EarlyConnection
+			Expect(conns).To(Receive(&conn))
+			Expect(conn.CloseWithError(0, "")).To(Succeed())
+			Eventually(conn.Context().Done()).Should(BeClosed())
+			Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+			Expect(atomic.LoadInt32(&numDials)).To(BeEquivalentTo(2))
+		})
+
+		It("dials a new connection after dialing failed", func() {
+			testErr := errors.New("test error")
+			dial := rt.Dial
+			rt.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+				rt.Dial = dial
+				return nil, testErr
+			}
+			_, err := rt.RoundTrip(newRequest("/helloworld"))
+			Expect(err).To(MatchError(testErr))
+			Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+		})
+
+		It("only uses cached connections, if requested", func() {
+			_, err := rt.RoundTripOpt(newRequest("/helloworld"), RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+			Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+			rsp, err := rt.RoundTripOpt(newRequest("/helloworld"), RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(rsp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("Hello World!")))
+			Expect(atomic.LoadInt32(&numDials)).To(BeEquivalentTo(1))
+		})
+	})
+
+	It("cancels requests when the context is canceled", func() {
+		handlerCanceled := make(chan struct{})
+		mux.HandleFunc("/cancel", func(w http.ResponseWriter, r *http.Request) {
+			defer close(handlerCanceled)
+			_, _ = w.Write([]byte("foo"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		})
+
+		rt := newRoundTripper()
+		defer rt.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		rsp, err := rt.RoundTrip(newRequest("/cancel").WithContext(ctx))
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 3)
+		_, err = io.ReadFull(rsp.Body, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("foo")))
+		cancel()
+		_, err = io.ReadAll(rsp.Body)
+		Expect(err).To(MatchError(context.Canceled))
+		Eventually(handlerCanceled).Should(BeClosed())
+	})
+
+	It("returns an error if the context is canceled before the request is sent", func() {
+		rt := newRoundTripper()
+		defer rt.Close()
+		// establish the connection first
+		Expect(get(rt, "/helloworld")).To(Equal([]byte("Hello World!")))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := rt.RoundTrip(newRequest("/helloworld").WithContext(ctx))
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	Context("closing", func() {
+		var (
+			handlerStarted chan struct{}
+			unblockHandler chan struct{}
+		)
+
+		BeforeEach(func() {
+			handlerStarted = make(chan struct{}, 10)
+			unblockHandler = make(chan struct{})
+			mux.HandleFunc("/block", func(w http.ResponseWriter, r *http.Request) {
+				handlerStarted <- struct{}{}
+				select {
+				case <-unblockHandler:
+				case <-r.Context().Done():
+					return
+				}
+				_, _ = w.Write([]byte("done"))
+			})
+		})
+
+		It("rejects Serve after the server was closed", func() {
+			Expect(server.Close()).To(Succeed())
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			Expect(server.Serve(conn)).To(MatchError(http.ErrServerClosed))
+		})
+
+		It("waits for the client to close the connection when closing gracefully", func() {
+			rt := newRoundTripper()
+			defer rt.Close()
+			rspChan := make(chan []byte, 1)
+			go func() {
+				defer GinkgoRecover()
+				rspChan <- get(rt, "/block")
+			}()
+			Eventually(handlerStarted).Should(Receive())
+
+			closed := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(closed)
+				Expect(server.CloseGracefully(scaleDuration(10 * time.Second))).To(Succeed())
+			}()
+			Eventually(func() bool {
+				server.mutex.Lock()
+				defer server.mutex.Unlock()
+				return server.closed
+			}).Should(BeTrue())
+			Consistently(closed).ShouldNot(BeClosed())
+
+			// new requests are rejected
+			rsp, err := rt.RoundTripOpt(newRequest("/helloworld"), RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.ReadAll(rsp.Body)
+			var streamErr *quic.StreamError
+			Expect(errors.As(err, &streamErr)).To(BeTrue())
+			Expect(streamErr.ErrorCode).To(BeEquivalentTo(errorRequestRejected))
+
+			close(unblockHandler)
+			Eventually(rspChan).Should(Receive(Equal([]byte("done"))))
+			Consistently(closed).ShouldNot(BeClosed())
+			Expect(rt.Close()).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("closes the connections when the graceful close times out", func() {
+			rt := newRoundTripper()
+			defer rt.Close()
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				rsp, err := rt.RoundTrip(newRequest("/block"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = io.ReadAll(rsp.Body)
+				errChan <- err
+			}()
+			Eventually(handlerStarted).Should(Receive())
+
+			start := time.Now()
+			Expect(server.CloseGracefully(scaleDuration(100 * time.Millisecond))).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically(">=", scaleDuration(100*time.Millisecond)))
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("HTTP 0.9 server", func() {
+	It("serves on a packet conn", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		server := &Server{
+			TLSConfig: testdata.GetTLSConfig(),
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("Hello World!"))
+			}),
+		}
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_ = server.Serve(conn)
+		}()
+
+		rt := &RoundTripper{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		defer rt.Close()
+		req := httptest.NewRequest(
+			http.MethodGet,
+			fmt.Sprintf("https://%s/helloworld", conn.LocalAddr()),
+			nil,
+		)
+		rsp, err := rt.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(rsp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("Hello World!")))
+		Expect(server.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("errors when no tls.Config is set", func() {
+		Expect((&Server{}).ListenAndServe()).To(MatchError(errServerWithoutTLSConfig))
+	})
+})
@@ -0,0 +1,385 @@
+// Package http09 implements HTTP/0.9 over QUIC, as used by the QUIC interop runner ("hq-interop").
+//
+// HTTP/0.9 only supports GET requests. There are no headers and no status codes:
+// a request consists of a single request line, and the response consists of the data sent on the stream.
+// This makes it a good fit for minimal test endpoints.
+// The server uses the same http.Handler interface as the http3 package,
+// so the same handler can be used to serve both HTTP/3 and HTTP/0.9.
+package http09
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// NextProtoH09 is the ALPN used for HTTP/0.9 over QUIC.
+const NextProtoH09 = "hq-interop"
+
+// HTTP/0.9 doesn't define any error codes.
+// We use the values of the corresponding HTTP/3 error codes.
+const (
+	errorRequestRejected = 0x10b
+	errorRequestCanceled = 0x10c
+	errorInvalidRequest  = 0x10e // H3_MESSAGE_ERROR
+)
+
+// maxRequestSize is the maximum size of a request (i.e. of the request line).
+const maxRequestSize = 8 << 10
+
+// contextKey is a value for use with context.WithValue. It's used as
+// a pointer so it fits in an interface{} without allocation.
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string { return "quic-go/http09 context value " + k.name }
+
+// ServerContextKey is a context key. It can be used in HTTP
+// handlers with Context.Value to access the server that
+// started the handler. The associated value will be of
+// type *http09.Server.
+var ServerContextKey = &contextKey{"http09-server"}
+
+type responseWriter struct {
+	io.Writer
+	headers http.Header
+}
+
+var (
+	_ http.ResponseWriter = &responseWriter{}
+	_ http.Flusher        = &responseWriter{}
+)
+
+func (w *responseWriter) Header() http.Header {
+	if w.headers == nil {
+		w.headers = make(http.Header)
+	}
+	return w.headers
+}
+
+// WriteHeader is a no-op, since HTTP/0.9 doesn't have status codes.
+func (w *responseWriter) WriteHeader(int) {}
+
+// Flush is a no-op, since data is written to the stream directly.
+func (w *responseWriter) Flush() {}
+
+// Server is a HTTP/0.9 server listening for QUIC connections.
+type Server struct {
+	// Addr optionally specifies the UDP address for the server to listen on,
+	// in the form "host:port".
+	//
+	// When used by ListenAndServe, if empty, ":https" (port 443) is used.
+	Addr string
+
+	// TLSConfig provides a TLS configuration for use by server. It must be
+	// set for ListenAndServe and Serve methods.
+	// The ALPN is set to NextProtoH09.
+	TLSConfig *tls.Config
+
+	// QuicConfig provides the parameters for QUIC connection created with
+	// Serve. If nil, it uses reasonable default values.
+	QuicConfig *quic.Config
+
+	// Handler is the HTTP request handler to use. If not set, defaults to
+	// http.DefaultServeMux.
+	Handler http.Handler
+
+	mutex     sync.Mutex
+	listeners map[*quic.EarlyListener]struct{}
+	conns     map[quic.EarlyConnection]struct{}
+	closed    bool
+	// set by CloseGracefully, closed when the last connection is closed
+	connsClosed chan struct{}
+
+	logger utils.Logger
+}
+
+// ListenAndServe listens on the UDP address s.Addr and calls s.Handler to handle HTTP/0.9 requests on incoming connections.
+//
+// If s.Addr is blank, ":https" is used.
+func (s *Server) ListenAndServe() error {
+	return s.serveConn(nil)
+}
+
+// Serve an existing UDP connection.
+// It is possible to reuse the same connection for outgoing connections.
+// Closing the server does not close the packet conn.
+func (s *Server) Serve(conn net.PacketConn) error {
+	return s.serveConn(conn)
+}
+
+// ServeListener serves an existing QUIC listener.
+// The listener's tls.Config must use NextProtoH09 as the ALPN.
+// Closing the server does close the listener.
+func (s *Server) ServeListener(ln quic.EarlyListener) error {
+	if err := s.addListener(&ln); err != nil {
+		return err
+	}
+	err := s.serveListener(ln)
+	s.removeListener(&ln)
+	return err
+}
+
+var errServerWithoutTLSConfig = errors.New("use of http09.Server without TLSConfig")
+
+func (s *Server) serveConn(conn net.PacketConn) error {
+	if s.TLSConfig == nil {
+		return errServerWithoutTLSConfig
+	}
+
+	s.mutex.Lock()
+	closed := s.closed
+	s.mutex.Unlock()
+	if closed {
+		return http.ErrServerClosed
+	}
+
+	tlsConf := s.TLSConfig.Clone()
+	tlsConf.NextProtos = []string{NextProtoH09}
+	var ln quic.EarlyListener
+	var err error
+	if conn == nil {
+		addr := s.Addr
+		if addr == "" {
+			addr = ":https"
+		}
+		ln, err = quic.ListenAddrEarly(addr, tlsConf, s.QuicConfig)
+	} else {
+		ln, err = quic.ListenEarly(conn, tlsConf, s.QuicConfig)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.addListener(&ln); err != nil {
+		ln.Close()
+		return err
+	}
+	err = s.serveListener(ln)
+	s.removeListener(&ln)
+	return err
+}
+
+func (s *Server) serveListener(ln quic.EarlyListener) error {
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) addListener(l *quic.EarlyListener) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return http.ErrServerClosed
+	}
+	if s.logger == nil {
+		s.logger = utils.DefaultLogger.WithPrefix("http09 server")
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[*quic.EarlyListener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	return nil
+}
+
+func (s *Server) removeListener(l *quic.EarlyListener) {
+	s.mutex.Lock()
+	delete(s.listeners, l)
+	s.mutex.Unlock()
+}
+
+func (s *Server) handleConn(conn quic.EarlyConnection) {
+	if !s.addConn(conn) {
+		conn.CloseWithError(0, "server closing")
+		return
+	}
+	defer s.removeConn(conn)
+
+	for {
+		str, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			s.logger.Debugf("Accepting stream failed: %s", err)
+			return
+		}
+		go func() {
+			if err := s.handleStream(conn, str); err != nil {
+				s.logger.Debugf("Handling stream failed: %s", err)
+			}
+		}()
+	}
+}
+
+// addConn returns false if the server is shutting down, and the connection must be closed.
+func (s *Server) addConn(conn quic.EarlyConnection) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[quic.EarlyConnection]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) removeConn(conn quic.EarlyConnection) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.conns, conn)
+	if len(s.conns) == 0 && s.connsClosed != nil {
+		close(s.connsClosed)
+		s.connsClosed = nil
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+func (s *Server) handleStream(conn quic.EarlyConnection, str quic.Stream) error {
+	if s.isClosed() {
+		str.CancelRead(errorRequestRejected)
+		str.CancelWrite(errorRequestRejected)
+		return nil
+	}
+
+	reqBytes, err := io.ReadAll(io.LimitReader(str, maxRequestSize+1))
+	if err != nil {
+		return err
+	}
+	if len(reqBytes) > maxRequestSize {
+		str.CancelRead(errorInvalidRequest)
+		str.CancelWrite(errorInvalidRequest)
+		return nil
+	}
+	request := string(reqBytes)
+	request = strings.TrimRight(request, "\r\n")
+	request = strings.TrimRight(request, " ")
+
+	s.logger.Debugf("Received request: %s", request)
+
+	if !strings.HasPrefix(request, "GET /") {
+		str.CancelWrite(errorInvalidRequest)
+		return nil
+	}
+
+	u, err := url.Parse(request[4:])
+	if err != nil {
+		str.CancelWrite(errorInvalidRequest)
+		return err
+	}
+	u.Scheme = "https"
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		Proto:      "HTTP/0.9",
+		ProtoMajor: 0,
+		ProtoMinor: 9,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		URL:        u,
+		Host:       conn.ConnectionState().TLS.ServerName,
+		RequestURI: request[4:],
+		RemoteAddr: conn.RemoteAddr().String(),
+	}
+	ctx := str.Context()
+	ctx = context.WithValue(ctx, ServerContextKey, s)
+	ctx = context.WithValue(ctx, http.LocalAddrContextKey, conn.LocalAddr())
+	req = req.WithContext(ctx)
+
+	handler := s.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+
+	var panicked bool
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				// Copied from net/http/server.go
+				const size = 64 << 10
+				buf := make([]byte, size)
+				buf = buf[:runtime.Stack(buf, false)]
+				s.logger.Errorf("http: panic serving: %v\n%s", p, buf)
+				panicked = true
+			}
+		}()
+		handler.ServeHTTP(&responseWriter{Writer: str}, req)
+	}()
+
+	if panicked {
+		if _, err := str.Write([]byte("500")); err != nil {
+			return err
+		}
+	}
+	return str.Close()
+}
+
+// Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
+// Close in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	listeners := make([]quic.EarlyListener, 0, len(s.listeners))
+	for ln := range s.listeners {
+		listeners = append(listeners, *ln)
+	}
+	s.mutex.Unlock()
+
+	var err error
+	for _, ln := range listeners {
+		if cerr := ln.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// CloseGracefully shuts down the server gracefully.
+// HTTP/0.9 has no way to tell clients that the server is shutting down.
+// New connections and new requests are rejected, and the server waits for either timeout to trigger,
+// or for the clients to close all connections, before closing the remaining connections.
+// Waiting for the clients makes sure that they received the responses to all running requests.
+func (s *Server) CloseGracefully(timeout time.Duration) error {
+	s.mutex.Lock()
+	s.closed = true
+	var done <-chan struct{}
+	if len(s.conns) > 0 {
+		if s.connsClosed == nil {
+			s.connsClosed = make(chan struct{})
+		}
+		done = s.connsClosed
+	}
+	s.mutex.Unlock()
+
+	if done != nil {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+		}
+	}
+	return s.Close()
+}
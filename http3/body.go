@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go"
 )
@@ -36,6 +37,11 @@ type body struct {
 	reqDone       chan<- struct{}
 	reqDoneClosed bool
 
+	// only set for the http.Request
+	// The channel is closed when Close() is called.
+	closed    chan struct{}
+	closeOnce sync.Once
+
 	onFrameError func()
 
 	bytesRemainingInFrame uint64
@@ -54,6 +60,7 @@ func newRequestBody(str quic.Stream, onFrameError func()) *body {
 	return &body{
 		str:          str,
 		onFrameError: onFrameError,
+		closed:       make(chan struct{}),
 	}
 }
 
@@ -129,6 +136,9 @@ func (r *body) StreamID() quic.StreamID {
 
 func (r *body) Close() error {
 	r.requestDone()
+	if r.closed != nil {
+		r.closeOnce.Do(func() { close(r.closed) })
+	}
 	// If the EOF was read, CancelRead() is a no-op.
 	r.str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
 	return nil
@@ -7,11 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/happyeyeballs"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qtls"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -26,6 +29,8 @@ const MethodGet0RTT = "GET_0RTT"
 const (
 	defaultUserAgent              = "quic-go HTTP/3"
 	defaultMaxResponseHeaderBytes = 10 * 1 << 20 // 10 MB
+	// the maximum number of 1xx responses accepted before the final response, same as net/http
+	max1xxResponses = 5
 )
 
 var defaultQuicConfig = &quic.Config{
@@ -34,9 +39,63 @@ var defaultQuicConfig = &quic.Config{
 	Versions:           []protocol.VersionNumber{protocol.VersionTLS},
 }
 
+// errGoAway is returned for requests that the server didn't process, because it sent a GOAWAY frame.
+var errGoAway = errors.New("http3: server sent GOAWAY")
+
+// An errNotProcessed is returned for requests that the server provably didn't process:
+// the request stream was reset with H3_REQUEST_REJECTED, the request was sent on a stream
+// at or above the stream ID of a GOAWAY frame, or the cached connection was already closed.
+// The RoundTripper retries these requests on a new connection.
+type errNotProcessed struct {
+	err error
+	// set if (part of) the request was sent, i.e. if the request body might have been read
+	sent bool
+}
+
+func (e *errNotProcessed) Error() string { return e.err.Error() }
+func (e *errNotProcessed) Unwrap() error { return e.err }
+
 type dialFunc func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error)
 
-var dialAddr = quic.DialAddrEarlyContext
+var dialAddr dialFunc = dialAddrEarly
+
+// dialAddrEarly dials addr using quic.DialAddrEarlyContext.
+// It resolves addr itself, such that the hooks of an httptrace.ClientTrace are called
+// in the same order as for HTTP/1 and HTTP/2: DNS resolution is followed by the QUIC handshake,
+// which establishes the connection and performs the TLS handshake at the same time.
+// If the host resolves to multiple IP addresses, the connection attempts are raced
+// as described in RFC 8305, and the ConnectStart and ConnectDone hooks are called for every attempt.
+func dialAddrEarly(ctx context.Context, addr string, tlsConf *tls.Config, conf *quic.Config) (quic.EarlyConnection, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	udpAddrs, err := happyeyeballs.Resolve(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+	// Since we're dialing the IP address, the host name needs to be set for SNI.
+	if tlsConf.ServerName == "" {
+		tlsConf = tlsConf.Clone()
+		tlsConf.ServerName = host
+	}
+	trace := httptrace.ContextClientTrace(ctx)
+	// The TLS handshake is started together with the first connection attempt.
+	var handshakeStartOnce sync.Once
+	conn, err := happyeyeballs.Dial(ctx, udpAddrs, func(ctx context.Context, udpAddr *net.UDPAddr) (interface{}, error) {
+		traceConnectStart(trace, "udp", udpAddr.String())
+		handshakeStartOnce.Do(func() { traceTLSHandshakeStart(trace) })
+		conn, err := quic.DialAddrEarlyContext(ctx, udpAddr.String(), tlsConf, conf)
+		traceConnectDone(trace, "udp", udpAddr.String(), err)
+		return conn, err
+	}, func(conn interface{}) {
+		conn.(quic.EarlyConnection).CloseWithError(0, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conn.(quic.EarlyConnection), nil
+}
 
 type roundTripperOpts struct {
 	DisableCompression bool
@@ -64,6 +123,12 @@ type client struct {
 	hostname string
 	conn     quic.EarlyConnection
 
+	mutex          sync.Mutex
+	receivedGoAway bool
+	goAwayID       quic.StreamID
+	// closed (and replaced) every time a GOAWAY frame is received
+	goAway chan struct{}
+
 	logger utils.Logger
 }
 
@@ -99,20 +164,34 @@ func newClient(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, con
 		config:        conf,
 		opts:          opts,
 		dialer:        dialer,
+		goAway:        make(chan struct{}),
 		logger:        logger,
 	}, nil
 }
 
 func (c *client) dial(ctx context.Context) error {
+	trace := httptrace.ContextClientTrace(ctx)
 	var err error
 	if c.dialer != nil {
+		traceTLSHandshakeStart(trace)
 		c.conn, err = c.dialer(ctx, c.hostname, c.tlsConf, c.config)
 	} else {
 		c.conn, err = dialAddr(ctx, c.hostname, c.tlsConf, c.config)
 	}
 	if err != nil {
+		traceTLSHandshakeDone(trace, tls.ConnectionState{}, err)
 		return err
 	}
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		// When using 0-RTT, the connection is returned before the handshake completes.
+		go func() {
+			select {
+			case <-c.conn.HandshakeComplete().Done():
+				trace.TLSHandshakeDone(qtls.ToTLSConnectionState(c.conn.ConnectionState().TLS), nil)
+			case <-c.conn.Context().Done():
+			}
+		}()
+	}
 
 	// send the SETTINGs frame, using 0-RTT data, if possible
 	go func() {
@@ -210,19 +289,70 @@ func (c *client) handleUnidirectionalStreams() {
 				c.conn.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
-			}
 			// If datagram support was enabled on our side as well as on the server side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if c.opts.EnableDatagram && !c.conn.ConnectionState().SupportsDatagrams {
+			if sf.Datagram && c.opts.EnableDatagram && !c.conn.ConnectionState().SupportsDatagrams {
 				c.conn.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+				return
+			}
+			for {
+				f, err := parseNextFrame(str, nil)
+				if err != nil {
+					return
+				}
+				gf, ok := f.(*goAwayFrame)
+				if !ok {
+					c.conn.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
+					return
+				}
+				if err := c.handleGoAway(gf.StreamID); err != nil {
+					c.conn.CloseWithError(quic.ApplicationErrorCode(errorIDError), err.Error())
+					return
+				}
 			}
 		}(str)
 	}
 }
 
+func (c *client) handleGoAway(id quic.StreamID) error {
+	if id.Type() != protocol.StreamTypeBidi || id.InitiatedBy() != protocol.PerspectiveClient {
+		return fmt.Errorf("GOAWAY for invalid stream ID %d", id)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.receivedGoAway && id > c.goAwayID {
+		return fmt.Errorf("GOAWAY stream ID increased from %d to %d", c.goAwayID, id)
+	}
+	c.receivedGoAway = true
+	c.goAwayID = id
+	close(c.goAway)
+	c.goAway = make(chan struct{})
+	return nil
+}
+
+// getGoAway returns a channel that is closed when the next GOAWAY frame is received.
+func (c *client) getGoAway() <-chan struct{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.goAway
+}
+
+func (c *client) hasReceivedGoAway() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.receivedGoAway
+}
+
+// isAboveGoAway says if a request sent on this stream won't be processed by the server,
+// because it sent a GOAWAY frame with a smaller or equal stream ID.
+func (c *client) isAboveGoAway(str quic.Stream) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.receivedGoAway && str.StreamID() >= c.goAwayID
+}
+
 func (c *client) Close() error {
 	if c.conn == nil {
 		return nil
@@ -230,6 +360,16 @@ func (c *client) Close() error {
 	return c.conn.CloseWithError(quic.ApplicationErrorCode(errorNoError), "")
 }
 
+func (c *client) connDone() <-chan struct{} {
+	if c.conn == nil {
+		// The client never established a connection.
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return c.conn.Context().Done()
+}
+
 func (c *client) maxHeaderBytes() uint64 {
 	if c.opts.MaxHeaderBytes <= 0 {
 		return defaultMaxResponseHeaderBytes
@@ -243,13 +383,19 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 		return nil, fmt.Errorf("http3 client BUG: RoundTripOpt called for the wrong client (expected %s, got %s)", c.hostname, req.Host)
 	}
 
+	traceGetConn(req, c.hostname)
+	var dialed bool
 	c.dialOnce.Do(func() {
+		dialed = true
 		c.handshakeErr = c.dial(req.Context())
 	})
 
 	if c.handshakeErr != nil {
 		return nil, c.handshakeErr
 	}
+	if c.hasReceivedGoAway() {
+		return nil, &errNotProcessed{err: errGoAway}
+	}
 
 	// Immediately send out this request, if this is a 0-RTT request.
 	if req.Method == MethodGet0RTT {
@@ -265,27 +411,57 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 
 	str, err := c.conn.OpenStreamSync(req.Context())
 	if err != nil {
+		// If the cached connection was closed, the request was never sent.
+		if !dialed && req.Context().Err() == nil && c.conn.Context().Err() != nil {
+			return nil, &errNotProcessed{err: err}
+		}
 		return nil, err
 	}
+	if c.isAboveGoAway(str) {
+		str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+		str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
+		return nil, &errNotProcessed{err: errGoAway}
+	}
+	traceGotConn(req, c.conn, str, !dialed)
 
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTripOpt() returns.
 	// It is shut down when the application is done processing the body.
+	// The request is also canceled if the server sends a GOAWAY frame, and won't process it.
 	reqDone := make(chan struct{})
 	go func() {
-		select {
-		case <-req.Context().Done():
-			str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
-			str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
-		case <-reqDone:
+		goAway := c.getGoAway()
+	loop:
+		for !c.isAboveGoAway(str) {
+			select {
+			case <-goAway:
+				goAway = c.getGoAway()
+			case <-req.Context().Done():
+				break loop
+			case <-reqDone:
+				return
+			}
 		}
+		str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+		str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
 	}()
 
 	rsp, rerr := c.doRequest(req, str, opt, reqDone)
 	if rerr.err != nil { // if any error occurred
 		close(reqDone)
+		// The server didn't process the request if it sent a GOAWAY frame, or if it rejected the request.
+		var notProcessedErr error
+		if c.isAboveGoAway(str) {
+			notProcessedErr = errGoAway
+		} else if isRequestRejected(rerr.err) {
+			notProcessedErr = rerr.err
+		}
 		if rerr.streamErr != 0 { // if it was a stream error
-			str.CancelWrite(quic.StreamErrorCode(rerr.streamErr))
+			code := rerr.streamErr
+			if notProcessedErr != nil {
+				code = errorRequestCanceled
+			}
+			str.CancelWrite(quic.StreamErrorCode(code))
 		}
 		if rerr.connErr != 0 { // if it was a connection error
 			var reason string
@@ -294,10 +470,19 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 			}
 			c.conn.CloseWithError(quic.ApplicationErrorCode(rerr.connErr), reason)
 		}
+		if notProcessedErr != nil {
+			return nil, &errNotProcessed{err: notProcessedErr, sent: true}
+		}
 	}
 	return rsp, rerr.err
 }
 
+// isRequestRejected says if the server reset the request stream with H3_REQUEST_REJECTED.
+func isRequestRejected(err error) bool {
+	var serr *quic.StreamError
+	return errors.As(err, &serr) && serr.ErrorCode == quic.StreamErrorCode(errorRequestRejected)
+}
+
 func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt, reqDone chan struct{}) (*http.Response, requestError) {
 	var requestGzip bool
 	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
@@ -307,6 +492,60 @@ func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt,
 		return nil, newStreamError(errorInternalError, err)
 	}
 
+	trace := httptrace.ContextClientTrace(req.Context())
+	res, rerr := c.readResponseHeaders(str)
+	if rerr.err != nil {
+		return nil, rerr
+	}
+	traceGotFirstResponseByte(trace)
+	// Skip informational (1xx) responses, until the final response is received.
+	for num1xx := 0; res.StatusCode >= 100 && res.StatusCode < 200; num1xx++ {
+		if num1xx == max1xxResponses {
+			return nil, newStreamError(errorGeneralProtocolError, errors.New("http3: too many 1xx informational responses"))
+		}
+		if err := traceGot1xxResponse(trace, res.StatusCode, res.Header); err != nil {
+			return nil, newStreamError(errorRequestCanceled, err)
+		}
+		res, rerr = c.readResponseHeaders(str)
+		if rerr.err != nil {
+			return nil, rerr
+		}
+	}
+	connState := qtls.ToTLSConnectionState(c.conn.ConnectionState().TLS)
+	res.TLS = &connState
+
+	respBody := newResponseBody(str, c.conn, reqDone, func() {
+		c.conn.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
+	})
+
+	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
+	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
+	isNoContent := res.StatusCode == 204
+	isSuccessfulConnect := req.Method == http.MethodConnect && res.StatusCode >= 200 && res.StatusCode < 300
+	if !hasTransferEncoding && !isNoContent && !isSuccessfulConnect {
+		res.ContentLength = -1
+		if clens, ok := res.Header["Content-Length"]; ok && len(clens) == 1 {
+			if clen64, err := strconv.ParseInt(clens[0], 10, 64); err == nil {
+				res.ContentLength = clen64
+			}
+		}
+	}
+
+	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = newGzipReader(respBody)
+		res.Uncompressed = true
+	} else {
+		res.Body = respBody
+	}
+
+	return res, requestError{}
+}
+
+// readResponseHeaders reads the HEADERS frame of a (final or informational) response.
+func (c *client) readResponseHeaders(str quic.Stream) (*http.Response, requestError) {
 	frame, err := parseNextFrame(str, nil)
 	if err != nil {
 		return nil, newStreamError(errorFrameError, err)
@@ -328,12 +567,10 @@ func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt,
 		return nil, newConnError(errorGeneralProtocolError, err)
 	}
 
-	connState := qtls.ToTLSConnectionState(c.conn.ConnectionState().TLS)
 	res := &http.Response{
 		Proto:      "HTTP/3.0",
 		ProtoMajor: 3,
 		Header:     http.Header{},
-		TLS:        &connState,
 	}
 	for _, hf := range hfs {
 		switch hf.Name {
@@ -348,33 +585,5 @@ func (c *client) doRequest(req *http.Request, str quic.Stream, opt RoundTripOpt,
 			res.Header.Add(hf.Name, hf.Value)
 		}
 	}
-	respBody := newResponseBody(str, c.conn, reqDone, func() {
-		c.conn.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
-	})
-
-	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
-	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
-	isInformational := res.StatusCode >= 100 && res.StatusCode < 200
-	isNoContent := res.StatusCode == 204
-	isSuccessfulConnect := req.Method == http.MethodConnect && res.StatusCode >= 200 && res.StatusCode < 300
-	if !hasTransferEncoding && !isInformational && !isNoContent && !isSuccessfulConnect {
-		res.ContentLength = -1
-		if clens, ok := res.Header["Content-Length"]; ok && len(clens) == 1 {
-			if clen64, err := strconv.ParseInt(clens[0], 10, 64); err == nil {
-				res.ContentLength = clen64
-			}
-		}
-	}
-
-	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
-		res.Header.Del("Content-Encoding")
-		res.Header.Del("Content-Length")
-		res.ContentLength = -1
-		res.Body = newGzipReader(respBody)
-		res.Uncompressed = true
-	} else {
-		res.Body = respBody
-	}
-
 	return res, requestError{}
 }
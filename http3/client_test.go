@@ -9,7 +9,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
@@ -165,6 +168,24 @@ var _ = Describe("Client", func() {
 		Expect(client.Close()).To(Succeed())
 	})
 
+	It("says that the connection is done if it was not created", func() {
+		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client.connDone()).To(BeClosed())
+	})
+
+	It("says when the connection is done", func() {
+		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		conn := mockquic.NewMockEarlyConnection(mockCtrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		conn.EXPECT().Context().Return(ctx).AnyTimes()
+		client.conn = conn
+		Expect(client.connDone()).ToNot(BeClosed())
+		cancel()
+		Expect(client.connDone()).To(BeClosed())
+	})
+
 	Context("validating the address", func() {
 		It("refuses to do requests for the wrong host", func() {
 			req, err := http.NewRequest("https", "https://quic.clemente.io:1336/foobar.html", nil)
@@ -473,6 +494,109 @@ var _ = Describe("Client", func() {
 			time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to conn.CloseWithError
 		})
 
+		It("parses GOAWAY frames", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 8}).Write(buf)
+			(&goAwayFrame{StreamID: 4}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			Eventually(func() quic.StreamID {
+				client.mutex.Lock()
+				defer client.mutex.Unlock()
+				return client.goAwayID
+			}).Should(Equal(quic.StreamID(4)))
+			Expect(client.hasReceivedGoAway()).To(BeTrue())
+			time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to conn.CloseWithError
+		})
+
+		It("errors when the stream ID of the GOAWAY frame increases", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 4}).Write(buf)
+			(&goAwayFrame{StreamID: 8}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorIDError))
+				Expect(reason).To(Equal("GOAWAY stream ID increased from 4 to 8"))
+				close(done)
+			})
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when the GOAWAY frame contains an invalid stream ID", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 3}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, _ string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorIDError))
+				close(done)
+			})
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when the control stream contains a frame other than GOAWAY after the SETTINGS frame", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{}).Write(buf)
+			(&dataFrame{}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				return controlStr, nil
+			})
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, _ string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorFrameUnexpected))
+				close(done)
+			})
+			_, err := client.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
 		for _, t := range []uint64{streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream} {
 			streamType := t
 			name := "encoder"
@@ -912,6 +1036,253 @@ var _ = Describe("Client", func() {
 			})
 		})
 
+		Context("requests that the server didn't process", func() {
+			It("doesn't send requests after receiving a GOAWAY frame", func() {
+				Expect(client.handleGoAway(4)).To(Succeed())
+				// don't EXPECT any calls to OpenStreamSync
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				var nerr *errNotProcessed
+				Expect(errors.As(err, &nerr)).To(BeTrue())
+				Expect(nerr.err).To(Equal(errGoAway))
+				Expect(nerr.sent).To(BeFalse())
+			})
+
+			It("doesn't use a stream at or above the stream ID of a GOAWAY frame", func() {
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				conn.EXPECT().OpenStreamSync(context.Background()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					Expect(client.handleGoAway(4)).To(Succeed())
+					return str, nil
+				})
+				str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+				str.EXPECT().CancelRead(quic.StreamErrorCode(errorRequestCanceled))
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				var nerr *errNotProcessed
+				Expect(errors.As(err, &nerr)).To(BeTrue())
+				Expect(nerr.err).To(Equal(errGoAway))
+				Expect(nerr.sent).To(BeFalse())
+			})
+
+			It("cancels requests at or above the stream ID of a GOAWAY frame", func() {
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+				str.EXPECT().StreamID().Return(quic.StreamID(8)).AnyTimes()
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				canceled := make(chan struct{})
+				gomock.InOrder(
+					str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled)),
+					str.EXPECT().CancelRead(quic.StreamErrorCode(errorRequestCanceled)).Do(func(quic.StreamErrorCode) { close(canceled) }),
+				)
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+					Expect(client.handleGoAway(4)).To(Succeed())
+					<-canceled
+					return 0, errors.New("read canceled")
+				})
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				var nerr *errNotProcessed
+				Expect(errors.As(err, &nerr)).To(BeTrue())
+				Expect(nerr.err).To(Equal(errGoAway))
+				Expect(nerr.sent).To(BeTrue())
+			})
+
+			It("doesn't cancel requests below the stream ID of a GOAWAY frame", func() {
+				rspBuf := bytes.NewBuffer(getResponse(200))
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				str.EXPECT().StreamID().Return(quic.StreamID(0)).AnyTimes()
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				// don't EXPECT any calls to CancelWrite or CancelRead
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+					Expect(client.handleGoAway(4)).To(Succeed())
+					return rspBuf.Read(b)
+				})
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				time.Sleep(scaleDuration(20 * time.Millisecond)) // make sure the request is not canceled
+			})
+
+			It("returns an errNotProcessed when the server rejects the request", func() {
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				rejectErr := &quic.StreamError{StreamID: 0, ErrorCode: quic.StreamErrorCode(errorRequestRejected)}
+				str.EXPECT().Read(gomock.Any()).Return(0, rejectErr)
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				var nerr *errNotProcessed
+				Expect(errors.As(err, &nerr)).To(BeTrue())
+				Expect(nerr.err).To(Equal(rejectErr))
+				Expect(nerr.sent).To(BeTrue())
+			})
+
+			It("returns an errNotProcessed when the cached connection was closed", func() {
+				testErr := errors.New("connection closed")
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx).Times(2)
+				conn.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).Times(2)
+				// the first request dialed the connection, so it won't be retried
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError(testErr))
+				closedCtx, cancel := context.WithCancel(context.Background())
+				cancel()
+				conn.EXPECT().Context().Return(closedCtx)
+				_, err = client.RoundTripOpt(req, RoundTripOpt{})
+				var nerr *errNotProcessed
+				Expect(errors.As(err, &nerr)).To(BeTrue())
+				Expect(nerr.err).To(MatchError(testErr))
+				Expect(nerr.sent).To(BeFalse())
+			})
+
+			It("doesn't return an errNotProcessed when opening a stream on a cached connection fails", func() {
+				testErr := errors.New("stream open error")
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx).Times(2)
+				conn.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).Times(2)
+				conn.EXPECT().Context().Return(context.Background())
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError(testErr))
+				_, err = client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(Equal(testErr))
+			})
+		})
+
+		Context("tracing", func() {
+			var events chan string
+
+			getTrace := func() *httptrace.ClientTrace {
+				events = make(chan string, 100)
+				return &httptrace.ClientTrace{
+					GetConn: func(hostPort string) { events <- "GetConn " + hostPort },
+					GotConn: func(info httptrace.GotConnInfo) {
+						events <- fmt.Sprintf("GotConn %s, reused: %t", info.Conn.RemoteAddr(), info.Reused)
+					},
+					TLSHandshakeDone:     func(tls.ConnectionState, error) { events <- "TLSHandshakeDone" },
+					WroteHeaders:         func() { events <- "WroteHeaders" },
+					WroteRequest:         func(info httptrace.WroteRequestInfo) { events <- fmt.Sprintf("WroteRequest, error: %v", info.Err) },
+					GotFirstResponseByte: func() { events <- "GotFirstResponseByte" },
+					Got100Continue:       func() { events <- "Got100Continue" },
+					Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+						events <- fmt.Sprintf("Got1xxResponse %d, %s", code, header.Get("Link"))
+						return nil
+					},
+				}
+			}
+
+			BeforeEach(func() {
+				conn.EXPECT().HandshakeComplete().Return(handshakeCtx).AnyTimes()
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{}).AnyTimes()
+				conn.EXPECT().Context().Return(context.Background()).AnyTimes()
+				conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1337}).AnyTimes()
+			})
+
+			getEvents := func() []string {
+				var evs []string
+				for {
+					select {
+					case ev := <-events:
+						evs = append(evs, ev)
+					default:
+						return evs
+					}
+				}
+			}
+
+			It("calls the hooks, and reports if the connection was reused", func() {
+				req := req.WithContext(httptrace.WithClientTrace(context.Background(), getTrace()))
+				for i := 0; i < 2; i++ {
+					str := mockquic.NewMockStream(mockCtrl)
+					rspBuf := bytes.NewBuffer(getResponse(200))
+					conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+					str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+					str.EXPECT().Close()
+					str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+					_, err := client.RoundTripOpt(req, RoundTripOpt{})
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Eventually(events).Should(HaveLen(11)) // TLSHandshakeDone is called asynchronously
+				evs := getEvents()
+				Expect(evs).To(ContainElement("TLSHandshakeDone"))
+				var withoutHandshake []string
+				for _, ev := range evs {
+					if ev != "TLSHandshakeDone" {
+						withoutHandshake = append(withoutHandshake, ev)
+					}
+				}
+				Expect(withoutHandshake).To(Equal([]string{
+					"GetConn quic.clemente.io:1337",
+					"GotConn 1.2.3.4:1337, reused: false",
+					"WroteHeaders",
+					"WroteRequest, error: <nil>",
+					"GotFirstResponseByte",
+					"GetConn quic.clemente.io:1337",
+					"GotConn 1.2.3.4:1337, reused: true",
+					"WroteHeaders",
+					"WroteRequest, error: <nil>",
+					"GotFirstResponseByte",
+				}))
+			})
+
+			It("skips informational responses", func() {
+				rspBuf := &bytes.Buffer{}
+				rspBuf.Write(getHeadersFrame(map[string]string{":status": "100"}))
+				rspBuf.Write(getHeadersFrame(map[string]string{":status": "103", "link": "</style.css>; rel=preload"}))
+				rspBuf.Write(getResponse(200))
+				req := req.WithContext(httptrace.WithClientTrace(context.Background(), getTrace()))
+				conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(rsp.Header).ToNot(HaveKey("Link"))
+				Eventually(events).Should(HaveLen(9))
+				Expect(getEvents()).To(ContainElements(
+					"GotFirstResponseByte",
+					"Got100Continue",
+					"Got1xxResponse 100, ",
+					"Got1xxResponse 103, </style.css>; rel=preload",
+				))
+			})
+
+			It("errors when receiving too many informational responses", func() {
+				rspBuf := &bytes.Buffer{}
+				for i := 0; i < 6; i++ {
+					rspBuf.Write(getHeadersFrame(map[string]string{":status": "103"}))
+				}
+				rspBuf.Write(getResponse(200))
+				conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorGeneralProtocolError))
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("http3: too many 1xx informational responses"))
+			})
+
+			It("aborts the request when the Got1xxResponse hook returns an error", func() {
+				rspBuf := &bytes.Buffer{}
+				rspBuf.Write(getHeadersFrame(map[string]string{":status": "103"}))
+				rspBuf.Write(getResponse(200))
+				testErr := errors.New("test error")
+				req := req.WithContext(httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+					Got1xxResponse: func(int, textproto.MIMEHeader) error { return testErr },
+				}))
+				conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+				_, err := client.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError(testErr))
+			})
+		})
+
 		Context("gzip compression", func() {
 			BeforeEach(func() {
 				conn.EXPECT().HandshakeComplete().Return(handshakeCtx)
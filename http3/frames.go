@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 
+	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/quicvarint"
 )
@@ -59,9 +60,10 @@ func parseNextFrame(r io.Reader, unknownFrameHandler unknownFrameHandlerFunc) (f
 			return &headersFrame{Length: l}, nil
 		case 0x4:
 			return parseSettingsFrame(r, l)
+		case 0x7:
+			return parseGoAwayFrame(r, l)
 		case 0x3: // CANCEL_PUSH
 		case 0x5: // PUSH_PROMISE
-		case 0x7: // GOAWAY
 		case 0xd: // MAX_PUSH_ID
 		}
 		// skip over unknown frames
@@ -162,3 +164,35 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 		quicvarint.Write(b, val)
 	}
 }
+
+type goAwayFrame struct {
+	StreamID quic.StreamID
+}
+
+func parseGoAwayFrame(r io.Reader, l uint64) (*goAwayFrame, error) {
+	if l > 8 {
+		return nil, fmt.Errorf("unexpected size for GOAWAY frame: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := quicvarint.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	if b.Len() > 0 {
+		return nil, fmt.Errorf("unexpected size for GOAWAY frame: %d", l)
+	}
+	return &goAwayFrame{StreamID: quic.StreamID(id)}, nil
+}
+
+func (f *goAwayFrame) Write(b *bytes.Buffer) {
+	quicvarint.Write(b, 0x7)
+	quicvarint.Write(b, uint64(quicvarint.Len(uint64(f.StreamID))))
+	quicvarint.Write(b, uint64(f.StreamID))
+}
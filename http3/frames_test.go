@@ -186,6 +186,46 @@ var _ = Describe("Frames", func() {
 		})
 	})
 
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(quicvarint.Len(0x1337)))
+			data = appendVarInt(data, 0x1337)
+			frame, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&goAwayFrame{}))
+			Expect(frame.(*goAwayFrame).StreamID).To(BeEquivalentTo(0x1337))
+		})
+
+		It("writes", func() {
+			gf := &goAwayFrame{StreamID: 0xdeadbeef}
+			buf := &bytes.Buffer{}
+			gf.Write(buf)
+			frame, err := parseNextFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(gf))
+		})
+
+		It("rejects frames with trailing data", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, 3)
+			data = appendVarInt(data, 0x13)
+			data = append(data, 0x37, 0x42)
+			_, err := parseNextFrame(bytes.NewReader(data), nil)
+			Expect(err).To(MatchError("unexpected size for GOAWAY frame: 3"))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0xdeadbeef}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]), nil)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
 	Context("hijacking", func() {
 		It("reads a frame without hijacking the stream", func() {
 			buf := &bytes.Buffer{}
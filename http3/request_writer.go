@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
 	"strings"
 	"sync"
@@ -39,61 +40,72 @@ func newRequestWriter(logger utils.Logger) *requestWriter {
 }
 
 func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, dontCloseStr, gzip bool) error {
+	trace := httptrace.ContextClientTrace(req.Context())
 	buf := &bytes.Buffer{}
 	if err := w.writeHeaders(buf, req, gzip); err != nil {
+		traceWroteRequest(trace, err)
 		return err
 	}
 	if _, err := str.Write(buf.Bytes()); err != nil {
+		traceWroteRequest(trace, err)
 		return err
 	}
+	traceWroteHeaders(trace)
 	// TODO: add support for trailers
 	if req.Body == nil {
 		if !dontCloseStr {
 			str.Close()
 		}
+		traceWroteRequest(trace, nil)
 		return nil
 	}
 
 	// send the request body asynchronously
 	go func() {
 		defer req.Body.Close()
-		b := make([]byte, bodyCopyBufferSize)
-		for {
-			n, rerr := req.Body.Read(b)
-			if n == 0 {
-				if rerr == nil {
-					continue
-				} else if rerr == io.EOF {
-					break
-				}
-			}
-			buf := &bytes.Buffer{}
-			(&dataFrame{Length: uint64(n)}).Write(buf)
-			if _, err := str.Write(buf.Bytes()); err != nil {
-				w.logger.Errorf("Error writing request: %s", err)
-				return
-			}
-			if _, err := str.Write(b[:n]); err != nil {
-				w.logger.Errorf("Error writing request: %s", err)
-				return
-			}
-			if rerr != nil {
-				if rerr == io.EOF {
-					break
-				}
-				str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
-				w.logger.Errorf("Error writing request: %s", rerr)
-				return
-			}
+		if err := w.writeBody(str, req.Body); err != nil {
+			w.logger.Errorf("Error writing request: %s", err)
+			traceWroteRequest(trace, err)
+			return
 		}
 		if !dontCloseStr {
 			str.Close()
 		}
+		traceWroteRequest(trace, nil)
 	}()
 
 	return nil
 }
 
+func (w *requestWriter) writeBody(str quic.Stream, body io.Reader) error {
+	b := make([]byte, bodyCopyBufferSize)
+	for {
+		n, rerr := body.Read(b)
+		if n == 0 {
+			if rerr == nil {
+				continue
+			} else if rerr == io.EOF {
+				return nil
+			}
+		}
+		buf := &bytes.Buffer{}
+		(&dataFrame{Length: uint64(n)}).Write(buf)
+		if _, err := str.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := str.Write(b[:n]); err != nil {
+			return err
+		}
+		if rerr != nil {
+			if rerr == io.EOF {
+				return nil
+			}
+			str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+			return rerr
+		}
+	}
+}
+
 func (w *requestWriter) writeHeaders(wr io.Writer, req *http.Request, gzip bool) error {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
@@ -242,16 +254,16 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// 	return errRequestHeaderListSize
 	// }
 
-	// trace := httptrace.ContextClientTrace(req.Context())
-	// traceHeaders := traceHasWroteHeaderField(trace)
+	trace := httptrace.ContextClientTrace(req.Context())
+	traceHeaders := traceHasWroteHeaderField(trace)
 
 	// Header list size is ok. Write the headers.
 	enumerateHeaders(func(name, value string) {
 		name = strings.ToLower(name)
 		w.encoder.WriteField(qpack.HeaderField{Name: name, Value: value})
-		// if traceHeaders {
-		// 	traceWroteHeaderField(trace, name, value)
-		// }
+		if traceHeaders {
+			traceWroteHeaderField(trace, name, value)
+		}
 	})
 
 	return nil
@@ -2,10 +2,16 @@ package http3
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
+	"sync"
 
+	"github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 
@@ -16,6 +22,10 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type errorReader struct{ err error }
+
+func (r *errorReader) Read([]byte) (int, error) { return 0, r.err }
+
 type foobarReader struct{}
 
 func (r *foobarReader) Read(b []byte) (int, error) {
@@ -170,4 +180,51 @@ var _ = Describe("Request Writer", func() {
 		Expect(headerFields).To(HaveKeyWithValue(":scheme", "https"))
 		Expect(headerFields).To(HaveKeyWithValue(":protocol", "webtransport"))
 	})
+
+	It("calls the httptrace hooks", func() {
+		closed := make(chan struct{})
+		str.EXPECT().Close().Do(func() { close(closed) })
+		var events []string
+		var mutex sync.Mutex
+		addEvent := func(ev string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			events = append(events, ev)
+		}
+		ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+			WroteHeaderField: func(key string, value []string) {
+				if key == ":method" {
+					addEvent(fmt.Sprintf("WroteHeaderField %s: %s", key, value))
+				}
+			},
+			WroteHeaders: func() { addEvent("WroteHeaders") },
+			WroteRequest: func(info httptrace.WroteRequestInfo) { addEvent(fmt.Sprintf("WroteRequest, error: %v", info.Err)) },
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://quic.clemente.io/upload.html", bytes.NewReader([]byte("foobar")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		Eventually(closed).Should(BeClosed())
+		Eventually(func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return events
+		}).Should(Equal([]string{
+			"WroteHeaderField :method: [POST]",
+			"WroteHeaders",
+			"WroteRequest, error: <nil>",
+		}))
+	})
+
+	It("reports errors when writing the request body to the WroteRequest hook", func() {
+		testErr := errors.New("test error")
+		errChan := make(chan error, 1)
+		ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+			WroteRequest: func(info httptrace.WroteRequestInfo) { errChan <- info.Err },
+		})
+		str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://quic.clemente.io/upload.html", &errorReader{err: testErr})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		Eventually(errChan).Should(Receive(MatchError(testErr)))
+	})
 })
@@ -15,8 +15,14 @@ import (
 	"golang.org/x/net/http/httpguts"
 )
 
+// maxRetries is the maximum number of times a request that the server didn't process
+// is retried on a new connection.
+const maxRetries = 3
+
 type roundTripCloser interface {
 	RoundTripOpt(*http.Request, RoundTripOpt) (*http.Response, error)
+	// connDone returns a channel that is closed when the QUIC connection is closed.
+	connDone() <-chan struct{}
 	io.Closer
 }
 
@@ -68,6 +74,7 @@ type RoundTripper struct {
 	// Dial specifies an optional dial function for creating QUIC
 	// connections for requests.
 	// If Dial is nil, quic.DialAddrEarlyContext will be used.
+	// The DNS and connect hooks of an httptrace.ClientTrace are only called when Dial is nil.
 	Dial func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error)
 
 	// MaxResponseHeaderBytes specifies a limit on how many response bytes are
@@ -75,7 +82,11 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
-	clients map[string]roundTripCloser
+	newClient func(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, conf *quic.Config, dialer dialFunc) (roundTripCloser, error) // so we can mock it in tests
+	clients   map[string]roundTripCloser
+	// clients that are not used for new requests anymore, but might still have requests in flight.
+	// They are removed when their connection is closed.
+	retiredClients map[roundTripCloser]struct{}
 }
 
 // RoundTripOpt are options for the Transport.RoundTripOpt method.
@@ -96,6 +107,10 @@ var (
 var ErrNoCachedConn = errors.New("http3: no cached connection was available")
 
 // RoundTripOpt is like RoundTrip, but takes options.
+// Requests that the server provably didn't process are retried on a new connection:
+// if the server rejected the request (using H3_REQUEST_REJECTED), if it sent a GOAWAY frame
+// before processing the request, or if the cached connection was already closed.
+// Request.GetBody is used to replay the request body, if necessary.
 func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
 	if req.URL == nil {
 		closeRequestBody(req)
@@ -132,11 +147,26 @@ func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.
 	}
 
 	hostname := authorityAddr("https", hostnameFromRequest(req))
-	cl, err := r.getClient(hostname, opt.OnlyCachedConn)
-	if err != nil {
-		return nil, err
+	for retry := 0; ; retry++ {
+		cl, err := r.getClient(hostname, opt.OnlyCachedConn)
+		if err != nil {
+			return nil, err
+		}
+		rsp, err := cl.RoundTripOpt(req, opt)
+		if err == nil {
+			return rsp, nil
+		}
+		var nerr *errNotProcessed
+		if !errors.As(err, &nerr) || retry == maxRetries {
+			return nil, err
+		}
+		// The server didn't process the request. It is safe to retry it on a new connection.
+		r.retireClient(hostname, cl)
+		req, err = rewindBody(req, nerr)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return cl.RoundTripOpt(req, opt)
 }
 
 // RoundTrip does a round trip.
@@ -157,8 +187,14 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (roundTripClo
 		if onlyCached {
 			return nil, ErrNoCachedConn
 		}
+		newCl := func(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, conf *quic.Config, dialer dialFunc) (roundTripCloser, error) {
+			return newClient(hostname, tlsConf, opts, conf, dialer)
+		}
+		if r.newClient != nil {
+			newCl = r.newClient
+		}
 		var err error
-		client, err = newClient(
+		client, err = newCl(
 			hostname,
 			r.TLSClientConfig,
 			&roundTripperOpts{
@@ -179,6 +215,29 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (roundTripClo
 	return client, nil
 }
 
+// retireClient makes sure that no new requests are sent using cl.
+// Requests that are in flight on its connection are not affected.
+// The client is forgotten once its connection is closed.
+func (r *RoundTripper) retireClient(hostname string, cl roundTripCloser) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.clients[hostname] != cl {
+		return
+	}
+	delete(r.clients, hostname)
+	if r.retiredClients == nil {
+		r.retiredClients = make(map[roundTripCloser]struct{})
+	}
+	r.retiredClients[cl] = struct{}{}
+	go func() {
+		<-cl.connDone()
+		r.mutex.Lock()
+		delete(r.retiredClients, cl)
+		r.mutex.Unlock()
+	}()
+}
+
 // Close closes the QUIC connections that this RoundTripper has used
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
@@ -189,9 +248,34 @@ func (r *RoundTripper) Close() error {
 		}
 	}
 	r.clients = nil
+	for client := range r.retiredClients {
+		if err := client.Close(); err != nil {
+			return err
+		}
+	}
+	r.retiredClients = nil
 	return nil
 }
 
+// rewindBody prepares a request that the server didn't process to be sent again.
+// If the request body might have been read already, Request.GetBody is used to obtain a new body.
+// This is the same logic as used by net/http for HTTP/2.
+func rewindBody(req *http.Request, nerr *errNotProcessed) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody || !nerr.sent {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("http3: cannot retry request after the request body was sent (%s); define Request.GetBody to avoid this error", nerr)
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	newReq := *req
+	newReq.Body = body
+	return &newReq, nil
+}
+
 func closeRequestBody(req *http.Request) {
 	if req.Body != nil {
 		req.Body.Close()
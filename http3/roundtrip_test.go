@@ -17,14 +17,26 @@ import (
 )
 
 type mockClient struct {
-	closed bool
+	closed    bool
+	done      chan struct{} // closed when the client is closed, if set
+	roundTrip func(*http.Request) (*http.Response, error)
 }
 
 func (m *mockClient) RoundTripOpt(req *http.Request, _ RoundTripOpt) (*http.Response, error) {
+	if m.roundTrip != nil {
+		return m.roundTrip(req)
+	}
 	return &http.Response{Request: req}, nil
 }
 
+func (m *mockClient) connDone() <-chan struct{} {
+	return m.done
+}
+
 func (m *mockClient) Close() error {
+	if m.done != nil && !m.closed {
+		close(m.done)
+	}
 	m.closed = true
 	return nil
 }
@@ -143,6 +155,7 @@ var _ = Describe("RoundTripper", func() {
 			conn.EXPECT().OpenUniStream().AnyTimes().Return(nil, testErr)
 			conn.EXPECT().HandshakeComplete().Return(handshakeCtx).Times(2)
 			conn.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).Times(2)
+			conn.EXPECT().Context().Return(context.Background()).AnyTimes() // the connection is still alive
 			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				<-closed
 				return nil, errors.New("test done")
@@ -231,6 +244,141 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("retrying requests", func() {
+		var clients []*mockClient
+
+		// addClient makes the RoundTripper use a client that responds using roundTrip
+		addClient := func(roundTrip func(*http.Request) (*http.Response, error)) {
+			clients = append(clients, &mockClient{roundTrip: roundTrip, done: make(chan struct{})})
+		}
+
+		BeforeEach(func() {
+			clients = nil
+			var numClients int
+			rt.newClient = func(string, *tls.Config, *roundTripperOpts, *quic.Config, dialFunc) (roundTripCloser, error) {
+				defer GinkgoRecover()
+				Expect(numClients).To(BeNumerically("<", len(clients)))
+				cl := clients[numClients]
+				numClients++
+				return cl, nil
+			}
+		})
+
+		It("retries requests that the server didn't process on a new connection", func() {
+			addClient(func(*http.Request) (*http.Response, error) {
+				return nil, &errNotProcessed{err: errGoAway}
+			})
+			addClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{Request: req}, nil
+			})
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Request).To(Equal(req1))
+			Expect(rt.clients).To(HaveLen(1))
+			Expect(rt.clients).To(ContainElement(clients[1]))
+			Expect(rt.Close()).To(Succeed())
+			Expect(clients[0].closed).To(BeTrue())
+			Expect(clients[1].closed).To(BeTrue())
+		})
+
+		It("forgets retired clients when their connection is closed", func() {
+			addClient(func(*http.Request) (*http.Response, error) {
+				return nil, &errNotProcessed{err: errGoAway}
+			})
+			addClient(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{Request: req}, nil
+			})
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			numRetiredClients := func() int {
+				rt.mutex.Lock()
+				defer rt.mutex.Unlock()
+				return len(rt.retiredClients)
+			}
+			Expect(numRetiredClients()).To(Equal(1))
+			Consistently(numRetiredClients).Should(Equal(1))
+			// the server closes the connection
+			close(clients[0].done)
+			Eventually(numRetiredClients).Should(BeZero())
+			Expect(clients[0].closed).To(BeFalse())
+		})
+
+		It("doesn't retry requests that failed for other reasons", func() {
+			testErr := errors.New("test error")
+			addClient(func(*http.Request) (*http.Response, error) { return nil, testErr })
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError(testErr))
+			Expect(rt.clients).To(ContainElement(clients[0]))
+		})
+
+		It("gives up after a few retries", func() {
+			var counter int
+			for i := 0; i <= maxRetries; i++ {
+				addClient(func(*http.Request) (*http.Response, error) {
+					counter++
+					return nil, &errNotProcessed{err: errGoAway}
+				})
+			}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError(errGoAway))
+			Expect(counter).To(Equal(maxRetries + 1))
+		})
+
+		It("replays the request body using GetBody", func() {
+			req, err := http.NewRequest(http.MethodPost, "https://www.example.org/upload", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(req.GetBody).ToNot(BeNil())
+			addClient(func(req *http.Request) (*http.Response, error) {
+				data, err := io.ReadAll(req.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(data)).To(Equal("foobar"))
+				return nil, &errNotProcessed{err: errors.New("rejected"), sent: true}
+			})
+			addClient(func(req *http.Request) (*http.Response, error) {
+				data, err := io.ReadAll(req.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(data)).To(Equal("foobar"))
+				return &http.Response{Request: req}, nil
+			})
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("reuses the request body if the request wasn't sent", func() {
+			body := &mockBody{}
+			body.SetData([]byte("foobar"))
+			req1.Body = body
+			addClient(func(*http.Request) (*http.Response, error) {
+				return nil, &errNotProcessed{err: errGoAway}
+			})
+			addClient(func(req *http.Request) (*http.Response, error) {
+				Expect(req.Body).To(Equal(body))
+				return &http.Response{Request: req}, nil
+			})
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("errors if the request body was sent, and GetBody is not set", func() {
+			req1.Body = &mockBody{}
+			addClient(func(*http.Request) (*http.Response, error) {
+				return nil, &errNotProcessed{err: errors.New("rejected"), sent: true}
+			})
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError("http3: cannot retry request after the request body was sent (rejected); define Request.GetBody to avoid this error"))
+		})
+
+		It("only uses cached connections when retrying, if RoundTripOpt.OnlyCachedConn is set", func() {
+			rt.clients = map[string]roundTripCloser{
+				"www.example.org:443": &mockClient{roundTrip: func(*http.Request) (*http.Response, error) {
+					return nil, &errNotProcessed{err: errGoAway}
+				}},
+			}
+			_, err := rt.RoundTripOpt(req1, RoundTripOpt{OnlyCachedConn: true})
+			Expect(err).To(MatchError(ErrNoCachedConn))
+		})
+	})
+
 	Context("closing", func() {
 		It("closes", func() {
 			rt.clients = make(map[string]roundTripCloser)
@@ -159,6 +159,10 @@ type Server struct {
 
 	// Handler is the HTTP request handler to use. If not set, defaults to
 	// http.NotFound.
+	// The context of the request is canceled when the client resets the request stream,
+	// when the connection is closed, or when ServeHTTP returns.
+	// Starting with Go 1.20, context.Cause returns a *quic.StreamError carrying the HTTP/3 error code
+	// if the client reset the stream, or the error the connection was closed with.
 	Handler http.Handler
 
 	// EnableDatagrams enables support for HTTP/3 datagrams.
@@ -549,7 +553,8 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	}
 
 	req.RemoteAddr = conn.RemoteAddr().String()
-	req.Body = newRequestBody(str, onFrameError)
+	reqBody := newRequestBody(str, onFrameError)
+	req.Body = reqBody
 
 	if s.logger.Debug() {
 		s.logger.Infof("%s %s%s, on stream %d", req.Method, req.Host, req.RequestURI, str.StreamID())
@@ -557,8 +562,23 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 		s.logger.Infof("%s %s%s", req.Method, req.Host, req.RequestURI)
 	}
 
-	ctx := str.Context()
-	ctx = context.WithValue(ctx, ServerContextKey, s)
+	// The stream's context is canceled when the client sends a STOP_SENDING frame, or when the connection is closed.
+	// A RESET_STREAM frame only cancels the read-side of the stream.
+	reqCtx, cancel := utils.WithCancelCause(str.Context())
+	readCtx := str.ReadContext()
+	go func() {
+		select {
+		case <-reqCtx.Done():
+		case <-readCtx.Done():
+			select {
+			case <-reqBody.closed:
+				// The handler closed the request body. This doesn't cancel the request.
+			default:
+				cancel(utils.ContextCause(readCtx))
+			}
+		}
+	}()
+	ctx := context.WithValue(reqCtx, ServerContextKey, s)
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, conn.LocalAddr())
 	req = req.WithContext(ctx)
 	r := newResponseWriter(str, conn, s.logger)
@@ -590,6 +610,7 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	if r.usedDataStream() {
 		return requestError{err: errHijacked}
 	}
+	cancel(nil)
 
 	if panicked {
 		r.WriteHeader(500)
@@ -109,6 +109,7 @@ var _ = Describe("Server", func() {
 			conn               *mockquic.MockEarlyConnection
 			exampleGetRequest  *http.Request
 			examplePostRequest *http.Request
+			readContext        context.Context
 		)
 		reqContext := context.Background()
 
@@ -162,6 +163,8 @@ var _ = Describe("Server", func() {
 
 			qpackDecoder = qpack.NewDecoder(nil)
 			str = mockquic.NewMockStream(mockCtrl)
+			readContext = context.Background()
+			str.EXPECT().ReadContext().DoAndReturn(func() context.Context { return readContext }).AnyTimes()
 
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
 			addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
@@ -813,6 +816,74 @@ var _ = Describe("Server", func() {
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
+
+		It("cancels the request context when the client resets the stream", func() {
+			var cancelRead func(error)
+			readContext, cancelRead = utils.WithCancelCause(context.Background())
+			handlerCalled := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Context().Done()).ToNot(BeClosed())
+				cancelRead(&quic.StreamError{StreamID: 4, ErrorCode: quic.StreamErrorCode(errorRequestCanceled)})
+				Eventually(r.Context().Done()).Should(BeClosed())
+				Expect(utils.ContextCause(r.Context())).To(Equal(utils.ContextCause(readContext)))
+				close(handlerCalled)
+			})
+			setRequest(encodeRequest(examplePostRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
+
+			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			Eventually(handlerCalled).Should(BeClosed())
+		})
+
+		It("doesn't cancel the request context when the handler closes the request body", func() {
+			var cancelRead func(error)
+			readContext, cancelRead = utils.WithCancelCause(context.Background())
+			handlerCalled := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Body.Close()).To(Succeed())
+				Consistently(r.Context().Done()).ShouldNot(BeClosed())
+				close(handlerCalled)
+			})
+			setRequest(encodeRequest(examplePostRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(quic.StreamErrorCode(errorRequestCanceled)).Do(func(quic.StreamErrorCode) {
+				cancelRead(errors.New("read canceled"))
+			})
+			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
+
+			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			Eventually(handlerCalled).Should(BeClosed())
+		})
+
+		It("cancels the request context when the handler returns", func() {
+			ctxChan := make(chan context.Context, 1)
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxChan <- r.Context()
+			})
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
+
+			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			var ctx context.Context
+			Expect(ctxChan).To(Receive(&ctx))
+			Expect(ctx.Done()).To(BeClosed())
+		})
 	})
 
 	Context("setting http headers", func() {
@@ -138,4 +138,41 @@ var _ = Describe("Datagram test", func() {
 			})
 		})
 	}
+
+	It("sends batches of datagrams", func() {
+		const numBatches = 10
+		const batchSize = 10
+
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{EnableDatagrams: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < numBatches; i++ {
+				msgs := make([][]byte, 0, batchSize)
+				for j := 0; j < batchSize; j++ {
+					b := make([]byte, 8)
+					binary.BigEndian.PutUint64(b, uint64(i*batchSize+j))
+					msgs = append(msgs, b)
+				}
+				Expect(conn.SendMessages(msgs)).To(Succeed())
+			}
+		}()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{EnableDatagrams: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		for i := 0; i < numBatches*batchSize; i++ {
+			msg, err := conn.ReceiveMessage()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(msg).To(HaveLen(8))
+			Expect(binary.BigEndian.Uint64(msg)).To(BeEquivalentTo(i))
+		}
+	})
 })
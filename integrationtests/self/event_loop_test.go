@@ -0,0 +1,134 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Event Loop", func() {
+	It("transfers data on many connections", func() {
+		const numConns = 20
+
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{EventLoopWorkers: 2}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			for {
+				conn, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					defer GinkgoRecover()
+					str, err := conn.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					// echo the data
+					_, err = io.Copy(str, str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+		}()
+
+		var wg sync.WaitGroup
+		wg.Add(numConns)
+		for i := 0; i < numConns; i++ {
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				conn, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(nil),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.CloseWithError(0, "")
+				str, err := conn.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Write(PRData)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+			}()
+		}
+		wg.Wait()
+	})
+
+	It("times out idle connections", func() {
+		const idleTimeout = 200 * time.Millisecond
+
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				EventLoopWorkers: 1,
+				MaxIdleTimeout:   idleTimeout,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{MaxIdleTimeout: time.Hour}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		start := time.Now()
+		Eventually(serverConn.Context().Done(), 5*idleTimeout).Should(BeClosed())
+		Expect(time.Since(start)).To(BeNumerically(">", idleTimeout/2))
+		_, err = serverConn.AcceptStream(context.Background())
+		Expect(err).To(MatchError(&quic.IdleTimeoutError{}))
+	})
+
+	It("closes connections when the server is closed", func() {
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{EventLoopWorkers: 1}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ln.Close()).To(Succeed())
+		Expect(serverConn.Context().Done()).To(BeClosed())
+		Eventually(conn.Context().Done()).Should(BeClosed())
+		_, err = conn.AcceptStream(context.Background())
+		var appErr *quic.ApplicationError
+		Expect(errors.As(err, &appErr)).To(BeTrue())
+		Expect(appErr.Remote).To(BeTrue())
+	})
+})
@@ -431,6 +431,91 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	It("reports the connection state", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{MaxIdleTimeout: 42 * time.Second}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		serverState := serverConn.ConnectionState()
+		Expect(serverState.Version).To(Equal(protocol.SupportedVersions[0]))
+		Expect(serverState.PeerAddressValidated).To(BeTrue())
+		Expect(serverState.Used0RTT).To(BeFalse())
+		Expect(serverState.DidResume).To(BeFalse())
+		Expect(serverState.PeerTransportParameters).ToNot(BeNil())
+		Expect(serverState.PeerTransportParameters.MaxIdleTimeout).To(Equal(42 * time.Second))
+		Expect(serverState.PathMTU).To(BeNumerically(">=", 1200))
+		Expect(serverState.LocalConnectionIDs).ToNot(BeEmpty())
+		// By default, the client uses zero-length connection IDs.
+		Expect(serverState.PeerConnectionID).To(BeEmpty())
+
+		clientState := conn.ConnectionState()
+		Expect(clientState.Version).To(Equal(serverState.Version))
+		Expect(clientState.PeerAddressValidated).To(BeTrue())
+		Expect(clientState.PeerTransportParameters).ToNot(BeNil())
+		Expect(clientState.LocalConnectionIDs).To(HaveLen(1))
+		Expect(clientState.LocalConnectionIDs[0]).To(BeEmpty())
+		// The server issued all connection IDs the client can use when the handshake completed.
+		Expect(serverState.LocalConnectionIDs).To(ContainElement(clientState.PeerConnectionID))
+		Expect(clientState.PathMTU).To(BeNumerically(">=", 1200))
+	})
+
+	It("exports keying material", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		dial := func() (quic.Connection, quic.Connection) {
+			conn, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			serverConn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			return conn, serverConn
+		}
+
+		conn1, serverConn1 := dial()
+		defer conn1.CloseWithError(0, "")
+		key, err := conn1.ExportKeyingMaterial("foobar", []byte("context"), 42)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(HaveLen(42))
+		serverKey, err := serverConn1.ExportKeyingMaterial("foobar", []byte("context"), 42)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverKey).To(Equal(key))
+		otherKey, err := conn1.ExportKeyingMaterial("raboof", []byte("context"), 42)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(otherKey).ToNot(Equal(key))
+
+		binding1, err := quic.TLSExporterChannelBinding(conn1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(binding1).To(HaveLen(32))
+		serverBinding1, err := quic.TLSExporterChannelBinding(serverConn1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverBinding1).To(Equal(binding1))
+
+		// the channel binding is unique to the connection
+		conn2, serverConn2 := dial()
+		defer conn2.CloseWithError(0, "")
+		binding2, err := quic.TLSExporterChannelBinding(conn2)
+		Expect(err).ToNot(HaveOccurred())
+		serverBinding2, err := quic.TLSExporterChannelBinding(serverConn2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverBinding2).To(Equal(binding2))
+		Expect(binding2).ToNot(Equal(binding1))
+	})
+
 	Context("ALPN", func() {
 		It("negotiates an application protocol", func() {
 			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
@@ -0,0 +1,152 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hole Punching", func() {
+	newUDPConn := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		return conn
+	}
+
+	newTransport := func(conf *quic.Config) (*quic.Transport, *net.UDPConn) {
+		conn := newUDPConn()
+		tr, err := quic.NewTransport(conn, getTLSConfig(), getQuicConfig(conf))
+		Expect(err).ToNot(HaveOccurred())
+		return tr, conn
+	}
+
+	// expectSameConn checks that the two connections are the two endpoints of the same QUIC connection.
+	expectSameConn := func(c1, c2 quic.Connection) {
+		m1, err := c1.ExportKeyingMaterial("test", nil, 16)
+		Expect(err).ToNot(HaveOccurred())
+		m2, err := c2.ExportKeyingMaterial("test", nil, 16)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m1).To(Equal(m2))
+
+		str, err := c1.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		rstr, err := c2.AcceptStream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+	}
+
+	It("establishes a connection when both peers punch at the same time", func() {
+		tr1, conn1 := newTransport(nil)
+		defer conn1.Close()
+		defer tr1.Close()
+		tr2, conn2 := newTransport(nil)
+		defer conn2.Close()
+		defer tr2.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		connChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := tr2.HolePunch(ctx, tr1.Addr(), "localhost", getTLSClientConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			connChan <- conn
+		}()
+		c1, err := tr1.HolePunch(ctx, tr2.Addr(), "localhost", getTLSClientConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		var c2 quic.Connection
+		Eventually(connChan, scaleDuration(5*time.Second)).Should(Receive(&c2))
+		expectSameConn(c1, c2)
+		expectSameConn(c2, c1)
+	})
+
+	It("uses the incoming connection, if dialing fails", func() {
+		tr, conn := newTransport(&quic.Config{HandshakeIdleTimeout: scaleDuration(500 * time.Millisecond)})
+		defer conn.Close()
+		defer tr.Close()
+		// The peer doesn't accept incoming connections.
+		peerConn := newUDPConn()
+		defer peerConn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(5*time.Second))
+		defer cancel()
+		connChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := tr.HolePunch(ctx, peerConn.LocalAddr(), "localhost", getTLSClientConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			connChan <- conn
+		}()
+		peer, err := quic.DialContext(ctx, peerConn, tr.Addr(), "localhost", getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer peer.CloseWithError(0, "")
+		var c quic.Connection
+		Eventually(connChan, scaleDuration(5*time.Second)).Should(Receive(&c))
+		expectSameConn(c, peer)
+	})
+
+	It("sends punch packets", func() {
+		tr, conn := newTransport(nil)
+		defer conn.Close()
+		defer tr.Close()
+		peerConn := newUDPConn()
+		defer peerConn.Close()
+
+		errChan := make(chan error, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(200*time.Millisecond))
+			defer cancel()
+			_, err := tr.HolePunch(ctx, peerConn.LocalAddr(), "localhost", getTLSClientConfig(), &quic.HolePunchConfig{
+				NumPackets: 3,
+				Interval:   scaleDuration(10 * time.Millisecond),
+				Payload:    []byte("punch"),
+			})
+			errChan <- err
+		}()
+
+		var numPunchPackets int
+		b := make([]byte, 1500)
+		for {
+			peerConn.SetReadDeadline(time.Now().Add(scaleDuration(300 * time.Millisecond)))
+			n, addr, err := peerConn.ReadFrom(b)
+			if err != nil {
+				break
+			}
+			Expect(addr.String()).To(Equal(tr.Addr().String()))
+			if string(b[:n]) == "punch" {
+				numPunchPackets++
+			}
+		}
+		Expect(numPunchPackets).To(Equal(3))
+		Eventually(errChan).Should(Receive(MatchError(context.DeadlineExceeded)))
+	})
+
+	It("accepts connections from other peers", func() {
+		tr, conn := newTransport(nil)
+		defer conn.Close()
+		defer tr.Close()
+
+		peer, err := quic.DialAddr(fmt.Sprintf("localhost:%d", tr.Addr().(*net.UDPAddr).Port), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer peer.CloseWithError(0, "")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		c, err := tr.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		expectSameConn(peer, c)
+	})
+})
@@ -11,7 +11,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
@@ -274,6 +276,70 @@ var _ = Describe("HTTP tests", func() {
 				Expect(err).To(HaveOccurred())
 			})
 
+			It("cancels the request context when the client cancels the request", func() {
+				handlerCalled := make(chan struct{})
+				ctxCanceled := make(chan struct{})
+				mux.HandleFunc("/cancel-context", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					close(handlerCalled)
+					select {
+					case <-r.Context().Done():
+						close(ctxCanceled)
+					case <-time.After(5 * time.Second):
+						Fail("request context not canceled")
+					}
+				})
+
+				r, w := io.Pipe()
+				defer w.Close()
+				ctx, cancel := context.WithCancel(context.Background())
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://localhost:"+port+"/cancel-context", r)
+				Expect(err).ToNot(HaveOccurred())
+				errChan := make(chan error, 1)
+				go func() {
+					_, err := client.Do(req)
+					errChan <- err
+				}()
+				Eventually(handlerCalled).Should(BeClosed())
+				Consistently(ctxCanceled, scaleDuration(50*time.Millisecond)).ShouldNot(BeClosed())
+				cancel()
+				Eventually(ctxCanceled).Should(BeClosed())
+				Eventually(errChan).Should(Receive(HaveOccurred()))
+			})
+
+			It("retries requests on a new connection when the cached connection was closed", func() {
+				var conns []quic.EarlyConnection
+				rt := &http3.RoundTripper{
+					TLSClientConfig:    &tls.Config{RootCAs: testdata.GetRootCA()},
+					DisableCompression: true,
+					QuicConfig:         getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+					Dial: func(ctx context.Context, addr string, tlsConf *tls.Config, conf *quic.Config) (quic.EarlyConnection, error) {
+						conn, err := quic.DialAddrEarlyContext(ctx, addr, tlsConf, conf)
+						if err == nil {
+							conns = append(conns, conn)
+						}
+						return conn, err
+					},
+				}
+				defer rt.Close()
+				cl := &http.Client{Transport: rt}
+
+				resp, err := cl.Get("https://localhost:" + port + "/hello")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Expect(resp.Body.Close()).To(Succeed())
+				Expect(conns).To(HaveLen(1))
+
+				Expect(conns[0].CloseWithError(0, "")).To(Succeed())
+				resp, err = cl.Get("https://localhost:" + port + "/hello")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(Equal("Hello, World!\n"))
+				Expect(conns).To(HaveLen(2))
+			})
+
 			It("allows streamed HTTP requests", func() {
 				done := make(chan struct{})
 				mux.HandleFunc("/echoline", func(w http.ResponseWriter, r *http.Request) {
@@ -311,6 +377,77 @@ var _ = Describe("HTTP tests", func() {
 				Expect(req.Body.Close()).To(Succeed())
 				Eventually(done).Should(BeClosed())
 			})
+
+			It("calls the httptrace hooks", func() {
+				var mutex sync.Mutex
+				var events []string
+				addEvent := func(ev string) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					events = append(events, ev)
+				}
+				getEvents := func() []string {
+					mutex.Lock()
+					defer mutex.Unlock()
+					return append([]string{}, events...)
+				}
+				handshakeDone := make(chan struct{})
+				trace := &httptrace.ClientTrace{
+					GetConn:           func(string) { addEvent("GetConn") },
+					DNSStart:          func(httptrace.DNSStartInfo) { addEvent("DNSStart") },
+					DNSDone:           func(httptrace.DNSDoneInfo) { addEvent("DNSDone") },
+					ConnectStart:      func(string, string) { addEvent("ConnectStart") },
+					TLSHandshakeStart: func() { addEvent("TLSHandshakeStart") },
+					ConnectDone: func(network, addr string, err error) {
+						Expect(network).To(Equal("udp"))
+						Expect(addr).To(Equal("127.0.0.1:" + port))
+						Expect(err).ToNot(HaveOccurred())
+						addEvent("ConnectDone")
+					},
+					// TLSHandshakeDone is called asynchronously
+					TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+						defer GinkgoRecover()
+						defer close(handshakeDone)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(state.HandshakeComplete).To(BeTrue())
+					},
+					GotConn: func(info httptrace.GotConnInfo) {
+						Expect(info.Conn.RemoteAddr().String()).To(Equal("127.0.0.1:" + port))
+						addEvent(fmt.Sprintf("GotConn, reused: %t", info.Reused))
+					},
+					WroteHeaders:         func() { addEvent("WroteHeaders") },
+					WroteRequest:         func(httptrace.WroteRequestInfo) { addEvent("WroteRequest") },
+					GotFirstResponseByte: func() { addEvent("GotFirstResponseByte") },
+				}
+				ctx := httptrace.WithClientTrace(context.Background(), trace)
+				for i := 0; i < 2; i++ {
+					req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://localhost:"+port+"/hello", nil)
+					Expect(err).ToNot(HaveOccurred())
+					resp, err := client.Do(req)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(200))
+					_, err = io.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Expect(getEvents()).To(Equal([]string{
+					"GetConn",
+					"DNSStart",
+					"DNSDone",
+					"ConnectStart",
+					"TLSHandshakeStart",
+					"ConnectDone",
+					"GotConn, reused: false",
+					"WroteHeaders",
+					"WroteRequest",
+					"GotFirstResponseByte",
+					"GetConn",
+					"GotConn, reused: true",
+					"WroteHeaders",
+					"WroteRequest",
+					"GotFirstResponseByte",
+				}))
+				Eventually(handshakeDone).Should(BeClosed())
+			})
 		})
 	}
 })
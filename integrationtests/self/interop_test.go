@@ -0,0 +1,245 @@
+package self_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http09"
+	"github.com/lucas-clemente/quic-go/integrationtests/tools/simnet"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// These tests reproduce the test cases of the QUIC interop runner (https://github.com/marten-seemann/quic-interop-runner),
+// using the HTTP/0.9 endpoints used in the interop, on a simulated network.
+var _ = Describe("Interop scenarios", func() {
+	const (
+		// the runner simulates a link with 10 Mbps and an RTT of 30ms
+		bandwidth = 10 * 1000 * 1000 / 8
+		delay     = 15 * time.Millisecond
+	)
+
+	// a quic.Config that doesn't do a Retry
+	noRetryConf := func() *quic.Config {
+		return &quic.Config{AcceptToken: func(net.Addr, *quic.Token) bool { return true }}
+	}
+
+	newNetwork := func(conditions ...simnet.Condition) *simnet.Network {
+		return simnet.NewNetwork(&simnet.Opts{
+			Delay:      delay,
+			Bandwidth:  bandwidth,
+			Conditions: conditions,
+		})
+	}
+
+	// runServer runs a HTTP/0.9 server on the network, serving data on every path.
+	runServer := func(n *simnet.Network, tlsConf *tls.Config, quicConf *quic.Config, data []byte) (closeFn func()) {
+		server := &http09.Server{
+			TLSConfig: tlsConf,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(data)
+			}),
+			QuicConfig: getQuicConfig(quicConf),
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = server.Serve(n.ServerConn())
+		}()
+		// Only call this after a connection was accepted.
+		// Otherwise, Serve might not have created the listener yet.
+		return func() {
+			Expect(server.Close()).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		}
+	}
+
+	newRoundTripper := func(n *simnet.Network, quicConf *quic.Config) *http09.RoundTripper {
+		return &http09.RoundTripper{
+			TLSClientConfig: getTLSClientConfig(),
+			QuicConfig:      getQuicConfig(quicConf),
+			Dial: func(ctx context.Context, _ string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
+				return quic.DialEarlyContext(ctx, n.ClientConn(), n.ServerConn().LocalAddr(), "localhost", tlsConf, quicConf)
+			},
+		}
+	}
+
+	download := func(n *simnet.Network, rt http.RoundTripper, numFiles int, expected []byte) {
+		var wg sync.WaitGroup
+		wg.Add(numFiles)
+		for i := 0; i < numFiles; i++ {
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/file%d", n.ServerConn().LocalAddr(), i), nil)
+				Expect(err).ToNot(HaveOccurred())
+				rsp, err := rt.RoundTrip(req)
+				Expect(err).ToNot(HaveOccurred())
+				defer rsp.Body.Close()
+				data, err := io.ReadAll(rsp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(expected))
+			}(i)
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		Eventually(done, scaleDuration(20*time.Second)).Should(BeClosed())
+	}
+
+	runTransfer := func(n *simnet.Network, serverConf *quic.Config, numFiles int) {
+		defer n.Close()
+		closeServer := runServer(n, getTLSConfig(), serverConf, PRData)
+		defer closeServer()
+		rt := newRoundTripper(n, nil)
+		defer rt.Close()
+		download(n, rt, numFiles, PRData)
+	}
+
+	// packetTypes returns the types of the long header packets coalesced in a datagram.
+	packetTypes := func(data []byte) []protocol.PacketType {
+		var types []protocol.PacketType
+		for len(data) > 0 {
+			hdr, _, rest, err := wire.ParsePacket(data, 0)
+			if err != nil || !hdr.IsLongHeader {
+				break
+			}
+			types = append(types, hdr.Type)
+			data = rest
+		}
+		return types
+	}
+
+	It("handshake", func() {
+		runTransfer(newNetwork(), noRetryConf(), 1)
+	})
+
+	It("transfer", func() {
+		runTransfer(newNetwork(), noRetryConf(), 2)
+	})
+
+	It("retry", func() {
+		var mutex sync.Mutex
+		var sawRetry bool
+		n := newNetwork(func(p *simnet.Packet) bool {
+			if !p.ToServer {
+				for _, t := range packetTypes(p.Data) {
+					if t == protocol.PacketTypeRetry {
+						mutex.Lock()
+						sawRetry = true
+						mutex.Unlock()
+					}
+				}
+			}
+			return true
+		})
+		// By default, quic-go performs a Retry on every incoming connection.
+		runTransfer(n, nil, 2)
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(sawRetry).To(BeTrue())
+	})
+
+	It("ipv6", func() {
+		n := simnet.NewNetwork(&simnet.Opts{
+			ClientAddr: &net.UDPAddr{IP: net.ParseIP("fd00::1"), Port: 1234},
+			ServerAddr: &net.UDPAddr{IP: net.ParseIP("fd00::2"), Port: 443},
+			Delay:      delay,
+			Bandwidth:  bandwidth,
+		})
+		runTransfer(n, noRetryConf(), 2)
+	})
+
+	It("transferloss", func() {
+		runTransfer(newNetwork(simnet.DropRate(0.02)), noRetryConf(), 2)
+	})
+
+	It("transfercorruption", func() {
+		runTransfer(newNetwork(simnet.CorruptRate(0.02)), noRetryConf(), 2)
+	})
+
+	It("blackhole", func() {
+		runTransfer(newNetwork(simnet.Blackhole(300*time.Millisecond, 200*time.Millisecond)), noRetryConf(), 2)
+	})
+
+	for _, tc := range []string{"handshakeloss", "handshakecorruption"} {
+		testcase := tc
+
+		It(testcase, func() {
+			const numConns = 5
+			cond := simnet.DropRate(0.3)
+			if testcase == "handshakecorruption" {
+				cond = simnet.CorruptRate(0.3)
+			}
+			n := newNetwork(cond)
+			defer n.Close()
+			// The runner uses small files for these test cases.
+			data := PRData[:1000]
+			closeServer := runServer(n, getTLSConfig(), noRetryConf(), data)
+			defer closeServer()
+			rt := newRoundTripper(n, nil)
+			defer rt.Close()
+			for i := 0; i < numConns; i++ {
+				download(n, rt, 1, data)
+				Expect(rt.Close()).To(Succeed())
+			}
+		})
+	}
+
+	It("amplificationlimit", func() {
+		var mutex sync.Mutex
+		var (
+			validated                bool
+			rcvdBytes, sentBytes     int
+			exceededAmplificationLim bool
+		)
+		n := newNetwork(func(p *simnet.Packet) bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if validated {
+				return true
+			}
+			if p.ToServer {
+				rcvdBytes += len(p.Data)
+				// The server validates the client's address when it receives a Handshake packet.
+				for _, t := range packetTypes(p.Data) {
+					if t == protocol.PacketTypeHandshake {
+						validated = true
+					}
+				}
+				return true
+			}
+			sentBytes += len(p.Data)
+			if sentBytes > 3*rcvdBytes {
+				exceededAmplificationLim = true
+			}
+			return true
+		})
+		defer n.Close()
+		// The certificate chain is too large to be sent in the first flight.
+		closeServer := runServer(n, getTLSConfigWithLongCertChain(), noRetryConf(), PRData)
+		defer closeServer()
+		rt := newRoundTripper(n, nil)
+		defer rt.Close()
+		download(n, rt, 1, PRData)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(validated).To(BeTrue())
+		Expect(sentBytes).To(BeNumerically(">", 0))
+		Expect(exceededAmplificationLim).To(BeFalse())
+	})
+})
@@ -3,6 +3,7 @@ package self_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"time"
 
@@ -119,4 +120,83 @@ var _ = Describe("Packetization", func() {
 			BeNumerically(">", numMsg*9/10),
 		))
 	})
+
+	It("coalesces small writes", func() {
+		const numMsg = 1000
+
+		serverTracer := newPacketTracer()
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				AcceptToken:             func(net.Addr, *quic.Token) bool { return true },
+				DisablePathMTUDiscovery: true,
+				Tracer:                  newTracer(func() logging.ConnectionTracer { return serverTracer }),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		serverAddr := fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port)
+		defer server.Close()
+
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: serverAddr,
+			DelayPacket: func(dir quicproxy.Direction, _ []byte) time.Duration {
+				return 5 * time.Millisecond
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				DisablePathMTUDiscovery: true,
+				StreamSendBufferSize:    10 * 1024,
+				CoalesceStreamWrites:    true,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, numMsg)
+			_, err = io.ReadFull(str, b)
+			Expect(err).ToNot(HaveOccurred())
+			for i := range b {
+				Expect(b[i]).To(Equal(uint8(i)))
+			}
+			_, err = str.Write([]byte("done"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		str, err := conn.OpenStreamSync(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		// Send numMsg 1-byte messages, and wait for the response.
+		for i := 0; i < numMsg; i++ {
+			_, err = str.Write([]byte{uint8(i)})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(str.Flush()).To(Succeed())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("done")))
+
+		var numStreamFrames int
+		for _, p := range serverTracer.getRcvdPackets() {
+			for _, f := range p.frames {
+				if _, ok := f.(*logging.StreamFrame); ok {
+					numStreamFrames++
+				}
+			}
+		}
+		fmt.Fprintf(GinkgoWriter, "received STREAM frames: %d\n", numStreamFrames)
+		Expect(numStreamFrames).To(BeNumerically("<", numMsg/10))
+	})
 })
@@ -0,0 +1,101 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ping", func() {
+	const rtt = 20 * time.Millisecond
+
+	It("measures the RTT", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serverConnChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverConnChan <- conn
+		}()
+
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return rtt / 2 },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		for i := 0; i < 3; i++ {
+			measured, err := conn.Ping(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(measured).To(BeNumerically(">=", rtt))
+			Expect(measured).To(BeNumerically("<", rtt+scaleDuration(20*time.Millisecond)))
+		}
+
+		// the server can ping the client as well
+		var serverConn quic.Connection
+		Eventually(serverConnChan).Should(Receive(&serverConn))
+		measured, err := serverConn.Ping(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(measured).To(BeNumerically(">=", rtt))
+	})
+
+	It("returns an error when the connection is closed", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		// drop all packets sent by the client after the handshake, so that the PING is never acknowledged
+		var drop int32
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(d quicproxy.Direction, _ []byte) bool {
+				return atomic.LoadInt32(&drop) == 1 && d == quicproxy.DirectionIncoming
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		atomic.StoreInt32(&drop, 1)
+
+		errChan := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			_, err := conn.Ping(context.Background())
+			errChan <- err
+		}()
+		Consistently(errChan, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+		conn.CloseWithError(0, "")
+		Eventually(errChan).Should(Receive(HaveOccurred()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		_, err = conn.Ping(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,83 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared Congestion Control", func() {
+	for _, v := range []struct {
+		name string
+		cc   quic.CongestionControlAlgorithm
+	}{
+		{name: "NewReno", cc: quic.CongestionControlNewReno},
+		{name: "Cubic", cc: quic.CongestionControlCubic},
+	} {
+		name := v.name
+		cc := v.cc
+
+		It(fmt.Sprintf("transfers data on multiple connections sharing a congestion controller, using %s", name), func() {
+			const numConns = 5
+			conf := &quic.Config{ShareCongestionControl: true, CongestionControl: cc}
+
+			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(conf))
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				for {
+					conn, err := ln.Accept(context.Background())
+					if err != nil {
+						return
+					}
+					go func() {
+						defer GinkgoRecover()
+						str, err := conn.AcceptStream(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						// echo the data
+						_, err = io.Copy(str, str)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(str.Close()).To(Succeed())
+					}()
+				}
+			}()
+
+			var wg sync.WaitGroup
+			wg.Add(numConns)
+			for i := 0; i < numConns; i++ {
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					conn, err := quic.DialAddr(
+						fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+						getTLSClientConfig(),
+						getQuicConfig(conf),
+					)
+					Expect(err).ToNot(HaveOccurred())
+					defer conn.CloseWithError(0, "")
+					str, err := conn.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					go func() {
+						defer GinkgoRecover()
+						_, err := str.Write(PRData)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(str.Close()).To(Succeed())
+					}()
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal(PRData))
+				}()
+			}
+			wg.Wait()
+		})
+	}
+})
@@ -526,6 +526,64 @@ var _ = Describe("0-RTT", func() {
 				Expect(get0RTTPackets(tracer.getRcvdPackets())).To(BeEmpty())
 			})
 
+			It("accepts 0-RTT when the server accepts the application data in the session ticket", func() {
+				tlsConf, clientConf := dialAndReceiveSessionTicket(getQuicConfig(&quic.Config{
+					AcceptToken:             func(_ net.Addr, _ *quic.Token) bool { return true },
+					GetSessionTicketAppData: func(quic.Connection) []byte { return []byte("generation 1") },
+				}))
+
+				appDataChan := make(chan []byte, 1)
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					getQuicConfig(&quic.Config{
+						Versions:    []protocol.VersionNumber{version},
+						AcceptToken: func(_ net.Addr, _ *quic.Token) bool { return true },
+						Allow0RTT: func(appData []byte) bool {
+							appDataChan <- appData
+							return true
+						},
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				proxy, _ := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				transfer0RTTData(ln, proxy.LocalPort(), clientConf, nil, PRData)
+				Expect(appDataChan).To(Receive(Equal([]byte("generation 1"))))
+			})
+
+			It("rejects 0-RTT when the server rejects the application data in the session ticket", func() {
+				tlsConf, clientConf := dialAndReceiveSessionTicket(getQuicConfig(&quic.Config{
+					AcceptToken:             func(_ net.Addr, _ *quic.Token) bool { return true },
+					GetSessionTicketAppData: func(quic.Connection) []byte { return []byte("generation 1") },
+				}))
+
+				tracer := newPacketTracer()
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					getQuicConfig(&quic.Config{
+						Versions:    []protocol.VersionNumber{version},
+						AcceptToken: func(_ net.Addr, _ *quic.Token) bool { return true },
+						Allow0RTT:   func(appData []byte) bool { return string(appData) == "generation 2" },
+						Tracer:      newTracer(func() logging.ConnectionTracer { return tracer }),
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+				check0RTTRejected(ln, proxy.LocalPort(), clientConf)
+
+				// The client should send 0-RTT packets, but the server doesn't process them.
+				num0RTT := atomic.LoadUint32(num0RTTPackets)
+				fmt.Fprintf(GinkgoWriter, "Sent %d 0-RTT packets.", num0RTT)
+				Expect(num0RTT).ToNot(BeZero())
+				Expect(get0RTTPackets(tracer.getRcvdPackets())).To(BeEmpty())
+			})
+
 			It("rejects 0-RTT when the ALPN changed", func() {
 				tlsConf, clientConf := dialAndReceiveSessionTicket(nil)
 
@@ -714,6 +772,71 @@ var _ = Describe("0-RTT", func() {
 				})
 			}
 
+			It("replays 0-RTT data when 0-RTT is rejected", func() {
+				tlsConf, clientConf := dialAndReceiveSessionTicket(nil)
+				// now dial new connection with different transport parameters
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					tlsConf,
+					getQuicConfig(&quic.Config{
+						Versions:              []protocol.VersionNumber{version},
+						MaxIncomingUniStreams: 1,
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				conn, err := quic.DialAddrEarly(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					clientConf,
+					getQuicConfig(&quic.Config{
+						Versions: []protocol.VersionNumber{version},
+						// don't replay the first unidirectional stream
+						Replay0RTT: func(id quic.StreamID) bool { return id != 2 },
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.CloseWithError(0, "")
+				// The client remembers that it was allowed to open more than one unidirectional stream.
+				firstStr, err := conn.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = firstStr.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				secondStr, err := conn.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(secondStr.StreamID()).To(Equal(quic.StreamID(6)))
+				go func() {
+					defer GinkgoRecover()
+					_, err := secondStr.Write(PRData)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(secondStr.Close()).To(Succeed())
+				}()
+
+				serverConn, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := serverConn.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.StreamID()).To(Equal(quic.StreamID(2)))
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+				Expect(serverConn.ConnectionState().TLS.Used0RTT).To(BeFalse())
+
+				Expect(conn.ConnectionState().TLS.Used0RTT).To(BeFalse())
+				// The second stream was reopened using the stream ID of the first stream.
+				Expect(secondStr.StreamID()).To(Equal(quic.StreamID(2)))
+				_, err = firstStr.Write([]byte("foobar"))
+				Expect(err).To(MatchError(quic.Err0RTTRejected))
+
+				// The client should send 0-RTT packets, but the server doesn't process them.
+				num0RTT := atomic.LoadUint32(num0RTTPackets)
+				fmt.Fprintf(GinkgoWriter, "Sent %d 0-RTT packets.", num0RTT)
+				Expect(num0RTT).ToNot(BeZero())
+				Expect(serverConn.CloseWithError(0, "")).To(Succeed())
+			})
+
 			It("queues 0-RTT packets, if the Initial is delayed", func() {
 				tlsConf, clientConf := dialAndReceiveSessionTicket(nil)
 
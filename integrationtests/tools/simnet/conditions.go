@@ -0,0 +1,40 @@
+package simnet
+
+import (
+	"math/rand"
+	"time"
+)
+
+// DropRate drops packets in both directions with the given probability (between 0 and 1).
+func DropRate(rate float64) Condition {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return func(*Packet) bool {
+		return r.Float64() >= rate
+	}
+}
+
+// CorruptRate corrupts packets in both directions with the given probability (between 0 and 1).
+// A corrupted packet has one of its bytes changed.
+func CorruptRate(rate float64) Condition {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return func(p *Packet) bool {
+		if len(p.Data) > 0 && r.Float64() < rate {
+			p.Data[r.Intn(len(p.Data))] ^= byte(1 + r.Intn(255))
+		}
+		return true
+	}
+}
+
+// Blackhole periodically drops all packets in both directions.
+// Packets are delivered for the duration on, then dropped for the duration off, and so on.
+// The first period starts when the first packet is sent.
+func Blackhole(on, off time.Duration) Condition {
+	var start time.Time
+	return func(*Packet) bool {
+		now := time.Now()
+		if start.IsZero() {
+			start = now
+		}
+		return now.Sub(start)%(on+off) < on
+	}
+}
@@ -0,0 +1,259 @@
+// Package simnet implements an in-memory network between a QUIC client and a QUIC server.
+// It reproduces the network conditions of the scenarios of the QUIC interop runner
+// (packet loss, corruption, blackholes, bandwidth limits),
+// without using any sockets.
+package simnet
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// Packet is a packet sent on the network.
+type Packet struct {
+	// ToServer is true for packets sent by the client, and false for packets sent by the server.
+	ToServer bool
+	From     *net.UDPAddr
+	To       *net.UDPAddr
+	Data     []byte
+}
+
+// A Condition is applied to every packet sent on the network.
+// It may modify the packet, e.g. by rewriting the addresses or by corrupting the payload.
+// It returns false if the packet is dropped.
+// Conditions are never called concurrently.
+type Condition func(*Packet) bool
+
+// Opts are the options of the network.
+type Opts struct {
+	// The address of the client. Defaults to 10.1.x.y:1234.
+	// quic-go multiplexes connections by the local address of the packet conn,
+	// so the addresses must not be used by any other network at the same time.
+	ClientAddr *net.UDPAddr
+	// The address of the server. Defaults to 10.2.x.y:443.
+	ServerAddr *net.UDPAddr
+	// Delay is the one-way delay.
+	// Note that the RTT is twice the delay.
+	Delay time.Duration
+	// Bandwidth is the bandwidth in each direction, in bytes per second.
+	// If 0, the bandwidth is unlimited.
+	Bandwidth int
+	// Conditions are applied to every packet, in order.
+	// The first condition that drops the packet stops the processing.
+	Conditions []Condition
+}
+
+const (
+	// the number of packets that can be in flight in each direction
+	linkQueueLen = 4096
+	// the number of packets that have arrived, but haven't been read yet
+	receiveQueueLen = 1024
+)
+
+var numNetworks uint32
+
+type queuedPacket struct {
+	deliverAt time.Time
+	packet    *Packet
+}
+
+type link struct {
+	queue     chan queuedPacket
+	busyUntil time.Time // guarded by the network's mutex
+}
+
+// Network is an in-memory network connecting a client and a server.
+type Network struct {
+	mutex sync.Mutex
+
+	delay      time.Duration
+	bandwidth  int
+	conditions []Condition
+
+	client, server *conn
+	toServer       link
+	toClient       link
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	logger utils.Logger
+}
+
+// NewNetwork creates a new network.
+func NewNetwork(opts *Opts) *Network {
+	if opts == nil {
+		opts = &Opts{}
+	}
+	// Every network uses different default addresses.
+	id := atomic.AddUint32(&numNetworks, 1)
+	clientAddr := opts.ClientAddr
+	if clientAddr == nil {
+		clientAddr = &net.UDPAddr{IP: net.IPv4(10, 1, byte(id>>8), byte(id)), Port: 1234}
+	}
+	serverAddr := opts.ServerAddr
+	if serverAddr == nil {
+		serverAddr = &net.UDPAddr{IP: net.IPv4(10, 2, byte(id>>8), byte(id)), Port: 443}
+	}
+	n := &Network{
+		delay:      opts.Delay,
+		bandwidth:  opts.Bandwidth,
+		conditions: opts.Conditions,
+		toServer:   link{queue: make(chan queuedPacket, linkQueueLen)},
+		toClient:   link{queue: make(chan queuedPacket, linkQueueLen)},
+		closed:     make(chan struct{}),
+		logger:     utils.DefaultLogger.WithPrefix("simnet"),
+	}
+	n.client = newConn(n, clientAddr, true)
+	n.server = newConn(n, serverAddr, false)
+	go n.runLink(&n.toServer, n.server)
+	go n.runLink(&n.toClient, n.client)
+	return n
+}
+
+// ClientConn returns the packet conn used by the client.
+func (n *Network) ClientConn() net.PacketConn { return n.client }
+
+// ServerConn returns the packet conn used by the server.
+func (n *Network) ServerConn() net.PacketConn { return n.server }
+
+// Close closes the network, and both packet conns.
+func (n *Network) Close() error {
+	n.closeOnce.Do(func() { close(n.closed) })
+	n.client.Close()
+	n.server.Close()
+	return nil
+}
+
+func (n *Network) send(p *Packet) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for _, cond := range n.conditions {
+		if !cond(p) {
+			n.logger.Debugf("dropping packet (%d bytes) from %s to %s", len(p.Data), p.From, p.To)
+			return
+		}
+	}
+	l := &n.toClient
+	if p.ToServer {
+		l = &n.toServer
+	}
+	now := time.Now()
+	if l.busyUntil.Before(now) {
+		l.busyUntil = now
+	}
+	if n.bandwidth > 0 {
+		l.busyUntil = l.busyUntil.Add(time.Duration(len(p.Data)) * time.Second / time.Duration(n.bandwidth))
+	}
+	select {
+	case l.queue <- queuedPacket{deliverAt: l.busyUntil.Add(n.delay), packet: p}:
+	default:
+		n.logger.Debugf("link queue full, dropping packet (%d bytes) from %s to %s", len(p.Data), p.From, p.To)
+	}
+}
+
+// runLink delivers the packets sent on a link.
+// Packets are delivered in the order they were sent.
+func (n *Network) runLink(l *link, dst *conn) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for {
+		var qp queuedPacket
+		select {
+		case qp = <-l.queue:
+		case <-n.closed:
+			return
+		}
+		if d := time.Until(qp.deliverAt); d > 0 {
+			timer.Reset(d)
+			select {
+			case <-timer.C:
+			case <-n.closed:
+				return
+			}
+		}
+		if !qp.packet.To.IP.Equal(dst.addr.IP) || qp.packet.To.Port != dst.addr.Port {
+			n.logger.Debugf("no host at %s, dropping packet (%d bytes)", qp.packet.To, len(qp.packet.Data))
+			continue
+		}
+		select {
+		case dst.packets <- qp.packet:
+		default:
+			n.logger.Debugf("receive queue full, dropping packet (%d bytes) for %s", len(qp.packet.Data), dst.addr)
+		}
+	}
+}
+
+type conn struct {
+	network  *Network
+	addr     *net.UDPAddr
+	isClient bool
+
+	packets chan *Packet
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ net.PacketConn = &conn{}
+
+func newConn(n *Network, addr *net.UDPAddr, isClient bool) *conn {
+	return &conn{
+		network:  n,
+		addr:     addr,
+		isClient: isClient,
+		packets:  make(chan *Packet, receiveQueueLen),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (c *conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.Data), p.From, nil
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *conn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, errors.New("simnet: not a UDP address")
+	}
+	data := make([]byte, len(b))
+	copy(data, b)
+	c.network.send(&Packet{
+		ToServer: c.isClient,
+		From:     c.addr,
+		To:       udpAddr,
+		Data:     data,
+	})
+	return len(b), nil
+}
+
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *conn) LocalAddr() net.Addr { return c.addr }
+
+// Deadlines are not supported. quic-go never sets them.
+var errDeadlinesNotSupported = errors.New("simnet: deadlines not supported")
+
+func (c *conn) SetDeadline(time.Time) error      { return errDeadlinesNotSupported }
+func (c *conn) SetReadDeadline(time.Time) error  { return errDeadlinesNotSupported }
+func (c *conn) SetWriteDeadline(time.Time) error { return errDeadlinesNotSupported }
@@ -0,0 +1,181 @@
+package simnet
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type received struct {
+	data []byte
+	addr net.Addr
+}
+
+func readPackets(c net.PacketConn) <-chan received {
+	ch := make(chan received, 1000)
+	go func() {
+		for {
+			b := make([]byte, 1500)
+			n, addr, err := c.ReadFrom(b)
+			if err != nil {
+				close(ch)
+				return
+			}
+			ch <- received{data: b[:n], addr: addr}
+		}
+	}()
+	return ch
+}
+
+var _ = Describe("Simulated Network", func() {
+	It("delivers packets in both directions", func() {
+		n := NewNetwork(nil)
+		defer n.Close()
+		fromClient := readPackets(n.ServerConn())
+		fromServer := readPackets(n.ClientConn())
+
+		_, err := n.ClientConn().WriteTo([]byte("foo"), n.ServerConn().LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		var p received
+		Eventually(fromClient).Should(Receive(&p))
+		Expect(p.data).To(Equal([]byte("foo")))
+		Expect(p.addr).To(Equal(n.ClientConn().LocalAddr()))
+
+		_, err = n.ServerConn().WriteTo([]byte("bar"), p.addr)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(fromServer).Should(Receive(&p))
+		Expect(p.data).To(Equal([]byte("bar")))
+		Expect(p.addr).To(Equal(n.ServerConn().LocalAddr()))
+	})
+
+	It("uses different addresses for every network", func() {
+		n1 := NewNetwork(nil)
+		defer n1.Close()
+		n2 := NewNetwork(nil)
+		defer n2.Close()
+		Expect(n1.ClientConn().LocalAddr()).ToNot(Equal(n2.ClientConn().LocalAddr()))
+		Expect(n1.ServerConn().LocalAddr()).ToNot(Equal(n2.ServerConn().LocalAddr()))
+		Expect(n1.ClientConn().LocalAddr()).ToNot(Equal(n1.ServerConn().LocalAddr()))
+	})
+
+	It("drops packets sent to unknown addresses", func() {
+		n := NewNetwork(nil)
+		defer n.Close()
+		fromServer := readPackets(n.ClientConn())
+		_, err := n.ServerConn().WriteTo([]byte("foo"), &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234})
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(fromServer).ShouldNot(Receive())
+	})
+
+	It("delays packets", func() {
+		const delay = 50 * time.Millisecond
+		n := NewNetwork(&Opts{Delay: delay})
+		defer n.Close()
+		fromClient := readPackets(n.ServerConn())
+		start := time.Now()
+		_, err := n.ClientConn().WriteTo([]byte("foo"), n.ServerConn().LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(fromClient).Should(Receive())
+		Expect(time.Since(start)).To(And(
+			BeNumerically(">=", delay),
+			BeNumerically("<", 2*delay),
+		))
+	})
+
+	It("limits the bandwidth", func() {
+		n := NewNetwork(&Opts{Bandwidth: 100 * 1000}) // 100 kB/s
+		defer n.Close()
+		fromClient := readPackets(n.ServerConn())
+		start := time.Now()
+		for i := 0; i < 10; i++ {
+			_, err := n.ClientConn().WriteTo(make([]byte, 1000), n.ServerConn().LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		for i := 0; i < 10; i++ {
+			Eventually(fromClient).Should(Receive())
+		}
+		// 10 kB take 100ms to transmit
+		Expect(time.Since(start)).To(And(
+			BeNumerically(">=", 100*time.Millisecond),
+			BeNumerically("<", 200*time.Millisecond),
+		))
+	})
+
+	It("closes the conns", func() {
+		n := NewNetwork(nil)
+		fromClient := readPackets(n.ServerConn())
+		Expect(n.Close()).To(Succeed())
+		Eventually(fromClient).Should(BeClosed())
+		_, err := n.ClientConn().WriteTo([]byte("foo"), n.ServerConn().LocalAddr())
+		Expect(err).To(MatchError(net.ErrClosed))
+	})
+
+	It("applies conditions", func() {
+		var packets []Packet
+		n := NewNetwork(&Opts{Conditions: []Condition{
+			func(p *Packet) bool {
+				packets = append(packets, *p)
+				return string(p.Data) != "drop"
+			},
+		}})
+		defer n.Close()
+		fromClient := readPackets(n.ServerConn())
+		_, err := n.ClientConn().WriteTo([]byte("drop"), n.ServerConn().LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = n.ClientConn().WriteTo([]byte("foo"), n.ServerConn().LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		var p received
+		Eventually(fromClient).Should(Receive(&p))
+		Expect(p.data).To(Equal([]byte("foo")))
+		Consistently(fromClient).ShouldNot(Receive())
+		Expect(packets).To(HaveLen(2))
+		Expect(packets[0].ToServer).To(BeTrue())
+	})
+
+	Context("conditions", func() {
+		newPacket := func(toServer bool) *Packet {
+			return &Packet{
+				ToServer: toServer,
+				From:     &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234},
+				To:       &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443},
+				Data:     []byte("foobar"),
+			}
+		}
+
+		It("drops packets", func() {
+			cond := DropRate(0.25)
+			var dropped int
+			for i := 0; i < 1000; i++ {
+				if !cond(newPacket(i%2 == 0)) {
+					dropped++
+				}
+			}
+			Expect(dropped).To(BeNumerically("~", 250, 75))
+		})
+
+		It("corrupts packets", func() {
+			cond := CorruptRate(0.25)
+			var corrupted int
+			for i := 0; i < 1000; i++ {
+				p := newPacket(i%2 == 0)
+				Expect(cond(p)).To(BeTrue())
+				if string(p.Data) != "foobar" {
+					corrupted++
+				}
+			}
+			Expect(corrupted).To(BeNumerically("~", 250, 75))
+		})
+
+		It("blackholes packets", func() {
+			cond := Blackhole(50*time.Millisecond, 50*time.Millisecond)
+			Expect(cond(newPacket(true))).To(BeTrue())
+			time.Sleep(75 * time.Millisecond)
+			Expect(cond(newPacket(true))).To(BeFalse())
+			Expect(cond(newPacket(false))).To(BeFalse())
+			time.Sleep(50 * time.Millisecond)
+			Expect(cond(newPacket(false))).To(BeTrue())
+		})
+	})
+})
@@ -15,6 +15,9 @@ import (
 // The StreamID is the ID of a QUIC stream.
 type StreamID = protocol.StreamID
 
+// A ConnectionID is a QUIC Connection ID, as defined in RFC 9000.
+type ConnectionID = protocol.ConnectionID
+
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
@@ -95,6 +98,12 @@ type ReceiveStream interface {
 	// Read will unblock immediately, and future Read calls will fail.
 	// When called multiple times or after reading the io.EOF it is a no-op.
 	CancelRead(StreamErrorCode)
+	// The ReadContext is canceled as soon as the read-side of the stream is aborted.
+	// This happens when CancelRead() is called, when the peer resets the stream,
+	// or when the connection is closed. It is not canceled when the stream is read until io.EOF.
+	// Starting with Go 1.20, context.Cause returns the reason: a *StreamError
+	// (carrying the peer's error code) if the stream was reset, or the error the connection was closed with.
+	ReadContext() context.Context
 	// SetReadDeadline sets the deadline for future Read calls and
 	// any currently-blocked Read call.
 	// A zero value for t means Read will not time out.
@@ -127,9 +136,17 @@ type SendStream interface {
 	// The Context is canceled as soon as the write-side of the stream is closed.
 	// This happens when Close() or CancelWrite() is called, or when the peer
 	// cancels the read-side of their stream.
+	// Starting with Go 1.20, context.Cause returns the reason: a *StreamError
+	// (carrying the peer's error code) if the peer sent a STOP_SENDING frame,
+	// or the error the connection was closed with.
 	Context() context.Context
-	// SetWriteDeadline sets the deadline for future Write calls
-	// and any currently-blocked Write call.
+	// Flush blocks until all data written to the stream has been packed into STREAM frames.
+	// If Config.CoalesceStreamWrites is set, it also makes the stream send buffered data right away,
+	// instead of waiting for previously sent data to be acknowledged.
+	// It must not be called concurrently with Write.
+	Flush() error
+	// SetWriteDeadline sets the deadline for future Write and Flush calls
+	// and any currently-blocked Write or Flush call.
 	// Even if write times out, it may return n > 0, indicating that
 	// some data was successfully written.
 	// A zero value for t means Write will not time out.
@@ -184,15 +201,30 @@ type Connection interface {
 	CloseWithError(ApplicationErrorCode, string) error
 	// The context is cancelled when the connection is closed.
 	Context() context.Context
-	// ConnectionState returns basic details about the QUIC connection.
-	// It blocks until the handshake completes.
-	// Warning: This API should not be considered stable and might change soon.
+	// ConnectionState returns details about the QUIC connection.
+	// Some of the values are only available once the handshake completes.
 	ConnectionState() ConnectionState
+	// ExportKeyingMaterial exports keying material derived from the TLS handshake,
+	// as defined in section 7.5 of RFC 8446.
+	// It returns an error if the handshake hasn't completed yet.
+	// See TLSExporterChannelBinding for the tls-exporter channel binding.
+	ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error)
 
 	// SendMessage sends a message as a datagram, as specified in RFC 9221.
 	SendMessage([]byte) error
+	// SendMessages sends a batch of messages as datagrams.
+	// Every message is sent in its own DATAGRAM frame, so message boundaries are preserved,
+	// but the messages are packed into as few QUIC packets as possible.
+	// The batch is flushed as a whole: the call blocks until all messages have been packed into packets.
+	// If any of the messages is too large, no message is sent.
+	SendMessages([][]byte) error
 	// ReceiveMessage gets a message received in a datagram, as specified in RFC 9221.
 	ReceiveMessage() ([]byte, error)
+	// Ping sends a PING frame to the peer, and blocks until it is acknowledged.
+	// It returns the RTT sample obtained from the acknowledgement.
+	// The PING frame is retransmitted if it is lost.
+	// It returns an error if the context is canceled, or if the connection is closed.
+	Ping(context.Context) (time.Duration, error)
 }
 
 // An EarlyConnection is a connection that is handshaking.
@@ -210,6 +242,28 @@ type EarlyConnection interface {
 	NextConnection() Connection
 }
 
+// A ConnectionIDGenerator generates the connection IDs that are used as source connection IDs.
+// This allows encoding information in the connection ID, e.g. for routing by a load balancer.
+type ConnectionIDGenerator interface {
+	// GenerateConnectionID generates a new connection ID.
+	// Generated connection IDs must be unique and all have the same length.
+	GenerateConnectionID() (ConnectionID, error)
+	// ConnectionIDLen returns the length of the connection IDs generated.
+	// It must be 0, or any value between 4 and 18.
+	// Servers need to use connection IDs of at least 4 bytes.
+	ConnectionIDLen() int
+}
+
+// A CongestionControlAlgorithm is a congestion control algorithm.
+type CongestionControlAlgorithm uint8
+
+const (
+	// CongestionControlNewReno is NewReno (RFC 9002).
+	CongestionControlNewReno CongestionControlAlgorithm = iota
+	// CongestionControlCubic is CUBIC (RFC 8312).
+	CongestionControlCubic
+)
+
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// The QUIC versions that can be negotiated.
@@ -222,6 +276,10 @@ type Config struct {
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
 	ConnectionIDLength int
+	// The ConnectionIDGenerator is used to generate the connection IDs of this endpoint.
+	// If set, ConnectionIDLength is ignored, and the length is determined by the generator.
+	// If not set, random connection IDs of length ConnectionIDLength are used.
+	ConnectionIDGenerator ConnectionIDGenerator
 	// HandshakeIdleTimeout is the idle timeout before completion of the handshake.
 	// Specifically, if we don't receive any packet from the peer within this time, the connection attempt is aborted.
 	// If this value is zero, the timeout is set to 5 seconds.
@@ -240,6 +298,27 @@ type Config struct {
 	//   * else, that it was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptToken func(clientAddr net.Addr, token *Token) bool
+	// RetryOffloadKey is the key shared with a Retry offload service, for example a load balancer.
+	// If set, the server accepts Retry tokens issued by that service (see quicpacket.RetryTokenProtector),
+	// in addition to the tokens it issued itself. Tokens need to use key sequence number 0.
+	// It needs to be 16 bytes long.
+	// This option is only valid for the server.
+	RetryOffloadKey []byte
+	// GetSessionTicketAppData is called by the server when it issues a session ticket,
+	// after the handshake completed. The application data returned is stored in the session ticket,
+	// for example a configuration generation or an authentication context.
+	// It is only used to decide on 0-RTT (see Allow0RTT), and it can't prevent the resumption of the session.
+	// It is called from the connection's run loop, and it must not block.
+	// This option is only valid for the server. It is only called if 0-RTT is enabled (see ListenEarly).
+	GetSessionTicketAppData func(Connection) []byte
+	// Allow0RTT is called by the server when the client offers 0-RTT using a session ticket,
+	// if the transport parameters remembered for 0-RTT are compatible.
+	// It is passed the application data stored in the session ticket (see GetSessionTicketAppData).
+	// If it returns false, 0-RTT is rejected, and the session is resumed using a 1-RTT handshake.
+	// To prevent the resumption of previously issued sessions, rotate the session ticket keys
+	// (see tls.Config.SetSessionTicketKeys).
+	// This option is only valid for the server.
+	Allow0RTT func(appData []byte) bool
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
@@ -268,6 +347,16 @@ type Config struct {
 	// To avoid deadlocks, it is not valid to call other functions on the connection or on streams
 	// in this callback.
 	AllowConnectionWindowIncrease func(sess Connection, delta uint64) bool
+	// StreamSendBufferSize is the maximum amount of data that is buffered for sending on a stream.
+	// Write returns as soon as the data has been copied into the send buffer,
+	// such that small writes don't have to wait until the data has been packed into a packet.
+	// If this value is zero, it will default to 1452 bytes.
+	StreamSendBufferSize uint64
+	// CoalesceStreamWrites enables a Nagle-like algorithm (RFC 896) for stream data:
+	// As long as data sent on a stream hasn't been acknowledged, buffered data is only sent
+	// once it fills a STREAM frame. This coalesces many small writes into fewer, larger STREAM frames.
+	// Flush sends the buffered data right away.
+	CoalesceStreamWrites bool
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// Values above 2^60 are invalid.
 	// If not set, it will default to 100.
@@ -278,6 +367,14 @@ type Config struct {
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	MaxIncomingUniStreams int64
+	// Replay0RTT enables the replay of data sent in 0-RTT, if the server rejects 0-RTT.
+	// It is called for every stream opened during 0-RTT, and returns if the data written on this stream
+	// is safe to replay. Replayed streams are reopened, and their data is resent once the handshake completes.
+	// Since streams that aren't replayed are skipped, replayed streams might be assigned a different stream ID.
+	// Streams that aren't replayed are closed with Err0RTTRejected.
+	// It is called from the connection's run loop, and it must not block.
+	// If not set, 0-RTT data is not replayed. It has no effect for a server.
+	Replay0RTT func(StreamID) bool
 	// The StatelessResetKey is used to generate stateless reset tokens.
 	// If no key is configured, sending of stateless resets is disabled.
 	StatelessResetKey []byte
@@ -291,16 +388,64 @@ type Config struct {
 	// This can be useful if version information is exchanged out-of-band.
 	// It has no effect for a client.
 	DisableVersionNegotiationPackets bool
+	// CongestionControl is the congestion control algorithm used for sending.
+	// If not set, NewReno is used.
+	CongestionControl CongestionControlAlgorithm
+	// ShareCongestionControl makes connections to the same peer (i.e. the same IP address) share a single
+	// congestion controller and pacer, similar to the Congestion Manager (RFC 3124).
+	// Instead of probing the network path independently, the connections then split the shared congestion window:
+	// every connection that has data in flight gets a fair share of the window.
+	// Only connections that enable this option, and that use the same CongestionControl algorithm, share a congestion controller.
+	ShareCongestionControl bool
+	// EventLoopWorkers enables the event loop mode for the connections accepted by a server.
+	// By default, every connection runs its own goroutine, and uses its own timer.
+	// In event loop mode, the connections accepted by the server are driven by at most EventLoopWorkers goroutines,
+	// and the timers of all connections are kept in a shared timer heap.
+	// Idle connections don't use any goroutines, which reduces memory usage and scheduler overhead
+	// for servers handling a very large number of mostly idle connections.
+	// Note that the API is still blocking: Every goroutine reading from or writing to a stream is a goroutine of the application.
+	// It has no effect for a client.
+	EventLoopWorkers int
+	// DSCP is the Differentiated Services Code Point (RFC 2474) that all packets sent on the connection are marked with.
+	// It is combined with the ECN bits that were set on the socket using the IP_TOS or IPV6_TCLASS socket option.
+	// Valid values are 0 to 63. If 0, packets are sent with the traffic class set on the socket.
+	// Marking packets is only supported on Linux, macOS and FreeBSD,
+	// and when the net.PacketConn implements OOBCapablePacketConn (like the *net.UDPConn does).
+	DSCP uint8
 	// See https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/.
 	// Datagrams will only be available when both peers enable datagram support.
 	EnableDatagrams bool
 	Tracer          logging.Tracer
 }
 
-// ConnectionState records basic details about a QUIC connection
+// ConnectionState records details about a QUIC connection
 type ConnectionState struct {
-	TLS               handshake.ConnectionState
+	// TLS contains information about the TLS connection state.
+	TLS handshake.ConnectionState
+	// SupportsDatagrams says if support for QUIC datagrams (RFC 9221) was negotiated.
 	SupportsDatagrams bool
+	// Version is the QUIC version used on this connection.
+	Version VersionNumber
+	// Used0RTT says if 0-RTT was used and accepted by the server.
+	Used0RTT bool
+	// DidResume says if the TLS session was resumed.
+	DidResume bool
+	// PeerTransportParameters are the transport parameters sent by the peer.
+	// It is nil until the peer's transport parameters have been received.
+	// It must not be modified.
+	PeerTransportParameters *logging.TransportParameters
+	// PathMTU is the maximum size of the UDP datagrams sent on this connection.
+	// It increases when Path MTU Discovery finds that the path supports larger datagrams.
+	PathMTU logging.ByteCount
+	// LocalConnectionIDs are the connection IDs that the peer can use to send packets to us,
+	// ordered by their sequence number.
+	LocalConnectionIDs []ConnectionID
+	// PeerConnectionID is the connection ID that we currently use to send packets to the peer.
+	PeerConnectionID ConnectionID
+	// PeerAddressValidated says if the peer's address was validated.
+	// A client always considers the server's address validated.
+	// A server validates the client's address when it receives a Handshake packet from the client.
+	PeerAddressValidated bool
 }
 
 // A Listener for incoming QUIC connections
@@ -1,9 +1,15 @@
 package ackhandler
 
-import "github.com/lucas-clemente/quic-go/internal/wire"
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
 
 type Frame struct {
 	wire.Frame // nil if the frame has already been acknowledged in another packet
 	OnLost     func(wire.Frame)
 	OnAcked    func(wire.Frame)
+	// OnSent is called with the send time of the packet when the packet containing the frame is sent.
+	OnSent func(time.Time)
 }
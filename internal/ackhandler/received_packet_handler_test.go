@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -166,3 +167,18 @@ var _ = Describe("Received Packet Handler", func() {
 		Expect(handler.IsPotentiallyDuplicate(4, protocol.Encryption1RTT)).To(BeTrue())
 	})
 })
+
+func BenchmarkReceivedPacketHandler(b *testing.B) {
+	rttStats := utils.NewRTTStats()
+	sentPackets := newSentPacketHandler(0, protocol.InitialPacketSizeIPv4, rttStats, protocol.PerspectiveClient, true, nil, nil, utils.DefaultLogger)
+	handler := newReceivedPacketHandler(sentPackets, rttStats, utils.DefaultLogger, protocol.Version1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := handler.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true); err != nil {
+			b.Fatal(err)
+		}
+		// this returns an ACK frame for every other packet
+		handler.GetAckFrame(protocol.Encryption1RTT, true)
+	}
+}
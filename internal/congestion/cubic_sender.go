@@ -136,6 +136,7 @@ func (c *cubicSender) OnPacketSent(
 	sentTime time.Time,
 	_ protocol.ByteCount,
 	packetNumber protocol.PacketNumber,
+	_ protocol.EncryptionLevel,
 	bytes protocol.ByteCount,
 	isRetransmittable bool,
 ) {
@@ -174,6 +175,7 @@ func (c *cubicSender) MaybeExitSlowStart() {
 
 func (c *cubicSender) OnPacketAcked(
 	ackedPacketNumber protocol.PacketNumber,
+	_ protocol.EncryptionLevel,
 	ackedBytes protocol.ByteCount,
 	priorInFlight protocol.ByteCount,
 	eventTime time.Time,
@@ -188,7 +190,7 @@ func (c *cubicSender) OnPacketAcked(
 	}
 }
 
-func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes, priorInFlight protocol.ByteCount) {
+func (c *cubicSender) OnPacketLost(packetNumber protocol.PacketNumber, _ protocol.EncryptionLevel, lostBytes, priorInFlight protocol.ByteCount) {
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
 	if packetNumber <= c.largestSentAtLastCutback {
@@ -270,6 +272,9 @@ func (c *cubicSender) BandwidthEstimate() Bandwidth {
 	return BandwidthFromDelta(c.GetCongestionWindow(), srtt)
 }
 
+// OnPacketsDropped is a no-op, since the cubic sender doesn't keep any state for sent packets.
+func (c *cubicSender) OnPacketsDropped(protocol.EncryptionLevel) {}
+
 // OnRetransmissionTimeout is called on an retransmission timeout
 func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	c.largestSentAtLastCutback = protocol.InvalidPacketNumber
@@ -56,7 +56,7 @@ var _ = Describe("Cubic Sender", func() {
 	SendAvailableSendWindowLen := func(packetLength protocol.ByteCount) int {
 		var packetsSent int
 		for sender.CanSend(bytesInFlight) {
-			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, packetLength, true)
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, protocol.Encryption1RTT, packetLength, true)
 			packetNumber++
 			packetsSent++
 			bytesInFlight += packetLength
@@ -70,7 +70,7 @@ var _ = Describe("Cubic Sender", func() {
 		sender.MaybeExitSlowStart()
 		for i := 0; i < n; i++ {
 			ackedPacketNumber++
-			sender.OnPacketAcked(ackedPacketNumber, maxDatagramSize, bytesInFlight, clock.Now())
+			sender.OnPacketAcked(ackedPacketNumber, protocol.Encryption1RTT, maxDatagramSize, bytesInFlight, clock.Now())
 		}
 		bytesInFlight -= protocol.ByteCount(n) * maxDatagramSize
 		clock.Advance(time.Millisecond)
@@ -79,14 +79,14 @@ var _ = Describe("Cubic Sender", func() {
 	LoseNPacketsLen := func(n int, packetLength protocol.ByteCount) {
 		for i := 0; i < n; i++ {
 			ackedPacketNumber++
-			sender.OnPacketLost(ackedPacketNumber, packetLength, bytesInFlight)
+			sender.OnPacketLost(ackedPacketNumber, protocol.Encryption1RTT, packetLength, bytesInFlight)
 		}
 		bytesInFlight -= protocol.ByteCount(n) * packetLength
 	}
 
 	// Does not increment acked_packet_number_.
 	LosePacket := func(number protocol.PacketNumber) {
-		sender.OnPacketLost(number, maxDatagramSize, bytesInFlight)
+		sender.OnPacketLost(number, protocol.Encryption1RTT, maxDatagramSize, bytesInFlight)
 		bytesInFlight -= maxDatagramSize
 	}
 
@@ -463,7 +463,7 @@ var _ = Describe("Cubic Sender", func() {
 
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
 			sender.MaybeExitSlowStart()
-			sender.OnPacketAcked(protocol.PacketNumber(i), 1350, sender.GetCongestionWindow(), clock.Now())
+			sender.OnPacketAcked(protocol.PacketNumber(i), protocol.Encryption1RTT, 1350, sender.GetCongestionWindow(), clock.Now())
 		}
 		Expect(sender.GetCongestionWindow()).To(Equal(initialMaxCongestionWindow))
 	})
@@ -478,7 +478,7 @@ var _ = Describe("Cubic Sender", func() {
 		const packetSize = initialMaxDatagramSize + 100
 		sender.SetMaxDatagramSize(packetSize)
 		for i := 1; i < protocol.MaxCongestionWindowPackets; i++ {
-			sender.OnPacketAcked(protocol.PacketNumber(i), packetSize, sender.GetCongestionWindow(), clock.Now())
+			sender.OnPacketAcked(protocol.PacketNumber(i), protocol.Encryption1RTT, packetSize, sender.GetCongestionWindow(), clock.Now())
 		}
 		const maxCwnd = protocol.MaxCongestionWindowPackets * packetSize
 		Expect(sender.GetCongestionWindow()).To(And(
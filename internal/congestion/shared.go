@@ -0,0 +1,308 @@
+package congestion
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// Connections to the same peer can share a single congestion controller and pacer,
+// similar to the Congestion Manager described in RFC 3124.
+// The connections in a group then compete for the shared congestion window,
+// instead of each connection probing the network path on its own.
+//
+// The cubic sender identifies packets by their packet number.
+// Since every connection uses its own packet number spaces, the group assigns its own sequence of packet numbers
+// to the packets sent by its members.
+
+type groupKey struct {
+	peer string
+	reno bool
+}
+
+var (
+	groupsMutex sync.Mutex
+	groups      = make(map[groupKey]*group)
+)
+
+type group struct {
+	key groupKey
+
+	mutex           sync.Mutex
+	sender          *cubicSender
+	rttStats        *utils.RTTStats
+	clock           Clock
+	maxDatagramSize protocol.ByteCount
+
+	members map[*GroupMember]struct{}
+	// the sum of the bytes in flight of all members
+	bytesInFlight protocol.ByteCount
+	nextPN        protocol.PacketNumber
+}
+
+// A GroupMember is the congestion controller of a connection that is part of a group.
+// It is used instead of a per-connection cubic sender.
+type GroupMember struct {
+	group    *group
+	rttStats *utils.RTTStats
+
+	bytesInFlight protocol.ByteCount
+	// maps the packets of the connection to the packet numbers of the group
+	packetNumbers map[memberPacket]protocol.PacketNumber
+}
+
+// A memberPacket identifies a packet sent by a member.
+// The Initial, Handshake and application data packet number spaces all start at 0,
+// so the packet number alone is not unique.
+type memberPacket struct {
+	encLevel     protocol.EncryptionLevel
+	packetNumber protocol.PacketNumber
+}
+
+var _ SendAlgorithmWithDebugInfos = &GroupMember{}
+
+// JoinGroup adds a connection to the group of connections to peer.
+// Connections only share a congestion controller with connections that use the same algorithm (NewReno or Cubic).
+// If no such group exists yet, a new group is created.
+// The rttStats are the RTT statistics of the connection. The group uses their samples to maintain its own RTT estimate.
+// Leave must be called when the connection is closed.
+func JoinGroup(
+	peer string,
+	clock Clock,
+	rttStats *utils.RTTStats,
+	initialMaxDatagramSize protocol.ByteCount,
+	reno bool,
+) *GroupMember {
+	key := groupKey{peer: peer, reno: reno}
+
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+
+	g, ok := groups[key]
+	if !ok {
+		g = &group{
+			key:             key,
+			rttStats:        utils.NewRTTStats(),
+			clock:           clock,
+			maxDatagramSize: initialMaxDatagramSize,
+			members:         make(map[*GroupMember]struct{}),
+		}
+		g.sender = NewCubicSender(clock, g.rttStats, initialMaxDatagramSize, reno, nil)
+		groups[key] = g
+	}
+	m := &GroupMember{
+		group:         g,
+		rttStats:      rttStats,
+		packetNumbers: make(map[memberPacket]protocol.PacketNumber),
+	}
+	g.mutex.Lock()
+	g.members[m] = struct{}{}
+	g.mutex.Unlock()
+	return m
+}
+
+// Leave removes the connection from its group.
+// The group is deleted when the last member leaves.
+func (m *GroupMember) Leave() {
+	g := m.group
+
+	groupsMutex.Lock()
+	defer groupsMutex.Unlock()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.members[m]; !ok {
+		return
+	}
+	delete(g.members, m)
+	g.bytesInFlight -= m.bytesInFlight
+	m.bytesInFlight = 0
+	if len(g.members) == 0 {
+		delete(groups, g.key)
+	}
+}
+
+// setBytesInFlight updates the bytes in flight of the member, and of the group.
+// It must be called with the group's mutex held.
+func (m *GroupMember) setBytesInFlight(b protocol.ByteCount) {
+	m.group.bytesInFlight = m.group.bytesInFlight - m.bytesInFlight + b
+	m.bytesInFlight = b
+}
+
+// removeBytesInFlight removes bytes from the bytes in flight.
+// The connection only reports its bytes in flight when sending,
+// so packets that are acknowledged or lost need to be removed here.
+// It must be called with the group's mutex held.
+func (m *GroupMember) removeBytesInFlight(b protocol.ByteCount) {
+	if b > m.bytesInFlight {
+		b = m.bytesInFlight
+	}
+	m.setBytesInFlight(m.bytesInFlight - b)
+}
+
+// numActiveMembers returns the number of members that have bytes in flight.
+// It must be called with the group's mutex held.
+func (g *group) numActiveMembers() int {
+	var n int
+	for m := range g.members {
+		if m.bytesInFlight > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *GroupMember) TimeUntilSend(bytesInFlight protocol.ByteCount) time.Time {
+	m.group.mutex.Lock()
+	defer m.group.mutex.Unlock()
+
+	m.setBytesInFlight(bytesInFlight)
+	return m.group.sender.TimeUntilSend(m.group.bytesInFlight)
+}
+
+func (m *GroupMember) HasPacingBudget() bool {
+	m.group.mutex.Lock()
+	defer m.group.mutex.Unlock()
+
+	return m.group.sender.HasPacingBudget()
+}
+
+func (m *GroupMember) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, encLevel protocol.EncryptionLevel, bytes protocol.ByteCount, isRetransmittable bool) {
+	g := m.group
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	m.setBytesInFlight(bytesInFlight)
+	pn := g.nextPN
+	if isRetransmittable {
+		m.packetNumbers[memberPacket{encLevel: encLevel, packetNumber: packetNumber}] = pn
+		g.nextPN++
+	}
+	g.sender.OnPacketSent(sentTime, g.bytesInFlight, pn, encLevel, bytes, isRetransmittable)
+}
+
+// CanSend says if the connection is allowed to send.
+// The bytes in flight of all members must fit into the shared congestion window.
+// In addition, every connection that has bytes in flight gets a fair share of the congestion window.
+// A connection that doesn't have any bytes in flight may always send, if the shared window allows,
+// such that idle connections don't reduce the share of the active connections.
+func (m *GroupMember) CanSend(bytesInFlight protocol.ByteCount) bool {
+	g := m.group
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	m.setBytesInFlight(bytesInFlight)
+	if !g.sender.CanSend(g.bytesInFlight) {
+		return false
+	}
+	if m.bytesInFlight == 0 {
+		return true
+	}
+	share := g.sender.GetCongestionWindow() / protocol.ByteCount(g.numActiveMembers())
+	return m.bytesInFlight < share
+}
+
+// MaybeExitSlowStart is called after the connection updated its RTT estimate.
+// The new RTT sample is also used for the group's RTT estimate.
+func (m *GroupMember) MaybeExitSlowStart() {
+	g := m.group
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	sendDelta := m.rttStats.LatestSendDelta()
+	g.rttStats.UpdateRTT(sendDelta, sendDelta-m.rttStats.LatestRTT(), g.clock.Now())
+	g.sender.MaybeExitSlowStart()
+}
+
+func (m *GroupMember) OnPacketAcked(number protocol.PacketNumber, encLevel protocol.EncryptionLevel, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time) {
+	g := m.group
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// priorInFlight are the bytes in flight of the connection before the ACK was received.
+	// Add the bytes in flight of all other members to get the bytes in flight of the group.
+	groupPriorInFlight := g.bytesInFlight - m.bytesInFlight + priorInFlight
+	m.removeBytesInFlight(ackedBytes)
+	key := memberPacket{encLevel: encLevel, packetNumber: number}
+	pn, ok := m.packetNumbers[key]
+	if !ok {
+		return
+	}
+	delete(m.packetNumbers, key)
+	g.sender.OnPacketAcked(pn, encLevel, ackedBytes, groupPriorInFlight, eventTime)
+}
+
+func (m *GroupMember) OnPacketLost(number protocol.PacketNumber, encLevel protocol.EncryptionLevel, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount) {
+	g := m.group
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	groupPriorInFlight := g.bytesInFlight - m.bytesInFlight + priorInFlight
+	m.removeBytesInFlight(lostBytes)
+	key := memberPacket{encLevel: encLevel, packetNumber: number}
+	pn, ok := m.packetNumbers[key]
+	if !ok {
+		return
+	}
+	delete(m.packetNumbers, key)
+	g.sender.OnPacketLost(pn, encLevel, lostBytes, groupPriorInFlight)
+}
+
+// OnPacketsDropped forgets the packets sent with an encryption level.
+// This happens when the Initial and Handshake packet number spaces are dropped.
+func (m *GroupMember) OnPacketsDropped(encLevel protocol.EncryptionLevel) {
+	m.group.mutex.Lock()
+	defer m.group.mutex.Unlock()
+
+	for p := range m.packetNumbers {
+		if p.encLevel == encLevel {
+			delete(m.packetNumbers, p)
+		}
+	}
+}
+
+func (m *GroupMember) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	m.group.mutex.Lock()
+	defer m.group.mutex.Unlock()
+
+	m.group.sender.OnRetransmissionTimeout(packetsRetransmitted)
+}
+
+// SetMaxDatagramSize sets the maximum datagram size.
+// The members of a group might use different datagram sizes. The group uses the largest one.
+func (m *GroupMember) SetMaxDatagramSize(s protocol.ByteCount) {
+	g := m.group
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if s <= g.maxDatagramSize {
+		return
+	}
+	g.maxDatagramSize = s
+	g.sender.SetMaxDatagramSize(s)
+}
+
+func (m *GroupMember) InSlowStart() bool {
+	m.group.mutex.Lock()
+	defer m.group.mutex.Unlock()
+
+	return m.group.sender.InSlowStart()
+}
+
+func (m *GroupMember) InRecovery() bool {
+	m.group.mutex.Lock()
+	defer m.group.mutex.Unlock()
+
+	return m.group.sender.InRecovery()
+}
+
+// GetCongestionWindow returns the shared congestion window of the group.
+func (m *GroupMember) GetCongestionWindow() protocol.ByteCount {
+	m.group.mutex.Lock()
+	defer m.group.mutex.Unlock()
+
+	return m.group.sender.GetCongestionWindow()
+}
@@ -0,0 +1,199 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared Congestion Control", func() {
+	const packetSize = protocol.InitialPacketSizeIPv4
+
+	var (
+		clock   mockClock
+		members []*GroupMember
+	)
+
+	join := func(peer string, reno bool) (*GroupMember, *utils.RTTStats) {
+		rttStats := utils.NewRTTStats()
+		m := JoinGroup(peer, &clock, rttStats, packetSize, reno)
+		members = append(members, m)
+		return m, rttStats
+	}
+
+	// sendPackets sends n packets, starting with packet number pn, and returns the new bytes in flight
+	sendPackets := func(m *GroupMember, bytesInFlight protocol.ByteCount, pn protocol.PacketNumber, n int) protocol.ByteCount {
+		for i := 0; i < n; i++ {
+			bytesInFlight += packetSize
+			m.OnPacketSent(clock.Now(), bytesInFlight, pn+protocol.PacketNumber(i), protocol.Encryption1RTT, packetSize, true)
+		}
+		return bytesInFlight
+	}
+
+	BeforeEach(func() {
+		clock = mockClock{}
+		members = nil
+	})
+
+	AfterEach(func() {
+		for _, m := range members {
+			m.Leave()
+		}
+		groupsMutex.Lock()
+		defer groupsMutex.Unlock()
+		Expect(groups).To(BeEmpty())
+	})
+
+	It("shares the congestion controller between connections to the same peer", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", true)
+		Expect(m1.group).To(Equal(m2.group))
+	})
+
+	It("uses separate congestion controllers for different peers", func() {
+		m1, _ := join("peer1", true)
+		m2, _ := join("peer2", true)
+		Expect(m1.group).ToNot(Equal(m2.group))
+	})
+
+	It("uses separate congestion controllers for different algorithms", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", false)
+		Expect(m1.group).ToNot(Equal(m2.group))
+	})
+
+	It("limits the bytes in flight of all connections to the shared congestion window", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", true)
+		cwnd := m1.GetCongestionWindow()
+		// m1 uses almost the entire congestion window
+		inFlight1 := sendPackets(m1, 0, 1, int(cwnd/packetSize)-1)
+		Expect(m1.group.bytesInFlight).To(Equal(inFlight1))
+		Expect(m2.CanSend(0)).To(BeTrue())
+		inFlight2 := sendPackets(m2, 0, 1, 1)
+		Expect(m1.group.bytesInFlight).To(Equal(inFlight1 + inFlight2))
+		Expect(m1.group.bytesInFlight).To(BeNumerically(">=", cwnd))
+		Expect(m1.CanSend(inFlight1)).To(BeFalse())
+		Expect(m2.CanSend(inFlight2)).To(BeFalse())
+	})
+
+	It("gives every active connection a fair share of the congestion window", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", true)
+		m3, _ := join("peer", true)
+		cwnd := m1.GetCongestionWindow()
+		inFlight1 := sendPackets(m1, 0, 1, int(cwnd/2/packetSize)+2)
+		// m1 is the only active connection, and may use the entire window
+		Expect(m1.CanSend(inFlight1)).To(BeTrue())
+		inFlight2 := sendPackets(m2, 0, 1, 1)
+		// now m1 and m2 share the window
+		Expect(m1.CanSend(inFlight1)).To(BeFalse())
+		Expect(m2.CanSend(inFlight2)).To(BeTrue())
+		// m3 is idle, and may send
+		Expect(m3.CanSend(0)).To(BeTrue())
+		// once m1's packets are acknowledged, it may send again
+		m1.OnPacketAcked(1, protocol.Encryption1RTT, packetSize, inFlight1, clock.Now())
+		m1.OnPacketAcked(2, protocol.Encryption1RTT, packetSize, inFlight1, clock.Now())
+		m1.OnPacketAcked(3, protocol.Encryption1RTT, packetSize, inFlight1, clock.Now())
+		Expect(m1.CanSend(inFlight1 - 3*packetSize)).To(BeTrue())
+	})
+
+	It("grows the shared congestion window when packets are acknowledged", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", true)
+		cwnd := m1.GetCongestionWindow()
+		inFlight := sendPackets(m1, 0, 1, int(cwnd/packetSize))
+		m1.OnPacketAcked(1, protocol.Encryption1RTT, packetSize, inFlight, clock.Now())
+		Expect(m2.GetCongestionWindow()).To(Equal(cwnd + packetSize))
+	})
+
+	It("treats losses of packets sent by different connections as a single loss event", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", true)
+		cwnd := m1.GetCongestionWindow()
+		inFlight1 := sendPackets(m1, 0, 100, 5)
+		inFlight2 := sendPackets(m2, 0, 1, 5)
+		m1.OnPacketLost(100, protocol.Encryption1RTT, packetSize, inFlight1)
+		reducedCwnd := m2.GetCongestionWindow()
+		Expect(reducedCwnd).To(BeNumerically("<", cwnd))
+		Expect(m2.InRecovery()).To(BeFalse()) // no packet was acknowledged yet
+		// m2's packet was sent before the loss was detected
+		m2.OnPacketLost(1, protocol.Encryption1RTT, packetSize, inFlight2)
+		Expect(m1.GetCongestionWindow()).To(Equal(reducedCwnd))
+	})
+
+	It("distinguishes packets with the same packet number in different packet number spaces", func() {
+		m, _ := join("peer", true)
+		m.OnPacketSent(clock.Now(), packetSize, 0, protocol.EncryptionInitial, packetSize, true)
+		m.OnPacketSent(clock.Now(), 2*packetSize, 0, protocol.EncryptionHandshake, packetSize, true)
+		m.OnPacketSent(clock.Now(), 3*packetSize, 0, protocol.Encryption1RTT, packetSize, true)
+		Expect(m.packetNumbers).To(HaveLen(3))
+		m.OnPacketAcked(0, protocol.EncryptionHandshake, packetSize, 3*packetSize, clock.Now())
+		// the Handshake packet was the second packet sent by the group
+		Expect(m.group.sender.largestAckedPacketNumber).To(Equal(protocol.PacketNumber(1)))
+		Expect(m.packetNumbers).To(Equal(map[memberPacket]protocol.PacketNumber{
+			{encLevel: protocol.EncryptionInitial}: 0,
+			{encLevel: protocol.Encryption1RTT}:    2,
+		}))
+	})
+
+	It("forgets the packets of dropped packet number spaces", func() {
+		m, _ := join("peer", true)
+		m.OnPacketSent(clock.Now(), packetSize, 0, protocol.EncryptionInitial, packetSize, true)
+		m.OnPacketSent(clock.Now(), 2*packetSize, 1, protocol.EncryptionInitial, packetSize, true)
+		m.OnPacketSent(clock.Now(), 3*packetSize, 0, protocol.EncryptionHandshake, packetSize, true)
+		m.OnPacketsDropped(protocol.EncryptionInitial)
+		Expect(m.packetNumbers).To(Equal(map[memberPacket]protocol.PacketNumber{
+			{encLevel: protocol.EncryptionHandshake}: 2,
+		}))
+		// acknowledging a packet of the dropped packet number space has no effect
+		m.OnPacketAcked(1, protocol.EncryptionInitial, packetSize, 3*packetSize, clock.Now())
+		Expect(m.group.sender.largestAckedPacketNumber).To(Equal(protocol.InvalidPacketNumber))
+	})
+
+	It("uses the RTT samples of all connections", func() {
+		m1, rttStats1 := join("peer", true)
+		m2, rttStats2 := join("peer", true)
+		rttStats1.UpdateRTT(100*time.Millisecond, 0, clock.Now())
+		m1.MaybeExitSlowStart()
+		Expect(m1.group.rttStats.MinRTT()).To(Equal(100 * time.Millisecond))
+		rttStats2.UpdateRTT(50*time.Millisecond, 0, clock.Now())
+		m2.MaybeExitSlowStart()
+		Expect(m1.group.rttStats.MinRTT()).To(Equal(50 * time.Millisecond))
+		Expect(m1.group.rttStats.LatestRTT()).To(Equal(50 * time.Millisecond))
+	})
+
+	It("uses the largest datagram size", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", true)
+		m1.SetMaxDatagramSize(1400)
+		Expect(m1.group.sender.maxDatagramSize).To(Equal(protocol.ByteCount(1400)))
+		// m2 didn't discover the larger datagram size yet
+		Expect(func() { m2.SetMaxDatagramSize(1300) }).ToNot(Panic())
+		Expect(m1.group.sender.maxDatagramSize).To(Equal(protocol.ByteCount(1400)))
+	})
+
+	It("removes the bytes in flight of a connection when it leaves", func() {
+		m1, _ := join("peer", true)
+		m2, _ := join("peer", true)
+		inFlight1 := sendPackets(m1, 0, 1, 3)
+		inFlight2 := sendPackets(m2, 0, 1, 2)
+		Expect(m1.group.bytesInFlight).To(Equal(inFlight1 + inFlight2))
+		m1.Leave()
+		Expect(m2.group.bytesInFlight).To(Equal(inFlight2))
+		// leaving twice is a no-op
+		m1.Leave()
+		Expect(m2.group.bytesInFlight).To(Equal(inFlight2))
+	})
+
+	It("deletes the group when the last connection leaves", func() {
+		m1, _ := join("peer", true)
+		m1.Leave()
+		m2, _ := join("peer", true)
+		Expect(m2.group).ToNot(BeIdenticalTo(m1.group))
+	})
+})
@@ -115,6 +115,8 @@ type cryptoSetup struct {
 	clientHelloWritten     bool
 	clientHelloWrittenChan chan struct{} // is closed as soon as the ClientHello is written
 	zeroRTTParametersChan  chan<- *wire.TransportParameters
+	// allow0RTT is called by the server to check the application data stored in the session ticket.
+	allow0RTT func(appData []byte) bool
 
 	rttStats *utils.RTTStats
 
@@ -196,6 +198,7 @@ func NewCryptoSetupServer(
 	runner handshakeRunner,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	allow0RTT func(appData []byte) bool,
 	rttStats *utils.RTTStats,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
@@ -215,6 +218,7 @@ func NewCryptoSetupServer(
 		protocol.PerspectiveServer,
 		version,
 	)
+	cs.allow0RTT = allow0RTT
 	cs.conn = qtls.Server(newConn(localAddr, remoteAddr, version), cs.tlsConf, cs.extraConf)
 	return cs
 }
@@ -470,16 +474,19 @@ func (h *cryptoSetup) handleDataFromSessionStateImpl(data []byte) (*wire.Transpo
 }
 
 // only valid for the server
-func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
-	var appData []byte
+// The application data is stored in the session ticket, if 0-RTT is enabled.
+// Without 0-RTT, it would never be read: the TLS stack only passes it to the server when deciding on 0-RTT.
+func (h *cryptoSetup) GetSessionTicket(appData []byte) ([]byte, error) {
+	var ticketData []byte
 	// Save transport parameters to the session ticket if we're allowing 0-RTT.
 	if h.extraConf.MaxEarlyData > 0 {
-		appData = (&sessionTicket{
+		ticketData = (&sessionTicket{
 			Parameters: h.ourParams,
 			RTT:        h.rttStats.SmoothedRTT(),
+			AppData:    appData,
 		}).Marshal()
 	}
-	return h.conn.GetSessionTicket(appData)
+	return h.conn.GetSessionTicket(ticketData)
 }
 
 // accept0RTT is called for the server when receiving the client's session ticket.
@@ -490,14 +497,17 @@ func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
 		h.logger.Debugf("Unmarshalling transport parameters from session ticket failed: %s", err.Error())
 		return false
 	}
-	valid := h.ourParams.ValidFor0RTT(t.Parameters)
-	if valid {
-		h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
-		h.rttStats.SetInitialRTT(t.RTT)
-	} else {
+	if !h.ourParams.ValidFor0RTT(t.Parameters) {
 		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
+		return false
+	}
+	if h.allow0RTT != nil && !h.allow0RTT(t.AppData) {
+		h.logger.Debugf("Application rejected 0-RTT.")
+		return false
 	}
-	return valid
+	h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
+	h.rttStats.SetInitialRTT(t.RTT)
+	return true
 }
 
 // rejected0RTT is called for the client when the server rejects 0-RTT.
@@ -96,6 +96,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -178,6 +179,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -219,6 +221,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -254,6 +257,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -297,6 +301,16 @@ var _ = Describe("Crypto Setup TLS", func() {
 			return rttStats
 		}
 
+		var (
+			sessionTicketAppData []byte                    // stored in the session ticket issued by the server
+			serverAllow0RTT      func(appData []byte) bool // passed to the server by handshakeWithTLSConf
+		)
+
+		BeforeEach(func() {
+			sessionTicketAppData = nil
+			serverAllow0RTT = nil
+		})
+
 		handshake := func(client CryptoSetup, cChunkChan <-chan chunk,
 			server CryptoSetup, sChunkChan <-chan chunk,
 		) {
@@ -337,7 +351,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				defer GinkgoRecover()
 				defer close(done)
 				server.RunHandshake()
-				ticket, err := server.GetSessionTicket()
+				ticket, err := server.GetSessionTicket(sessionTicketAppData)
 				Expect(err).ToNot(HaveOccurred())
 				if ticket != nil {
 					client.HandleMessage(ticket, protocol.Encryption1RTT)
@@ -399,6 +413,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				enable0RTT,
+				serverAllow0RTT,
 				serverRTTStats,
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -537,6 +552,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				false,
+				nil,
 				&utils.RTTStats{},
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -592,6 +608,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
@@ -651,6 +668,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
@@ -859,6 +877,67 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(server.ConnectionState().Used0RTT).To(BeFalse())
 				Expect(client.ConnectionState().Used0RTT).To(BeFalse())
 			})
+
+			Context("with application data", func() {
+				// handshakeAndResume performs a handshake, and uses the session ticket to resume with 0-RTT.
+				// It returns the server's crypto setup of the resumed connection.
+				handshakeAndResume := func() CryptoSetup {
+					csc := mocktls.NewMockClientSessionCache(mockCtrl)
+					var state *tls.ClientSessionState
+					receivedSessionTicket := make(chan struct{})
+					csc.EXPECT().Get(gomock.Any())
+					csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) {
+						state = css
+						close(receivedSessionTicket)
+					})
+					clientConf.ClientSessionCache = csc
+					_, _, clientErr, _, serverErr := handshakeWithTLSConf(
+						clientConf, serverConf,
+						&utils.RTTStats{}, &utils.RTTStats{},
+						&wire.TransportParameters{}, &wire.TransportParameters{},
+						true,
+					)
+					Expect(clientErr).ToNot(HaveOccurred())
+					Expect(serverErr).ToNot(HaveOccurred())
+					Eventually(receivedSessionTicket).Should(BeClosed())
+
+					csc.EXPECT().Get(gomock.Any()).Return(state, true)
+					csc.EXPECT().Put(gomock.Any(), gomock.Any()).AnyTimes()
+					_, client, clientErr, server, serverErr := handshakeWithTLSConf(
+						clientConf, serverConf,
+						&utils.RTTStats{}, &utils.RTTStats{},
+						&wire.TransportParameters{}, &wire.TransportParameters{},
+						true,
+					)
+					Expect(clientErr).ToNot(HaveOccurred())
+					Expect(serverErr).ToNot(HaveOccurred())
+					Expect(server.ConnectionState().DidResume).To(BeTrue())
+					Expect(client.ConnectionState().DidResume).To(BeTrue())
+					return server
+				}
+
+				It("passes the application data to the server when 0-RTT is offered", func() {
+					sessionTicketAppData = []byte("foobar")
+					var appData []byte
+					serverAllow0RTT = func(data []byte) bool {
+						appData = data
+						return true
+					}
+					server := handshakeAndResume()
+					Expect(appData).To(Equal([]byte("foobar")))
+					Expect(server.ConnectionState().Used0RTT).To(BeTrue())
+				})
+
+				It("rejects 0-RTT, when the server rejects the application data", func() {
+					sessionTicketAppData = []byte("foobar")
+					serverAllow0RTT = func(data []byte) bool {
+						Expect(data).To(Equal([]byte("foobar")))
+						return false
+					}
+					server := handshakeAndResume()
+					Expect(server.ConnectionState().Used0RTT).To(BeFalse())
+				})
+			})
 		})
 	})
 })
@@ -0,0 +1,151 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// RetryOffloadTokenKeyLen is the length of the key used by the RetryOffloadTokenProtector.
+const RetryOffloadTokenKeyLen = 16
+
+const retryOffloadTokenUniqueNumberLen = 12
+
+// A RetryOffloadToken is the content of a Retry token issued by a Retry offload service.
+type RetryOffloadToken struct {
+	OriginalDestConnectionID protocol.ConnectionID
+	RetrySrcConnectionID     protocol.ConnectionID
+	ClientIP                 net.IP
+	SentTime                 time.Time
+}
+
+// A RetryOffloadTokenProtector creates and decodes Retry tokens using a key that is shared between
+// a Retry service (for example a load balancer) and the servers behind it.
+// This allows offloading the sending of Retry packets, while the server is still able
+// to send the transport parameters that authenticate the Retry to the client.
+// The token format is modeled after the shared-state Retry token of draft-ietf-quic-retry-offload:
+// The first byte contains the token type (a 0 bit for Retry tokens) and the 7 bit key sequence number,
+// followed by a 96 bit unique token number, which is used as the nonce for the AES-128-GCM
+// encrypted token body.
+type RetryOffloadTokenProtector struct {
+	keySequence uint8
+	aead        cipher.AEAD
+}
+
+// NewRetryOffloadTokenProtector creates a new RetryOffloadTokenProtector.
+// The key needs to be RetryOffloadTokenKeyLen bytes long.
+// The key sequence number can be used to rotate keys, it must be smaller than 128.
+func NewRetryOffloadTokenProtector(key []byte, keySequence uint8) (*RetryOffloadTokenProtector, error) {
+	if len(key) != RetryOffloadTokenKeyLen {
+		return nil, errors.New("invalid key length")
+	}
+	if keySequence > 0x7f {
+		return nil, errors.New("invalid key sequence number")
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		return nil, err
+	}
+	return &RetryOffloadTokenProtector{keySequence: keySequence, aead: aead}, nil
+}
+
+// NewToken creates a new Retry token.
+func (p *RetryOffloadTokenProtector) NewToken(clientAddr net.Addr, origDestConnID, retrySrcConnID protocol.ConnectionID) ([]byte, error) {
+	body := &bytes.Buffer{}
+	body.WriteByte(uint8(origDestConnID.Len()))
+	body.Write(origDestConnID)
+	body.WriteByte(uint8(retrySrcConnID.Len()))
+	body.Write(retrySrcConnID)
+	ip := addrToIP(clientAddr).To16()
+	if ip == nil {
+		ip = net.IPv6unspecified
+	}
+	body.Write(ip)
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	body.Write(ts[:])
+
+	token := make([]byte, 1+retryOffloadTokenUniqueNumberLen, 1+retryOffloadTokenUniqueNumberLen+body.Len()+p.aead.Overhead())
+	token[0] = p.keySequence
+	if _, err := rand.Read(token[1:]); err != nil {
+		return nil, err
+	}
+	return p.aead.Seal(token, token[1:], body.Bytes(), token[:1]), nil
+}
+
+// IsRetryOffloadToken says if a token (potentially) was issued by a Retry offload service.
+// The first bit of these tokens is 0. Tokens issued by quic-go servers (both in Retry packets
+// and in NEW_TOKEN frames) always have the first bit set, so that a Retry offload service
+// can tell them apart from its own tokens.
+func IsRetryOffloadToken(token []byte) bool {
+	return len(token) > 0 && token[0]&0x80 == 0
+}
+
+// DecodeToken decodes a Retry token.
+// It is the caller's responsibility to check that the token was issued for the client's
+// IP address, and that it hasn't expired.
+func (p *RetryOffloadTokenProtector) DecodeToken(token []byte) (*RetryOffloadToken, error) {
+	if len(token) < 1+retryOffloadTokenUniqueNumberLen+p.aead.Overhead() {
+		return nil, errors.New("token too short")
+	}
+	if token[0]&0x80 != 0 {
+		return nil, errors.New("not a Retry token")
+	}
+	if token[0] != p.keySequence {
+		return nil, errors.New("unknown key sequence number")
+	}
+	body, err := p.aead.Open(nil, token[1:1+retryOffloadTokenUniqueNumberLen], token[1+retryOffloadTokenUniqueNumberLen:], token[:1])
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(body)
+	t := &RetryOffloadToken{}
+	l, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if t.OriginalDestConnectionID, err = protocol.ReadConnectionID(r, int(l)); err != nil {
+		return nil, err
+	}
+	if l, err = r.ReadByte(); err != nil {
+		return nil, err
+	}
+	if t.RetrySrcConnectionID, err = protocol.ReadConnectionID(r, int(l)); err != nil {
+		return nil, err
+	}
+	if r.Len() != net.IPv6len+8 {
+		return nil, errors.New("invalid token length")
+	}
+	ip := make(net.IP, net.IPv6len)
+	_, _ = r.Read(ip)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	t.ClientIP = ip
+	var ts [8]byte
+	_, _ = r.Read(ts[:])
+	t.SentTime = time.Unix(0, int64(binary.BigEndian.Uint64(ts[:]))*int64(time.Millisecond))
+	return t, nil
+}
+
+func addrToIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	default:
+		return net.IPv6unspecified
+	}
+}
@@ -0,0 +1,93 @@
+package handshake
+
+import (
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retry offload tokens", func() {
+	var p *RetryOffloadTokenProtector
+	key := []byte("0123456789abcdef")
+
+	BeforeEach(func() {
+		var err error
+		p, err = NewRetryOffloadTokenProtector(key, 5)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects invalid keys", func() {
+		_, err := NewRetryOffloadTokenProtector([]byte("foobar"), 0)
+		Expect(err).To(MatchError("invalid key length"))
+		_, err = NewRetryOffloadTokenProtector(key, 0x80)
+		Expect(err).To(MatchError("invalid key sequence number"))
+	})
+
+	It("creates and decodes tokens", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+		token, err := p.NewToken(addr, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.ConnectionID{9, 8, 7, 6})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token[0]).To(Equal(uint8(5)))
+		t, err := p.DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.OriginalDestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}))
+		Expect(t.RetrySrcConnectionID).To(Equal(protocol.ConnectionID{9, 8, 7, 6}))
+		Expect(t.ClientIP.Equal(addr.IP)).To(BeTrue())
+		Expect(t.SentTime).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
+	})
+
+	It("handles IPv6 addresses", func() {
+		addr := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1337}
+		token, err := p.NewToken(addr, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.ConnectionID{9, 8, 7, 6})
+		Expect(err).ToNot(HaveOccurred())
+		t, err := p.DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.ClientIP).To(Equal(addr.IP))
+	})
+
+	It("rejects tokens created with a different key", func() {
+		p2, err := NewRetryOffloadTokenProtector([]byte("fedcba9876543210"), 5)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := p2.NewToken(&net.UDPAddr{}, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.ConnectionID{9, 8, 7, 6})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = p.DecodeToken(token)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects tokens with a different key sequence number", func() {
+		p2, err := NewRetryOffloadTokenProtector(key, 6)
+		Expect(err).ToNot(HaveOccurred())
+		token, err := p2.NewToken(&net.UDPAddr{}, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.ConnectionID{9, 8, 7, 6})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = p.DecodeToken(token)
+		Expect(err).To(MatchError("unknown key sequence number"))
+	})
+
+	It("rejects tokens that are not Retry tokens", func() {
+		token := make([]byte, 100)
+		token[0] = 0x80
+		_, err := p.DecodeToken(token)
+		Expect(err).To(MatchError("not a Retry token"))
+	})
+
+	It("tells Retry offload tokens apart from the server's tokens", func() {
+		token, err := p.NewToken(&net.UDPAddr{}, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, protocol.ConnectionID{9, 8, 7, 6})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(IsRetryOffloadToken(token)).To(BeTrue())
+		tp, err := newTokenProtector(&zeroReader{})
+		Expect(err).ToNot(HaveOccurred())
+		token, err = tp.NewToken([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(IsRetryOffloadToken(token)).To(BeFalse())
+		Expect(IsRetryOffloadToken(nil)).To(BeFalse())
+	})
+
+	It("rejects tokens that are too short", func() {
+		_, err := p.DecodeToken([]byte{5, 1, 2, 3})
+		Expect(err).To(MatchError("token too short"))
+	})
+})
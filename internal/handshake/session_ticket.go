@@ -10,17 +10,20 @@ import (
 	"github.com/lucas-clemente/quic-go/quicvarint"
 )
 
-const sessionTicketRevision = 2
+const sessionTicketRevision = 3
 
 type sessionTicket struct {
 	Parameters *wire.TransportParameters
 	RTT        time.Duration // to be encoded in mus
+	AppData    []byte        // application data, set by the server application
 }
 
 func (t *sessionTicket) Marshal() []byte {
 	b := &bytes.Buffer{}
 	quicvarint.Write(b, sessionTicketRevision)
 	quicvarint.Write(b, uint64(t.RTT.Microseconds()))
+	quicvarint.Write(b, uint64(len(t.AppData)))
+	b.Write(t.AppData)
 	t.Parameters.MarshalForSessionTicket(b)
 	return b.Bytes()
 }
@@ -38,11 +41,21 @@ func (t *sessionTicket) Unmarshal(b []byte) error {
 	if err != nil {
 		return errors.New("failed to read RTT")
 	}
+	appDataLen, err := quicvarint.Read(r)
+	if err != nil || appDataLen > uint64(r.Len()) {
+		return errors.New("failed to read application data")
+	}
+	var appData []byte
+	if appDataLen > 0 {
+		appData = make([]byte, appDataLen)
+		r.Read(appData)
+	}
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(r); err != nil {
 		return fmt.Errorf("unmarshaling transport parameters from session ticket failed: %s", err.Error())
 	}
 	t.Parameters = &tp
 	t.RTT = time.Duration(rtt) * time.Microsecond
+	t.AppData = appData
 	return nil
 }
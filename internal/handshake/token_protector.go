@@ -42,11 +42,13 @@ func newTokenProtector(rand io.Reader) (tokenProtector, error) {
 }
 
 // NewToken encodes data into a new token.
+// The first bit of the token is always set, see IsRetryOffloadToken.
 func (s *tokenProtectorImpl) NewToken(data []byte) ([]byte, error) {
 	nonce := make([]byte, tokenNonceSize)
 	if _, err := s.rand.Read(nonce); err != nil {
 		return nil, err
 	}
+	nonce[0] |= 0x80
 	aead, aeadNonce, err := s.createAEAD(nonce)
 	if err != nil {
 		return nil, err
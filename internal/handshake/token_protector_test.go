@@ -60,6 +60,17 @@ var _ = Describe("Token Protector", func() {
 		Expect(err.Error()).To(ContainSubstring("message authentication failed"))
 	})
 
+	It("sets the first bit", func() {
+		tp, err := newTokenProtector(&zeroReader{})
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tp.NewToken([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token[0] & 0x80).ToNot(BeZero())
+		decoded, err := tp.DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal([]byte("foobar")))
+	})
+
 	It("errors when decoding too short tokens", func() {
 		_, err := tp.DecodeToken([]byte("foobar"))
 		Expect(err).To(MatchError("token too short: 6"))
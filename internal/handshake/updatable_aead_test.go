@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"crypto/tls"
 	"fmt"
+	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -11,6 +12,7 @@ import (
 	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/qtls"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
@@ -507,3 +509,73 @@ var _ = Describe("Updatable AEAD", func() {
 		})
 	}
 })
+
+func newBenchmarkAEADs(cs *qtls.CipherSuiteTLS13) (client, server *updatableAEAD) {
+	trafficSecret1 := make([]byte, 16)
+	trafficSecret2 := make([]byte, 16)
+	rand.Read(trafficSecret1)
+	rand.Read(trafficSecret2)
+	rttStats := utils.NewRTTStats()
+	client = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger)
+	server = newUpdatableAEAD(rttStats, nil, utils.DefaultLogger)
+	client.SetReadKey(cs, trafficSecret2)
+	client.SetWriteKey(cs, trafficSecret1)
+	server.SetReadKey(cs, trafficSecret1)
+	server.SetWriteKey(cs, trafficSecret2)
+	return
+}
+
+func BenchmarkAEADSeal(b *testing.B) {
+	for _, cs := range cipherSuites {
+		client, _ := newBenchmarkAEADs(cs)
+		b.Run(tls.CipherSuiteName(cs.ID), func(b *testing.B) {
+			msg := make([]byte, 1200)
+			ad := make([]byte, 20)
+			buf := make([]byte, 0, len(msg)+client.Overhead())
+			b.SetBytes(int64(len(msg)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				client.Seal(buf[:0], msg, protocol.PacketNumber(i), ad)
+			}
+		})
+	}
+}
+
+func BenchmarkAEADOpen(b *testing.B) {
+	for _, cs := range cipherSuites {
+		client, server := newBenchmarkAEADs(cs)
+		b.Run(tls.CipherSuiteName(cs.ID), func(b *testing.B) {
+			msg := make([]byte, 1200)
+			ad := make([]byte, 20)
+			sealed := client.Seal(nil, msg, 42, ad)
+			buf := make([]byte, 0, len(msg))
+			now := time.Now()
+			b.SetBytes(int64(len(msg)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := server.Open(buf[:0], sealed, now, 42, protocol.KeyPhaseZero, ad); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHeaderProtection(b *testing.B) {
+	for _, cs := range cipherSuites {
+		client, server := newBenchmarkAEADs(cs)
+		b.Run(tls.CipherSuiteName(cs.ID), func(b *testing.B) {
+			sample := make([]byte, 16)
+			rand.Read(sample)
+			header := []byte{0x45, 0xde, 0xad, 0xbe, 0xef}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				client.EncryptHeader(sample, &header[0], header[1:])
+				server.DecryptHeader(sample, &header[0], header[1:])
+			}
+		})
+	}
+}
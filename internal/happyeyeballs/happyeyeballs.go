@@ -0,0 +1,144 @@
+// Package happyeyeballs implements dual-stack connection establishment as described in RFC 8305.
+package happyeyeballs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// ConnectionAttemptDelay is the time to wait for a connection attempt to succeed
+// before starting the attempt for the next address.
+// This is the value recommended in section 8 of RFC 8305.
+var ConnectionAttemptDelay = 250 * time.Millisecond
+
+// Resolve resolves host and port to all its UDP addresses.
+// Contrary to net.ResolveUDPAddr, it uses the context (which means that the DNS hooks of an
+// httptrace.ClientTrace are called), and it returns both the A and the AAAA records,
+// in the order in which the connection attempts should be made.
+func Resolve(ctx context.Context, host, port string) ([]*net.UDPAddr, error) {
+	portNum, err := net.DefaultResolver.LookupPort(ctx, "udp", port)
+	if err != nil {
+		return nil, err
+	}
+	// Like for net.ResolveUDPAddr, an empty host means the local system.
+	if host == "" {
+		return []*net.UDPAddr{{Port: portNum}}, nil
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]*net.UDPAddr, 0, len(ips))
+	for _, ip := range sortAddrs(ips) {
+		addrs = append(addrs, &net.UDPAddr{IP: ip.IP, Port: portNum, Zone: ip.Zone})
+	}
+	return addrs, nil
+}
+
+// sortAddrs sorts the addresses as described in section 4 of RFC 8305:
+// It starts with an IPv6 address (if there is one), and then alternates between the address families.
+// Within an address family, the order returned by the resolver is preserved.
+func sortAddrs(ips []net.IPAddr) []net.IPAddr {
+	var v4, v6 []net.IPAddr
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	sorted := make([]net.IPAddr, 0, len(ips))
+	for len(v4) > 0 || len(v6) > 0 {
+		if len(v6) > 0 {
+			sorted = append(sorted, v6[0])
+			v6 = v6[1:]
+		}
+		if len(v4) > 0 {
+			sorted = append(sorted, v4[0])
+			v4 = v4[1:]
+		}
+	}
+	return sorted
+}
+
+// A DialFunc establishes a connection to a single address.
+// It must return when the context is canceled.
+type DialFunc func(ctx context.Context, addr *net.UDPAddr) (interface{}, error)
+
+// Dial races connection attempts to addrs, which are tried in order.
+// The attempts are staggered by the ConnectionAttemptDelay. If an attempt fails,
+// the next one is started right away.
+// As soon as one attempt succeeds, the context of all other attempts is canceled.
+// Attempts that succeed nevertheless are closed using closeConn.
+// If all attempts fail, the error of the first attempt is returned.
+func Dial(ctx context.Context, addrs []*net.UDPAddr, dial DialFunc, closeConn func(interface{})) (interface{}, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("happyeyeballs: no addresses to dial")
+	}
+	if len(addrs) == 1 {
+		return dial(ctx, addrs[0])
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn interface{}
+		err  error
+	}
+	results := make(chan result, len(addrs))
+	var next, pending int
+	startNext := func() {
+		addr := addrs[next]
+		next++
+		pending++
+		go func() {
+			conn, err := dial(ctx, addr)
+			results <- result{conn: conn, err: err}
+		}()
+	}
+
+	var firstErr error
+	startNext()
+	for {
+		var timer *time.Timer
+		var timerChan <-chan time.Time
+		if next < len(addrs) && ctx.Err() == nil {
+			timer = time.NewTimer(ConnectionAttemptDelay)
+			timerChan = timer.C
+		}
+
+		select {
+		case <-timerChan:
+			startNext()
+		case res := <-results:
+			if timer != nil {
+				timer.Stop()
+			}
+			pending--
+			if res.err == nil {
+				cancel()
+				// Close the connections of the attempts that succeed after this one.
+				go func(pending int) {
+					for i := 0; i < pending; i++ {
+						if r := <-results; r.err == nil {
+							closeConn(r.conn)
+						}
+					}
+				}(pending)
+				return res.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			// Don't wait for the ConnectionAttemptDelay if an attempt failed.
+			if next < len(addrs) && ctx.Err() == nil {
+				startNext()
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package happyeyeballs
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHappyEyeballs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Happy Eyeballs Suite")
+}
@@ -0,0 +1,258 @@
+package happyeyeballs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Happy Eyeballs", func() {
+	Context("sorting addresses", func() {
+		ipAddr := func(s string) net.IPAddr { return net.IPAddr{IP: net.ParseIP(s)} }
+
+		It("starts with IPv6 and alternates between the address families", func() {
+			sorted := sortAddrs([]net.IPAddr{
+				ipAddr("192.0.2.1"),
+				ipAddr("192.0.2.2"),
+				ipAddr("192.0.2.3"),
+				ipAddr("2001:db8::1"),
+				ipAddr("2001:db8::2"),
+			})
+			Expect(sorted).To(Equal([]net.IPAddr{
+				ipAddr("2001:db8::1"),
+				ipAddr("192.0.2.1"),
+				ipAddr("2001:db8::2"),
+				ipAddr("192.0.2.2"),
+				ipAddr("192.0.2.3"),
+			}))
+		})
+
+		It("handles a single address family", func() {
+			ips := []net.IPAddr{ipAddr("192.0.2.1"), ipAddr("192.0.2.2")}
+			Expect(sortAddrs(ips)).To(Equal(ips))
+			ips = []net.IPAddr{ipAddr("2001:db8::1"), ipAddr("2001:db8::2")}
+			Expect(sortAddrs(ips)).To(Equal(ips))
+		})
+	})
+
+	Context("resolving", func() {
+		It("resolves IP addresses", func() {
+			addrs, err := Resolve(context.Background(), "::1", "443")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addrs).To(HaveLen(1))
+			Expect(addrs[0].String()).To(Equal("[::1]:443"))
+		})
+
+		It("uses the local system for an empty host", func() {
+			addrs, err := Resolve(context.Background(), "", "1234")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addrs).To(Equal([]*net.UDPAddr{{Port: 1234}}))
+		})
+
+		It("errors on invalid ports", func() {
+			_, err := Resolve(context.Background(), "127.0.0.1", "foobar")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("dialing", func() {
+		var origDelay time.Duration
+		addrs := []*net.UDPAddr{
+			{IP: net.ParseIP("2001:db8::1"), Port: 443},
+			{IP: net.ParseIP("192.0.2.1"), Port: 443},
+			{IP: net.ParseIP("2001:db8::2"), Port: 443},
+		}
+
+		type attempt struct {
+			ctx    context.Context
+			addr   *net.UDPAddr
+			result chan error // the result of the dial
+		}
+
+		// newDialFunc returns a DialFunc that blocks until the result of the attempt is set.
+		// If the attempt succeeds, the address is returned as the connection.
+		newDialFunc := func() (DialFunc, <-chan *attempt) {
+			attempts := make(chan *attempt, len(addrs))
+			return func(ctx context.Context, addr *net.UDPAddr) (interface{}, error) {
+				a := &attempt{ctx: ctx, addr: addr, result: make(chan error, 1)}
+				attempts <- a
+				select {
+				case err := <-a.result:
+					if err != nil {
+						return nil, err
+					}
+					return addr, nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}, attempts
+		}
+
+		BeforeEach(func() {
+			origDelay = ConnectionAttemptDelay
+			ConnectionAttemptDelay = 50 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			ConnectionAttemptDelay = origDelay
+		})
+
+		It("dials a single address", func() {
+			dial, attempts := newDialFunc()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := Dial(context.Background(), addrs[:1], dial, func(interface{}) { Fail("didn't expect any call to close") })
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conn).To(Equal(addrs[0]))
+			}()
+			var a *attempt
+			Eventually(attempts).Should(Receive(&a))
+			a.result <- nil
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when there are no addresses", func() {
+			dial, _ := newDialFunc()
+			_, err := Dial(context.Background(), nil, dial, func(interface{}) {})
+			Expect(err).To(MatchError("happyeyeballs: no addresses to dial"))
+		})
+
+		It("doesn't start other attempts if the first one succeeds quickly", func() {
+			ConnectionAttemptDelay = time.Hour
+			dial, attempts := newDialFunc()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := Dial(context.Background(), addrs, dial, func(interface{}) { Fail("didn't expect any call to close") })
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conn).To(Equal(addrs[0]))
+			}()
+			var a *attempt
+			Eventually(attempts).Should(Receive(&a))
+			Expect(a.addr).To(Equal(addrs[0]))
+			a.result <- nil
+			Eventually(done).Should(BeClosed())
+			Consistently(attempts).ShouldNot(Receive())
+		})
+
+		It("staggers the attempts, and cancels the losing ones", func() {
+			dial, attempts := newDialFunc()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := Dial(context.Background(), addrs, dial, func(interface{}) { Fail("didn't expect any call to close") })
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conn).To(Equal(addrs[1]))
+			}()
+			start := time.Now()
+			var first, second, third *attempt
+			Eventually(attempts).Should(Receive(&first))
+			Expect(first.addr).To(Equal(addrs[0]))
+			Eventually(attempts).Should(Receive(&second))
+			Expect(second.addr).To(Equal(addrs[1]))
+			Expect(time.Since(start)).To(BeNumerically(">=", ConnectionAttemptDelay))
+			Eventually(attempts).Should(Receive(&third))
+			Expect(third.addr).To(Equal(addrs[2]))
+			Expect(time.Since(start)).To(BeNumerically(">=", 2*ConnectionAttemptDelay))
+			second.result <- nil
+			Eventually(done).Should(BeClosed())
+			Expect(first.ctx.Done()).To(BeClosed())
+			Expect(third.ctx.Done()).To(BeClosed())
+		})
+
+		It("starts the next attempt right away when an attempt fails", func() {
+			ConnectionAttemptDelay = time.Hour
+			dial, attempts := newDialFunc()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := Dial(context.Background(), addrs, dial, func(interface{}) {})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conn).To(Equal(addrs[1]))
+			}()
+			var a *attempt
+			Eventually(attempts).Should(Receive(&a))
+			a.result <- errors.New("network unreachable")
+			Eventually(attempts).Should(Receive(&a))
+			Expect(a.addr).To(Equal(addrs[1]))
+			a.result <- nil
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the error of the first attempt if all attempts fail", func() {
+			ConnectionAttemptDelay = time.Hour
+			dial, attempts := newDialFunc()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := Dial(context.Background(), addrs, dial, func(interface{}) {})
+				Expect(err).To(MatchError("error 0"))
+			}()
+			for i := range addrs {
+				var a *attempt
+				Eventually(attempts).Should(Receive(&a))
+				Expect(a.addr).To(Equal(addrs[i]))
+				a.result <- fmt.Errorf("error %d", i)
+			}
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes connections that are established after the winning connection", func() {
+			closed := make(chan interface{}, 1)
+			// this DialFunc ignores context cancelations
+			attempts := make(chan *attempt, len(addrs))
+			dial := func(ctx context.Context, addr *net.UDPAddr) (interface{}, error) {
+				a := &attempt{ctx: ctx, addr: addr, result: make(chan error, 1)}
+				attempts <- a
+				if err := <-a.result; err != nil {
+					return nil, err
+				}
+				return addr, nil
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				conn, err := Dial(context.Background(), addrs[:2], dial, func(conn interface{}) { closed <- conn })
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conn).To(Equal(addrs[1]))
+			}()
+			var first, second *attempt
+			Eventually(attempts).Should(Receive(&first))
+			Eventually(attempts).Should(Receive(&second))
+			second.result <- nil
+			Eventually(done).Should(BeClosed())
+			Consistently(closed).ShouldNot(Receive())
+			first.result <- nil
+			Eventually(closed).Should(Receive(Equal(addrs[0])))
+		})
+
+		It("stops dialing when the context is canceled", func() {
+			ConnectionAttemptDelay = time.Hour
+			dial, attempts := newDialFunc()
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := Dial(ctx, addrs, dial, func(interface{}) {})
+				Expect(err).To(MatchError(context.Canceled))
+			}()
+			Eventually(attempts).Should(Receive())
+			cancel()
+			Eventually(done).Should(BeClosed())
+			Expect(attempts).ToNot(Receive())
+		})
+	})
+})
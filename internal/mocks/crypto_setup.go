@@ -197,18 +197,18 @@ func (mr *MockCryptoSetupMockRecorder) GetInitialSealer() *gomock.Call {
 }
 
 // GetSessionTicket mocks base method.
-func (m *MockCryptoSetup) GetSessionTicket() ([]byte, error) {
+func (m *MockCryptoSetup) GetSessionTicket(arg0 []byte) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSessionTicket")
+	ret := m.ctrl.Call(m, "GetSessionTicket", arg0)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSessionTicket indicates an expected call of GetSessionTicket.
-func (mr *MockCryptoSetupMockRecorder) GetSessionTicket() *gomock.Call {
+func (mr *MockCryptoSetupMockRecorder) GetSessionTicket(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionTicket", reflect.TypeOf((*MockCryptoSetup)(nil).GetSessionTicket))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionTicket", reflect.TypeOf((*MockCryptoSetup)(nil).GetSessionTicket), arg0)
 }
 
 // HandleMessage mocks base method.
@@ -0,0 +1,19 @@
+//go:build !go1.20
+// +build !go1.20
+
+package utils
+
+import "context"
+
+// WithCancelCause is context.WithCancelCause.
+// Before Go 1.20, the cause is dropped.
+func WithCancelCause(parent context.Context) (context.Context, func(cause error)) {
+	ctx, cancel := context.WithCancel(parent)
+	return ctx, func(error) { cancel() }
+}
+
+// ContextCause is context.Cause.
+// Before Go 1.20, it returns ctx.Err().
+func ContextCause(ctx context.Context) error {
+	return ctx.Err()
+}
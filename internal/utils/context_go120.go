@@ -0,0 +1,19 @@
+//go:build go1.20
+// +build go1.20
+
+package utils
+
+import "context"
+
+// WithCancelCause is context.WithCancelCause.
+// The cause passed to the cancel function is returned by ContextCause.
+func WithCancelCause(parent context.Context) (context.Context, func(cause error)) {
+	ctx, cancel := context.WithCancelCause(parent)
+	return ctx, cancel
+}
+
+// ContextCause is context.Cause.
+// It returns the cause that ctx was canceled with, or ctx.Err() if it was canceled without a cause.
+func ContextCause(ctx context.Context) error {
+	return context.Cause(ctx)
+}
@@ -19,10 +19,11 @@ const (
 type RTTStats struct {
 	hasMeasurement bool
 
-	minRTT        time.Duration
-	latestRTT     time.Duration
-	smoothedRTT   time.Duration
-	meanDeviation time.Duration
+	minRTT          time.Duration
+	latestRTT       time.Duration
+	latestSendDelta time.Duration
+	smoothedRTT     time.Duration
+	meanDeviation   time.Duration
 
 	maxAckDelay time.Duration
 }
@@ -40,6 +41,10 @@ func (r *RTTStats) MinRTT() time.Duration { return r.minRTT }
 // May return Zero if no valid updates have occurred.
 func (r *RTTStats) LatestRTT() time.Duration { return r.latestRTT }
 
+// LatestSendDelta returns the most recent rtt measurement, without correcting for the ack delay.
+// May return Zero if no valid updates have occurred.
+func (r *RTTStats) LatestSendDelta() time.Duration { return r.latestSendDelta }
+
 // SmoothedRTT returns the smoothed RTT for the connection.
 // May return Zero if no valid updates have occurred.
 func (r *RTTStats) SmoothedRTT() time.Duration { return r.smoothedRTT }
@@ -67,6 +72,7 @@ func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 	if sendDelta == InfDuration || sendDelta <= 0 {
 		return
 	}
+	r.latestSendDelta = sendDelta
 
 	// Update r.minRTT first. r.minRTT does not use an rttSample corrected for
 	// ackDelay but the raw observed sendDelta, since poor clock granularity at
@@ -113,6 +119,7 @@ func (r *RTTStats) SetInitialRTT(t time.Duration) {
 // OnConnectionMigration is called when connection migrates and rtt measurement needs to be reset.
 func (r *RTTStats) OnConnectionMigration() {
 	r.latestRTT = 0
+	r.latestSendDelta = 0
 	r.minRTT = 0
 	r.smoothedRTT = 0
 	r.meanDeviation = 0
@@ -29,6 +29,7 @@ var _ = Describe("RTT stats", func() {
 		// Verify that Smoothed RTT includes max ack delay if it's reasonable.
 		rttStats.UpdateRTT((350 * time.Millisecond), (50 * time.Millisecond), time.Time{})
 		Expect(rttStats.LatestRTT()).To(Equal((300 * time.Millisecond)))
+		Expect(rttStats.LatestSendDelta()).To(Equal((350 * time.Millisecond)))
 		Expect(rttStats.SmoothedRTT()).To(Equal((300 * time.Millisecond)))
 		// Verify that large erroneous ack_delay does not change Smoothed RTT.
 		rttStats.UpdateRTT((200 * time.Millisecond), (300 * time.Millisecond), time.Time{})
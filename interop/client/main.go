@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -15,10 +17,10 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http09"
 	"github.com/lucas-clemente/quic-go/http3"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
-	"github.com/lucas-clemente/quic-go/interop/http09"
 	"github.com/lucas-clemente/quic-go/interop/utils"
 	"github.com/lucas-clemente/quic-go/qlog"
 )
@@ -86,7 +88,13 @@ func runTestcase(testcase string) error {
 	defer r.Close()
 
 	switch testcase {
-	case "handshake", "transfer", "retry":
+	case "handshake", "transfer", "retry", "ipv6", "amplificationlimit":
+	case "transferloss", "transfercorruption", "blackhole":
+		// Loss recovery takes care of these.
+	case "ecn":
+		r.Dial = dialECN
+	case "handshakeloss", "handshakecorruption":
+		return runMultiConnectTest(r, urls)
 	case "keyupdate":
 		handshake.KeyUpdateInterval = 100
 	case "chacha20":
@@ -106,6 +114,37 @@ func runTestcase(testcase string) error {
 	return downloadFiles(r, urls, false)
 }
 
+// dialECN dials a connection on a socket that marks all packets as ECT(0).
+func dialECN(ctx context.Context, addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.EarlyConnection, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.SetECT0(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	qconn, err := quic.DialEarlyContext(ctx, conn, udpAddr, host, tlsConf, quicConf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// quic-go doesn't close packet conns passed in by the application.
+	go func() {
+		<-qconn.Context().Done()
+		conn.Close()
+	}()
+	return qconn, nil
+}
+
 func runVersionNegotiationTest(r *http09.RoundTripper, urls []string) error {
 	if len(urls) != 1 {
 		return errors.New("expected at least 2 URLs")
@@ -9,8 +9,8 @@ import (
 	"os"
 
 	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http09"
 	"github.com/lucas-clemente/quic-go/http3"
-	"github.com/lucas-clemente/quic-go/interop/http09"
 	"github.com/lucas-clemente/quic-go/interop/utils"
 	"github.com/lucas-clemente/quic-go/qlog"
 )
@@ -58,15 +58,20 @@ func main() {
 	}
 
 	switch testcase {
-	case "versionnegotiation", "handshake", "transfer", "resumption", "zerortt", "multiconnect":
-		err = runHTTP09Server(quicConf)
+	case "versionnegotiation", "handshake", "transfer", "resumption", "zerortt", "multiconnect",
+		"ipv6", "transferloss", "transfercorruption", "handshakeloss", "handshakecorruption", "blackhole",
+		"amplificationlimit":
+		// quic-go enforces the anti-amplification limit before the client's address is validated.
+		err = runHTTP09Server(quicConf, false)
+	case "ecn":
+		err = runHTTP09Server(quicConf, true)
 	case "chacha20":
 		tlsConf.CipherSuites = []uint16{tls.TLS_CHACHA20_POLY1305_SHA256}
-		err = runHTTP09Server(quicConf)
+		err = runHTTP09Server(quicConf, false)
 	case "retry":
 		// By default, quic-go performs a Retry on every incoming connection.
 		quicConf.AcceptToken = nil
-		err = runHTTP09Server(quicConf)
+		err = runHTTP09Server(quicConf, false)
 	case "http3":
 		err = runHTTP3Server(quicConf)
 	default:
@@ -80,16 +85,24 @@ func main() {
 	}
 }
 
-func runHTTP09Server(quicConf *quic.Config) error {
+func runHTTP09Server(quicConf *quic.Config, useECN bool) error {
 	server := http09.Server{
-		Server: &http.Server{
-			Addr:      ":443",
-			TLSConfig: tlsConf,
-		},
+		Addr:       ":443",
+		TLSConfig:  tlsConf,
 		QuicConfig: quicConf,
 	}
 	http.DefaultServeMux.Handle("/", http.FileServer(http.Dir("/www")))
-	return server.ListenAndServe()
+	if !useECN {
+		return server.ListenAndServe()
+	}
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: 443})
+	if err != nil {
+		return err
+	}
+	if err := utils.SetECT0(conn); err != nil {
+		return err
+	}
+	return server.Serve(conn)
 }
 
 func runHTTP3Server(quicConf *quic.Config) error {
@@ -0,0 +1,36 @@
+//go:build linux
+// +build linux
+
+package utils
+
+import (
+	"errors"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+const ecnECT0 = 0x2
+
+// SetECT0 marks all packets sent on the connection as ECN-capable (ECT(0)).
+// quic-go reads the ECN bits of received packets and reports them in its ACK frames,
+// but doesn't set them on packets it sends.
+func SetECT0(conn *net.UDPConn) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	// We don't know if this is an IPv4-only, IPv6-only or a dual-stack socket.
+	// Try setting the ECN bits for both IP versions, and expect at least one of them to succeed.
+	var errIPv4, errIPv6 error
+	if err := rawConn.Control(func(fd uintptr) {
+		errIPv4 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, ecnECT0)
+		errIPv6 = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_TCLASS, ecnECT0)
+	}); err != nil {
+		return err
+	}
+	if errIPv4 != nil && errIPv6 != nil {
+		return errors.New("setting ECT(0) failed for both IPv4 and IPv6")
+	}
+	return nil
+}
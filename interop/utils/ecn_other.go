@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package utils
+
+import (
+	"errors"
+	"net"
+)
+
+// SetECT0 marks all packets sent on the connection as ECN-capable (ECT(0)).
+// It is only supported on Linux.
+func SetECT0(*net.UDPConn) error {
+	return errors.New("setting ECN bits is not supported on this platform")
+}
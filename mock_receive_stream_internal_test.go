@@ -5,6 +5,7 @@
 package quic
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -63,6 +64,20 @@ func (mr *MockReceiveStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReceiveStreamI)(nil).Read), p)
 }
 
+// ReadContext mocks base method.
+func (m *MockReceiveStreamI) ReadContext() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadContext")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// ReadContext indicates an expected call of ReadContext.
+func (mr *MockReceiveStreamIMockRecorder) ReadContext() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadContext", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadContext))
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockReceiveStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
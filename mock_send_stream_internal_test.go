@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -78,6 +79,20 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// Flush mocks base method.
+func (m *MockSendStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockSendStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockSendStreamI)(nil).Flush))
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockSendStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -133,6 +148,18 @@ func (mr *MockSendStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockSendStreamI)(nil).closeForShutdown), arg0)
 }
 
+// drop0RTTReplayData mocks base method.
+func (m *MockSendStreamI) drop0RTTReplayData() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "drop0RTTReplayData")
+}
+
+// drop0RTTReplayData indicates an expected call of drop0RTTReplayData.
+func (mr *MockSendStreamIMockRecorder) drop0RTTReplayData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "drop0RTTReplayData", reflect.TypeOf((*MockSendStreamI)(nil).drop0RTTReplayData))
+}
+
 // handleStopSendingFrame mocks base method.
 func (m *MockSendStreamI) handleStopSendingFrame(arg0 *wire.StopSendingFrame) {
 	m.ctrl.T.Helper()
@@ -174,6 +201,18 @@ func (mr *MockSendStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockSendStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// reopenFor0RTTReplay mocks base method.
+func (m *MockSendStreamI) reopenFor0RTTReplay(arg0 protocol.StreamID, arg1 flowcontrol.StreamFlowController) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "reopenFor0RTTReplay", arg0, arg1)
+}
+
+// reopenFor0RTTReplay indicates an expected call of reopenFor0RTTReplay.
+func (mr *MockSendStreamIMockRecorder) reopenFor0RTTReplay(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "reopenFor0RTTReplay", reflect.TypeOf((*MockSendStreamI)(nil).reopenFor0RTTReplay), arg0, arg1)
+}
+
 // updateSendWindow mocks base method.
 func (m *MockSendStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
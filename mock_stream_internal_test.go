@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -90,6 +91,20 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// Flush mocks base method.
+func (m *MockStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockStreamI)(nil).Flush))
+}
+
 // Read mocks base method.
 func (m *MockStreamI) Read(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -105,6 +120,20 @@ func (mr *MockStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), p)
 }
 
+// ReadContext mocks base method.
+func (m *MockStreamI) ReadContext() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadContext")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// ReadContext indicates an expected call of ReadContext.
+func (mr *MockStreamIMockRecorder) ReadContext() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadContext", reflect.TypeOf((*MockStreamI)(nil).ReadContext))
+}
+
 // SetDeadline mocks base method.
 func (m *MockStreamI) SetDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -188,6 +217,18 @@ func (mr *MockStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockStreamI)(nil).closeForShutdown), arg0)
 }
 
+// drop0RTTReplayData mocks base method.
+func (m *MockStreamI) drop0RTTReplayData() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "drop0RTTReplayData")
+}
+
+// drop0RTTReplayData indicates an expected call of drop0RTTReplayData.
+func (mr *MockStreamIMockRecorder) drop0RTTReplayData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "drop0RTTReplayData", reflect.TypeOf((*MockStreamI)(nil).drop0RTTReplayData))
+}
+
 // getWindowUpdate mocks base method.
 func (m *MockStreamI) getWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -271,6 +312,18 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// reopenFor0RTTReplay mocks base method.
+func (m *MockStreamI) reopenFor0RTTReplay(arg0 protocol.StreamID, arg1 flowcontrol.StreamFlowController) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "reopenFor0RTTReplay", arg0, arg1)
+}
+
+// reopenFor0RTTReplay indicates an expected call of reopenFor0RTTReplay.
+func (mr *MockStreamIMockRecorder) reopenFor0RTTReplay(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "reopenFor0RTTReplay", reflect.TypeOf((*MockStreamI)(nil).reopenFor0RTTReplay), arg0, arg1)
+}
+
 // updateSendWindow mocks base method.
 func (m *MockStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
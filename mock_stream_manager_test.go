@@ -92,6 +92,18 @@ func (mr *MockStreamManagerMockRecorder) DeleteStream(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteStream", reflect.TypeOf((*MockStreamManager)(nil).DeleteStream), arg0)
 }
 
+// Drop0RTTReplayData mocks base method.
+func (m *MockStreamManager) Drop0RTTReplayData() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Drop0RTTReplayData")
+}
+
+// Drop0RTTReplayData indicates an expected call of Drop0RTTReplayData.
+func (mr *MockStreamManagerMockRecorder) Drop0RTTReplayData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drop0RTTReplayData", reflect.TypeOf((*MockStreamManager)(nil).Drop0RTTReplayData))
+}
+
 // GetOrOpenReceiveStream mocks base method.
 func (m *MockStreamManager) GetOrOpenReceiveStream(arg0 protocol.StreamID) (receiveStreamI, error) {
 	m.ctrl.T.Helper()
@@ -15,23 +15,45 @@ type testConn struct {
 	net.PacketConn
 }
 
+// areConnsMultiplexed says if any packet conns are still used by the multiplexer.
+// The multiplexer is reset in between tests, so tests need to wait until all of them were removed.
+func areConnsMultiplexed() bool {
+	m := getMultiplexer().(*connMultiplexer)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.conns) > 0
+}
+
 var _ = Describe("Multiplexer", func() {
+	// The packet handler map holds a packet buffer while it is blocked reading from the conn.
+	// Tests wait for the read to start, so that the buffer isn't taken while another test checks for buffer leaks.
+	expectBlockingRead := func(conn *MockPacketConn) <-chan struct{} {
+		readStarted := make(chan struct{})
+		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) {
+			close(readStarted)
+			<-(make(chan struct{}))
+		}).MaxTimes(1)
+		return readStarted
+	}
+
 	It("adds a new packet conn ", func() {
 		conn := NewMockPacketConn(mockCtrl)
-		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
+		readStarted := expectBlockingRead(conn)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234})
 		_, err := getMultiplexer().AddConn(conn, 8, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
+		Eventually(readStarted).Should(BeClosed())
 	})
 
 	It("recognizes when the same connection is added twice", func() {
 		pconn := NewMockPacketConn(mockCtrl)
 		pconn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4321}).Times(2)
-		pconn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
+		readStarted := expectBlockingRead(pconn)
 		conn := testConn{PacketConn: pconn}
 		tracer := mocklogging.NewMockTracer(mockCtrl)
 		_, err := getMultiplexer().AddConn(conn, 8, []byte("foobar"), tracer)
 		Expect(err).ToNot(HaveOccurred())
+		Eventually(readStarted).Should(BeClosed())
 		conn.counter++
 		_, err = getMultiplexer().AddConn(conn, 8, []byte("foobar"), tracer)
 		Expect(err).ToNot(HaveOccurred())
@@ -40,30 +62,33 @@ var _ = Describe("Multiplexer", func() {
 
 	It("errors when adding an existing conn with a different connection ID length", func() {
 		conn := NewMockPacketConn(mockCtrl)
-		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
+		readStarted := expectBlockingRead(conn)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}).Times(2)
 		_, err := getMultiplexer().AddConn(conn, 5, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
+		Eventually(readStarted).Should(BeClosed())
 		_, err = getMultiplexer().AddConn(conn, 6, nil, nil)
 		Expect(err).To(MatchError("cannot use 6 byte connection IDs on a connection that is already using 5 byte connction IDs"))
 	})
 
 	It("errors when adding an existing conn with a different stateless rest key", func() {
 		conn := NewMockPacketConn(mockCtrl)
-		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
+		readStarted := expectBlockingRead(conn)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}).Times(2)
 		_, err := getMultiplexer().AddConn(conn, 7, []byte("foobar"), nil)
 		Expect(err).ToNot(HaveOccurred())
+		Eventually(readStarted).Should(BeClosed())
 		_, err = getMultiplexer().AddConn(conn, 7, []byte("raboof"), nil)
 		Expect(err).To(MatchError("cannot use different stateless reset keys on the same packet conn"))
 	})
 
 	It("errors when adding an existing conn with different tracers", func() {
 		conn := NewMockPacketConn(mockCtrl)
-		conn.EXPECT().ReadFrom(gomock.Any()).Do(func([]byte) { <-(make(chan struct{})) }).MaxTimes(1)
+		readStarted := expectBlockingRead(conn)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}).Times(2)
 		_, err := getMultiplexer().AddConn(conn, 7, nil, mocklogging.NewMockTracer(mockCtrl))
 		Expect(err).ToNot(HaveOccurred())
+		Eventually(readStarted).Should(BeClosed())
 		_, err = getMultiplexer().AddConn(conn, 7, nil, mocklogging.NewMockTracer(mockCtrl))
 		Expect(err).To(MatchError("cannot use different tracers on the same packet conn"))
 	})
@@ -368,7 +368,7 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 		if h.tracer != nil {
 			h.tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropHeaderParseError)
 		}
-		p.buffer.MaybeRelease()
+		p.buffer.Release()
 		return
 	}
 
@@ -300,6 +300,7 @@ func (p *packetPacker) packConnectionClose(
 		}
 		c, err := p.appendPacket(buffer, hdrs[i], payloads[i], paddingLen, encLevel, sealers[i], false)
 		if err != nil {
+			buffer.Release()
 			return nil, err
 		}
 		contents = append(contents, c)
@@ -431,7 +432,7 @@ func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
 		return nil, nil
 	}
 
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(maxPacketSize)
 	packet := &coalescedPacket{
 		buffer:  buffer,
 		packets: make([]*packetContents, 0, numPackets),
@@ -440,6 +441,7 @@ func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
 		padding := p.initialPaddingLen(initialPayload.frames, size)
 		cont, err := p.appendPacket(buffer, initialHdr, initialPayload, padding, protocol.EncryptionInitial, initialSealer, false)
 		if err != nil {
+			buffer.Release()
 			return nil, err
 		}
 		packet.packets = append(packet.packets, cont)
@@ -447,6 +449,7 @@ func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
 	if handshakePayload != nil {
 		cont, err := p.appendPacket(buffer, handshakeHdr, handshakePayload, 0, protocol.EncryptionHandshake, handshakeSealer, false)
 		if err != nil {
+			buffer.Release()
 			return nil, err
 		}
 		packet.packets = append(packet.packets, cont)
@@ -454,6 +457,7 @@ func (p *packetPacker) PackCoalescedPacket() (*coalescedPacket, error) {
 	if appDataPayload != nil {
 		cont, err := p.appendPacket(buffer, appDataHdr, appDataPayload, 0, appDataEncLevel, appDataSealer, false)
 		if err != nil {
+			buffer.Release()
 			return nil, err
 		}
 		packet.packets = append(packet.packets, cont)
@@ -468,13 +472,14 @@ func (p *packetPacker) PackPacket() (*packedPacket, error) {
 	if payload == nil {
 		return nil, nil
 	}
-	buffer := getPacketBuffer()
+	buffer := getPacketBufferWithSize(p.maxPacketSize)
 	encLevel := protocol.Encryption1RTT
 	if hdr.IsLongHeader {
 		encLevel = protocol.Encryption0RTT
 	}
 	cont, err := p.appendPacket(buffer, hdr, payload, 0, encLevel, sealer, false)
 	if err != nil {
+		buffer.Release()
 		return nil, err
 	}
 	return &packedPacket{
@@ -563,12 +568,20 @@ func (p *packetPacker) maybeGetAppDataPacket(maxPacketSize, currentSize protocol
 	}
 
 	maxPayloadSize := maxPacketSize - hdr.GetLength(p.version) - protocol.ByteCount(sealer.Overhead())
-	payload := p.maybeGetAppDataPacketWithEncLevel(maxPayloadSize, encLevel == protocol.Encryption1RTT && currentSize == 0)
+	payload := p.maybeGetAppDataPacketWithEncLevel(maxPayloadSize, p.maxDatagramFrameSize(sealer), encLevel == protocol.Encryption1RTT && currentSize == 0)
 	return sealer, hdr, payload
 }
 
-func (p *packetPacker) maybeGetAppDataPacketWithEncLevel(maxPayloadSize protocol.ByteCount, ackAllowed bool) *payload {
-	payload := p.composeNextPacket(maxPayloadSize, ackAllowed)
+// maxDatagramFrameSize returns the size of the largest DATAGRAM frame that fits into a full-size 1-RTT packet.
+// It assumes the longest packet number encoding, so that a DATAGRAM frame that is smaller than this
+// is guaranteed to fit into one of the following packets.
+func (p *packetPacker) maxDatagramFrameSize(sealer sealer) protocol.ByteCount {
+	hdrLen := 1 /* type byte */ + protocol.ByteCount(p.getDestConnID().Len()) + protocol.ByteCount(protocol.PacketNumberLen4)
+	return p.maxPacketSize - hdrLen - protocol.ByteCount(sealer.Overhead())
+}
+
+func (p *packetPacker) maybeGetAppDataPacketWithEncLevel(maxPayloadSize, maxDatagramFrameSize protocol.ByteCount, ackAllowed bool) *payload {
+	payload := p.composeNextPacket(maxPayloadSize, maxDatagramFrameSize, ackAllowed)
 
 	// check if we have anything to send
 	if len(payload.frames) == 0 {
@@ -591,27 +604,14 @@ func (p *packetPacker) maybeGetAppDataPacketWithEncLevel(maxPayloadSize protocol
 	return payload
 }
 
-func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAllowed bool) *payload {
+func (p *packetPacker) composeNextPacket(maxFrameSize, maxDatagramFrameSize protocol.ByteCount, ackAllowed bool) *payload {
 	payload := &payload{frames: make([]ackhandler.Frame, 0, 1)}
 
-	var hasDatagram bool
-	if p.datagramQueue != nil {
-		if datagram := p.datagramQueue.Get(); datagram != nil {
-			payload.frames = append(payload.frames, ackhandler.Frame{
-				Frame: datagram,
-				// set it to a no-op. Then we won't set the default callback, which would retransmit the frame.
-				OnLost: func(wire.Frame) {},
-			})
-			payload.length += datagram.Length(p.version)
-			hasDatagram = true
-		}
-	}
-
 	var ack *wire.AckFrame
 	hasData := p.framer.HasData()
 	hasRetransmission := p.retransmissionQueue.HasAppData()
-	// TODO: make sure ACKs are sent when a lot of DATAGRAMs are queued
-	if !hasDatagram && ackAllowed {
+	// Add the ACK first, so that ACKs are sent even if a lot of DATAGRAMs are queued.
+	if ackAllowed {
 		ack = p.acks.GetAckFrame(protocol.Encryption1RTT, !hasRetransmission && !hasData)
 		if ack != nil {
 			payload.ack = ack
@@ -619,6 +619,36 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAll
 		}
 	}
 
+	if p.datagramQueue != nil {
+		// Pack as many DATAGRAM frames as fit into this packet.
+		// Every message is sent in its own DATAGRAM frame, so message boundaries are preserved.
+		for {
+			datagram := p.datagramQueue.Peek()
+			if datagram == nil {
+				break
+			}
+			length := datagram.Length(p.version)
+			if length > maxDatagramFrameSize {
+				// The DATAGRAM frame doesn't even fit into a full-size 1-RTT packet.
+				// There's no point in retrying this in the next packet.
+				// Drop it, so it doesn't block the following DATAGRAM frames.
+				p.datagramQueue.Pop()
+				continue
+			}
+			if length > maxFrameSize-payload.length {
+				// Send the DATAGRAM frame in one of the next packets.
+				break
+			}
+			p.datagramQueue.Pop()
+			payload.frames = append(payload.frames, ackhandler.Frame{
+				Frame: datagram,
+				// set it to a no-op. Then we won't set the default callback, which would retransmit the frame.
+				OnLost: func(wire.Frame) {},
+			})
+			payload.length += length
+		}
+	}
+
 	if ack == nil && !hasData && !hasRetransmission {
 		return payload
 	}
@@ -676,7 +706,7 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 		}
 		sealer = oneRTTSealer
 		hdr = p.getShortHeader(oneRTTSealer.KeyPhase())
-		payload = p.maybeGetAppDataPacketWithEncLevel(p.maxPacketSize-protocol.ByteCount(sealer.Overhead())-hdr.GetLength(p.version), true)
+		payload = p.maybeGetAppDataPacketWithEncLevel(p.maxPacketSize-protocol.ByteCount(sealer.Overhead())-hdr.GetLength(p.version), p.maxDatagramFrameSize(sealer), true)
 	default:
 		panic("unknown encryption level")
 	}
@@ -691,6 +721,7 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 	buffer := getPacketBuffer()
 	cont, err := p.appendPacket(buffer, hdr, payload, padding, encLevel, sealer, false)
 	if err != nil {
+		buffer.Release()
 		return nil, err
 	}
 	return &packedPacket{
@@ -704,15 +735,16 @@ func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.B
 		frames: []ackhandler.Frame{ping},
 		length: ping.Length(p.version),
 	}
-	buffer := getPacketBuffer()
 	sealer, err := p.cryptoSetup.Get1RTTSealer()
 	if err != nil {
 		return nil, err
 	}
+	buffer := getPacketBufferWithSize(size)
 	hdr := p.getShortHeader(sealer.KeyPhase())
 	padding := size - p.packetLength(hdr, payload) - protocol.ByteCount(sealer.Overhead())
 	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, true)
 	if err != nil {
+		buffer.Release()
 		return nil, err
 	}
 	contents.isMTUProbePacket = true
@@ -805,6 +837,7 @@ func (p *packetPacker) writeSinglePacket(
 	}
 	contents, err := p.appendPacket(buffer, hdr, payload, paddingLen, encLevel, sealer, false)
 	if err != nil {
+		buffer.Release()
 		return nil, err
 	}
 	return &packedPacket{
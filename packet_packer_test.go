@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"testing"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
@@ -24,6 +25,8 @@ import (
 )
 
 var _ = Describe("Packet packer", func() {
+	expectNoBufferLeaks()
+
 	const maxPacketSize protocol.ByteCount = 1357
 	const version = protocol.VersionTLS
 
@@ -202,6 +205,7 @@ var _ = Describe("Packet packer", func() {
 			hdrRawEncrypted[len(hdrRaw)-1] ^= 0xff
 			Expect(p.buffer.Data[0:len(hdrRaw)]).To(Equal(hdrRawEncrypted))
 			Expect(p.buffer.Data[p.buffer.Len()-4:]).To(Equal([]byte{0xde, 0xca, 0xfb, 0xad}))
+			p.buffer.Release()
 		})
 	})
 
@@ -242,6 +246,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.ack).To(Equal(ack))
 				Expect(p.buffer.Len()).To(BeEquivalentTo(packer.maxPacketSize))
 				parsePacket(p.buffer.Data)
+				p.buffer.Release()
 			})
 
 			It("packs Initial ACK-only packets, and doesn't pads them (for the server)", func() {
@@ -256,6 +261,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
 				Expect(p.ack).To(Equal(ack))
 				parsePacket(p.buffer.Data)
+				p.buffer.Release()
 			})
 
 			It("packs 1-RTT ACK-only packets", func() {
@@ -270,6 +276,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 				Expect(p.ack).To(Equal(ack))
 				parsePacket(p.buffer.Data)
+				p.buffer.Release()
 			})
 		})
 
@@ -307,6 +314,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.packets[0].header.Type).To(Equal(protocol.PacketType0RTT))
 				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.Encryption0RTT))
 				Expect(p.packets[0].frames).To(Equal([]ackhandler.Frame{cf}))
+				p.buffer.Release()
 			})
 		})
 
@@ -330,6 +338,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(ccf.ErrorCode).To(BeEquivalentTo(0x100 + 0x42))
 				Expect(ccf.FrameType).To(BeEquivalentTo(0x1234))
 				Expect(ccf.ReasonPhrase).To(BeEmpty())
+				p.buffer.Release()
 			})
 
 			It("packs a CONNECTION_CLOSE in 1-RTT", func() {
@@ -352,6 +361,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(ccf.IsApplicationError).To(BeFalse())
 				Expect(ccf.ErrorCode).To(BeEquivalentTo(qerr.CryptoBufferExceeded))
 				Expect(ccf.ReasonPhrase).To(Equal("test error"))
+				p.buffer.Release()
 			})
 
 			It("packs a CONNECTION_CLOSE in all available encryption levels, and replaces application errors in Initial and Handshake", func() {
@@ -394,6 +404,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(ccf.IsApplicationError).To(BeTrue())
 				Expect(ccf.ErrorCode).To(BeEquivalentTo(0x1337))
 				Expect(ccf.ReasonPhrase).To(Equal("test error"))
+				p.buffer.Release()
 			})
 
 			It("packs a CONNECTION_CLOSE in all available encryption levels, as a client", func() {
@@ -429,6 +440,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(ccf.IsApplicationError).To(BeTrue())
 				Expect(ccf.ErrorCode).To(BeEquivalentTo(0x1337))
 				Expect(ccf.ReasonPhrase).To(Equal("test error"))
+				p.buffer.Release()
 			})
 
 			It("packs a CONNECTION_CLOSE in all available encryption levels and pads, as a client", func() {
@@ -469,6 +481,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(hdrs).To(HaveLen(2))
 				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
 				Expect(hdrs[1].Type).To(Equal(protocol.PacketType0RTT))
+				p.buffer.Release()
 			})
 		})
 
@@ -503,6 +516,7 @@ var _ = Describe("Packet packer", func() {
 				f.Write(b, packer.version)
 				Expect(p.frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
 				Expect(p.buffer.Data).To(ContainSubstring(b.String()))
+				p.buffer.Release()
 			})
 
 			It("stores the encryption level a packet was sealed with", func() {
@@ -519,6 +533,7 @@ var _ = Describe("Packet packer", func() {
 				p, err := packer.PackPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				p.buffer.Release()
 			})
 
 			It("packs a single ACK", func() {
@@ -532,6 +547,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(p).ToNot(BeNil())
 				Expect(p.ack).To(Equal(ack))
+				p.buffer.Release()
 			})
 
 			It("packs control frames", func() {
@@ -551,6 +567,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.frames).To(Equal(frames))
 				Expect(p.buffer.Len()).ToNot(BeZero())
+				p.buffer.Release()
 			})
 
 			It("packs DATAGRAM frames", func() {
@@ -570,6 +587,7 @@ var _ = Describe("Packet packer", func() {
 				// make sure the DATAGRAM has actually been queued
 				time.Sleep(scaleDuration(20 * time.Millisecond))
 
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
 				framer.EXPECT().HasData()
 				p, err := packer.PackPacket()
 				Expect(p).ToNot(BeNil())
@@ -578,6 +596,159 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.frames[0].Frame).To(Equal(f))
 				Expect(p.buffer.Data).ToNot(BeEmpty())
 				Eventually(done).Should(BeClosed())
+				p.buffer.Release()
+			})
+
+			It("packs multiple DATAGRAM frames into one packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				frames := []*wire.DatagramFrame{
+					{DataLenPresent: true, Data: []byte("foo")},
+					{DataLenPresent: true, Data: []byte("bar")},
+					{DataLenPresent: true, Data: []byte("baz")},
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(frames...)
+				}()
+				// make sure the DATAGRAMs have actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				framer.EXPECT().HasData()
+				p, err := packer.PackPacket()
+				Expect(p).ToNot(BeNil())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(3))
+				for i, f := range frames {
+					Expect(p.frames[i].Frame).To(Equal(f))
+				}
+				Eventually(done).Should(BeClosed())
+				p.buffer.Release()
+			})
+
+			It("packs DATAGRAM frames that don't fit into the current packet into the next packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42)).Times(2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil).Times(2)
+				frames := []*wire.DatagramFrame{
+					{DataLenPresent: true, Data: make([]byte, 800)},
+					{DataLenPresent: true, Data: make([]byte, 800)},
+				}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(frames...)
+				}()
+				// make sure the DATAGRAMs have actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Times(2)
+				framer.EXPECT().HasData().Times(2)
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(frames[0]))
+				p.buffer.Release()
+				Consistently(done, scaleDuration(20*time.Millisecond)).ShouldNot(BeClosed())
+				p, err = packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(frames[1]))
+				Eventually(done).Should(BeClosed())
+				p.buffer.Release()
+			})
+
+			It("drops DATAGRAM frames that are too large for a packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				small := &wire.DatagramFrame{DataLenPresent: true, Data: []byte("foobar")}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(&wire.DatagramFrame{DataLenPresent: true, Data: make([]byte, 2000)}, small)
+				}()
+				// make sure the DATAGRAMs have actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				framer.EXPECT().HasData()
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(small))
+				Eventually(done).Should(BeClosed())
+				p.buffer.Release()
+			})
+
+			It("packs ACK frames together with DATAGRAM frames", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				f := &wire.DatagramFrame{DataLenPresent: true, Data: []byte("foobar")}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(f)
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 100}}}
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(ack)
+				framer.EXPECT().HasData()
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.ack).To(Equal(ack))
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				Eventually(done).Should(BeClosed())
+				p.buffer.Release()
+			})
+
+			It("doesn't drop DATAGRAM frames that fit into a full-size packet", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).Times(2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42)).Times(2)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil).Times(2)
+				f := &wire.DatagramFrame{DataLenPresent: true}
+				f.Data = make([]byte, f.MaxDataLen(packer.maxDatagramFrameSize(getSealer()), packer.version))
+				Expect(f.Length(packer.version)).To(Equal(packer.maxDatagramFrameSize(getSealer())))
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					datagramQueue.AddAndWait(f)
+				}()
+				// make sure the DATAGRAM has actually been queued
+				time.Sleep(scaleDuration(20 * time.Millisecond))
+
+				// The first packet contains an ACK, so the DATAGRAM frame doesn't fit.
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 100}}}
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(ack)
+				framer.EXPECT().HasData().Times(2)
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.ack).To(Equal(ack))
+				Expect(p.frames).To(BeEmpty())
+				p.buffer.Release()
+				Consistently(done, scaleDuration(20*time.Millisecond)).ShouldNot(BeClosed())
+				// The DATAGRAM frame is sent in the next packet.
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
+				p, err = packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.ack).To(BeNil())
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				Expect(p.buffer.Len()).To(BeNumerically("<=", packer.maxPacketSize))
+				Eventually(done).Should(BeClosed())
+				p.buffer.Release()
 			})
 
 			It("accounts for the space consumed by control frames", func() {
@@ -636,6 +807,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
 				Expect(r.Len()).To(Equal(sealer.Overhead()))
+				packet.buffer.Release()
 			})
 
 			It("pads if payload length + packet number length is smaller than 4", func() {
@@ -677,6 +849,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(sf.Fin).To(Equal(f.Fin))
 				Expect(sf.Data).To(BeEmpty())
 				Expect(r.Len()).To(BeZero())
+				packet.buffer.Release()
 			})
 
 			It("packs multiple small STREAM frames into single packet", func() {
@@ -709,6 +882,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.frames[0].Frame.(*wire.StreamFrame).Data).To(Equal([]byte("frame 1")))
 				Expect(p.frames[1].Frame.(*wire.StreamFrame).Data).To(Equal([]byte("frame 2")))
 				Expect(p.frames[2].Frame.(*wire.StreamFrame).Data).To(Equal([]byte("frame 3")))
+				p.buffer.Release()
 			})
 
 			Context("making ACK packets ack-eliciting", func() {
@@ -726,6 +900,7 @@ var _ = Describe("Packet packer", func() {
 						Expect(err).ToNot(HaveOccurred())
 						Expect(p.ack).ToNot(BeNil())
 						Expect(p.frames).To(BeEmpty())
+						p.buffer.Release()
 					}
 				}
 
@@ -749,6 +924,7 @@ var _ = Describe("Packet packer", func() {
 						}
 					}
 					Expect(hasPing).To(BeTrue())
+					p.buffer.Release()
 					// make sure the next packet doesn't contain another PING
 					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -762,6 +938,7 @@ var _ = Describe("Packet packer", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p.ack).ToNot(BeNil())
 					Expect(p.frames).To(BeEmpty())
+					p.buffer.Release()
 				})
 
 				It("waits until there's something to send before adding a PING frame", func() {
@@ -796,6 +973,7 @@ var _ = Describe("Packet packer", func() {
 						}
 					}
 					Expect(hasPing).To(BeTrue())
+					p.buffer.Release()
 				})
 
 				It("doesn't send a PING if it already sent another ack-eliciting frame", func() {
@@ -811,6 +989,7 @@ var _ = Describe("Packet packer", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(p).ToNot(BeNil())
 					Expect(p.frames).ToNot(ContainElement(&wire.PingFrame{}))
+					p.buffer.Release()
 				})
 			})
 
@@ -914,6 +1093,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p).ToNot(BeNil())
 				parsePacket(p.buffer.Data)
+				p.buffer.Release()
 			})
 
 			It("packs an Initial packet and pads it", func() {
@@ -940,6 +1120,7 @@ var _ = Describe("Packet packer", func() {
 				hdrs := parsePacket(p.buffer.Data)
 				Expect(hdrs).To(HaveLen(1))
 				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
+				p.buffer.Release()
 			})
 
 			It("packs a maximum size Handshake packet", func() {
@@ -965,6 +1146,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.packets[0].header.IsLongHeader).To(BeTrue())
 				Expect(p.buffer.Len()).To(BeEquivalentTo(packer.maxPacketSize))
 				parsePacket(p.buffer.Data)
+				p.buffer.Release()
 			})
 
 			It("packs a coalesced packet with Initial / Handshake, and pads it", func() {
@@ -999,6 +1181,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(hdrs).To(HaveLen(2))
 				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
 				Expect(hdrs[1].Type).To(Equal(protocol.PacketTypeHandshake))
+				p.buffer.Release()
 			})
 
 			It("packs a coalesced packet with Initial / super short Handshake, and pads it", func() {
@@ -1031,6 +1214,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(hdrs).To(HaveLen(2))
 				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
 				Expect(hdrs[1].Type).To(Equal(protocol.PacketTypeHandshake))
+				p.buffer.Release()
 			})
 
 			It("packs a coalesced packet with super short Initial / super short Handshake, and pads it", func() {
@@ -1060,6 +1244,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(hdrs).To(HaveLen(2))
 				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
 				Expect(hdrs[1].Type).To(Equal(protocol.PacketTypeHandshake))
+				p.buffer.Release()
 			})
 
 			It("packs a coalesced packet with Initial / super short 1-RTT, and pads it", func() {
@@ -1093,6 +1278,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(hdrs).To(HaveLen(2))
 				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
 				Expect(hdrs[1].IsLongHeader).To(BeFalse())
+				p.buffer.Release()
 			})
 
 			It("packs a coalesced packet with Initial / 0-RTT, and pads it", func() {
@@ -1129,6 +1315,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(hdrs).To(HaveLen(2))
 				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
 				Expect(hdrs[1].Type).To(Equal(protocol.PacketType0RTT))
+				p.buffer.Release()
 			})
 
 			It("packs a coalesced packet with Handshake / 1-RTT", func() {
@@ -1165,6 +1352,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(hdr.IsLongHeader).To(BeFalse())
 				Expect(rest).To(BeEmpty())
+				p.buffer.Release()
 			})
 
 			It("doesn't add a coalesced packet if the remaining size is smaller than MaxCoalescedPacketSize", func() {
@@ -1188,6 +1376,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionHandshake))
 				Expect(len(p.buffer.Data)).To(BeEquivalentTo(maxPacketSize - protocol.MinCoalescedPacketSize))
 				parsePacket(p.buffer.Data)
+				p.buffer.Release()
 			})
 
 			It("pads if payload length + packet number length is smaller than 4, for Long Header packets", func() {
@@ -1226,6 +1415,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
 				Expect(r.Len()).To(Equal(sealer.Overhead()))
+				packet.buffer.Release()
 			})
 
 			It("adds retransmissions", func() {
@@ -1245,6 +1435,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
 				Expect(p.packets[0].frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
 				Expect(p.packets[0].header.IsLongHeader).To(BeTrue())
+				p.buffer.Release()
 			})
 
 			It("sends an Initial packet containing only an ACK", func() {
@@ -1260,6 +1451,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.packets).To(HaveLen(1))
 				Expect(p.packets[0].ack).To(Equal(ack))
+				p.buffer.Release()
 			})
 
 			It("doesn't pack anything if there's nothing to send at Initial and Handshake keys are not yet available", func() {
@@ -1288,6 +1480,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.packets).To(HaveLen(1))
 				Expect(p.packets[0].ack).To(Equal(ack))
+				p.buffer.Release()
 			})
 
 			for _, pers := range []protocol.Perspective{protocol.PerspectiveServer, protocol.PerspectiveClient} {
@@ -1316,6 +1509,7 @@ var _ = Describe("Packet packer", func() {
 					Expect(p.packets[0].frames).To(HaveLen(1))
 					cf := p.packets[0].frames[0].Frame.(*wire.CryptoFrame)
 					Expect(cf.Data).To(Equal([]byte("foobar")))
+					p.buffer.Release()
 				})
 			}
 
@@ -1339,6 +1533,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.packets[0].ack).To(Equal(ack))
 				Expect(p.packets[0].frames).To(HaveLen(1))
 				Expect(p.buffer.Len()).To(BeEquivalentTo(maxPacketSize))
+				p.buffer.Release()
 			})
 		})
 
@@ -1365,6 +1560,7 @@ var _ = Describe("Packet packer", func() {
 					Expect(packet.frames).To(HaveLen(1))
 					Expect(packet.frames[0].Frame).To(Equal(f))
 					parsePacket(packet.buffer.Data)
+					packet.buffer.Release()
 				})
 
 				It(fmt.Sprintf("packs an Initial probe packet with 1 byte payload, for the %s", perspective), func() {
@@ -1385,6 +1581,7 @@ var _ = Describe("Packet packer", func() {
 					Expect(packet.frames).To(HaveLen(1))
 					Expect(packet.frames[0].Frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
 					parsePacket(packet.buffer.Data)
+					packet.buffer.Release()
 				})
 			}
 
@@ -1404,6 +1601,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.frames).To(HaveLen(1))
 				Expect(packet.frames[0].Frame).To(Equal(f))
 				parsePacket(packet.buffer.Data)
+				packet.buffer.Release()
 			})
 
 			It("packs a full size  Handshake probe packet", func() {
@@ -1423,6 +1621,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.frames[0].Frame).To(BeAssignableToTypeOf(&wire.CryptoFrame{}))
 				Expect(packet.length).To(Equal(maxPacketSize))
 				parsePacket(packet.buffer.Data)
+				packet.buffer.Release()
 			})
 
 			It("packs a 1-RTT probe packet", func() {
@@ -1442,6 +1641,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 				Expect(packet.frames).To(HaveLen(1))
 				Expect(packet.frames[0].Frame).To(Equal(f))
+				packet.buffer.Release()
 			})
 
 			It("packs a full size 1-RTT probe packet", func() {
@@ -1466,6 +1666,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.frames).To(HaveLen(1))
 				Expect(packet.frames[0].Frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
 				Expect(packet.length).To(Equal(maxPacketSize))
+				packet.buffer.Release()
 			})
 
 			It("returns nil if there's no probe data to send", func() {
@@ -1493,6 +1694,21 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 				Expect(p.buffer.Data).To(HaveLen(int(probePacketSize)))
 				Expect(p.packetContents.isMTUProbePacket).To(BeTrue())
+				p.buffer.Release()
+			})
+
+			It("packs MTU probe packets larger than the default packet buffer size", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				ping := ackhandler.Frame{Frame: &wire.PingFrame{}}
+				const probePacketSize = protocol.MaxPacketBufferSize + 100
+				p, err := packer.PackMTUProbePacket(ping, probePacketSize)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.length).To(BeEquivalentTo(probePacketSize))
+				Expect(p.buffer.Data).To(HaveLen(int(probePacketSize)))
+				Expect(p.buffer.Data).To(HaveCap(int(largePacketBufferSize)))
+				p.buffer.Release()
 			})
 		})
 	})
@@ -1554,3 +1770,88 @@ var _ = Describe("Converting to AckHandler packets", func() {
 		Entry(protocol.Encryption1RTT.String(), wire.Header{}),
 	)
 })
+
+// benchmarkSealer uses the Initial AEAD to seal 1-RTT packets.
+type benchmarkSealer struct{ handshake.LongHeaderSealer }
+
+func (s *benchmarkSealer) KeyPhase() protocol.KeyPhaseBit { return protocol.KeyPhaseZero }
+
+// benchmarkOpener uses the Initial AEAD to open 1-RTT packets.
+type benchmarkOpener struct{ handshake.LongHeaderOpener }
+
+func (o *benchmarkOpener) Open(dst, src []byte, _ time.Time, pn protocol.PacketNumber, _ protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	return o.LongHeaderOpener.Open(dst, src, pn, ad)
+}
+
+type benchmarkSealingManager struct{ sealer handshake.ShortHeaderSealer }
+
+func (m *benchmarkSealingManager) GetInitialSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (m *benchmarkSealingManager) GetHandshakeSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (m *benchmarkSealingManager) Get0RTTSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (m *benchmarkSealingManager) Get1RTTSealer() (handshake.ShortHeaderSealer, error) {
+	return m.sealer, nil
+}
+
+// benchmarkFramer always has a STREAM frame that fills the whole packet.
+type benchmarkFramer struct {
+	data   []byte
+	offset protocol.ByteCount
+}
+
+func (f *benchmarkFramer) HasData() bool { return true }
+
+func (f *benchmarkFramer) AppendControlFrames(frames []ackhandler.Frame, _ protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+	return frames, 0
+}
+
+func (f *benchmarkFramer) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
+	frame := &wire.StreamFrame{StreamID: 4, Offset: f.offset, DataLenPresent: true}
+	frame.Data = f.data[:frame.MaxDataLen(maxLen, protocol.Version1)]
+	f.offset += frame.DataLen()
+	return append(frames, ackhandler.Frame{Frame: frame}), frame.Length(protocol.Version1)
+}
+
+// newBenchmarkPacker creates a packer that packs 1-RTT packets containing a single STREAM frame.
+func newBenchmarkPacker(connID protocol.ConnectionID) *packetPacker {
+	sealer, _ := handshake.NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version1)
+	sph, rph := ackhandler.NewAckHandler(0, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), protocol.PerspectiveClient, true, nil, nil, utils.DefaultLogger, protocol.Version1)
+	return newPacketPacker(
+		protocol.ConnectionID{1, 2, 3, 4},
+		func() protocol.ConnectionID { return connID },
+		newCryptoStream(),
+		newCryptoStream(),
+		sph,
+		newRetransmissionQueue(protocol.Version1),
+		&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		&benchmarkSealingManager{sealer: &benchmarkSealer{sealer}},
+		&benchmarkFramer{data: make([]byte, protocol.MaxPacketBufferSize)},
+		rph,
+		nil,
+		protocol.PerspectiveClient,
+		protocol.Version1,
+	)
+}
+
+func BenchmarkPackPacket(b *testing.B) {
+	packer := newBenchmarkPacker(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37})
+	// the framer fills every packet
+	b.SetBytes(int64(packer.maxPacketSize))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, err := packer.PackPacket()
+		if err != nil {
+			b.Fatal(err)
+		}
+		p.buffer.Release()
+	}
+}
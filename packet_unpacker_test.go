@@ -3,6 +3,7 @@ package quic
 import (
 	"bytes"
 	"errors"
+	"testing"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
@@ -290,3 +291,39 @@ var _ = Describe("Packet Unpacker", func() {
 		Expect(packet.packetNumber).To(Equal(protocol.PacketNumber(0x7331)))
 	})
 })
+
+type benchmarkCryptoSetup struct {
+	handshake.CryptoSetup
+	opener handshake.ShortHeaderOpener
+}
+
+func (cs *benchmarkCryptoSetup) Get1RTTOpener() (handshake.ShortHeaderOpener, error) {
+	return cs.opener, nil
+}
+
+func BenchmarkUnpackPacket(b *testing.B) {
+	connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+	p, err := newBenchmarkPacker(connID).PackPacket()
+	if err != nil {
+		b.Fatal(err)
+	}
+	packet := p.buffer.Data
+	// the packer seals packets using the client's Initial keys
+	_, opener := handshake.NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.Version1)
+	unpacker := newPacketUnpacker(&benchmarkCryptoSetup{opener: &benchmarkOpener{opener}}, protocol.Version1)
+	data := make([]byte, len(packet))
+	b.SetBytes(int64(len(packet)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// unpacking decrypts the packet in place
+		copy(data, packet)
+		hdr, packetData, _, err := wire.ParsePacket(data, connID.Len())
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := unpacker.Unpack(hdr, time.Now(), packetData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
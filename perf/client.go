@@ -0,0 +1,227 @@
+package perf
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// Request performs a single perf request on the connection.
+// It uploads upload bytes, and downloads download bytes.
+// It returns the time it took until the last byte of the response was received.
+func Request(ctx context.Context, conn quic.Connection, upload, download uint64) (time.Duration, error) {
+	start := time.Now()
+	str, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return 0, err
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], download)
+		if _, err := str.Write(b[:]); err != nil {
+			errChan <- err
+			return
+		}
+		if err := writeZeros(str, upload); err != nil {
+			errChan <- err
+			return
+		}
+		errChan <- str.Close()
+	}()
+	n, err := io.Copy(io.Discard, str)
+	if err != nil {
+		return 0, err
+	}
+	if err := <-errChan; err != nil {
+		return 0, err
+	}
+	if uint64(n) != download {
+		return 0, fmt.Errorf("perf: expected %d bytes, received %d", download, n)
+	}
+	return time.Since(start), nil
+}
+
+// ClientConfig configures a perf run.
+type ClientConfig struct {
+	// Dial establishes a new connection to the perf server.
+	// It must return once the handshake has completed.
+	Dial func(context.Context) (quic.Connection, error)
+	// Connections is the number of connections established. Defaults to 1.
+	Connections int
+	// RequestsPerConnection is the number of requests performed on every connection. Defaults to 1.
+	RequestsPerConnection int
+	// Parallel is the number of connections established at the same time,
+	// and the number of requests performed at the same time on every connection. Defaults to 1.
+	Parallel int
+	// UploadBytes is the number of bytes uploaded with every request.
+	UploadBytes uint64
+	// DownloadBytes is the number of bytes downloaded with every request.
+	DownloadBytes uint64
+}
+
+// Results are the results of a perf run.
+type Results struct {
+	// Duration is the duration of the whole run.
+	Duration time.Duration
+	// Handshakes is the number of connections established.
+	Handshakes int
+	// HandshakeLatencies are the durations of the handshakes, sorted in ascending order.
+	HandshakeLatencies []time.Duration
+	// RequestLatencies are the durations of the requests, sorted in ascending order.
+	RequestLatencies []time.Duration
+	// BytesUploaded is the total number of bytes uploaded, not including the request headers.
+	BytesUploaded uint64
+	// BytesDownloaded is the total number of bytes downloaded.
+	BytesDownloaded uint64
+}
+
+// UploadThroughput is the upload throughput in bits per second.
+func (r *Results) UploadThroughput() float64 {
+	return float64(r.BytesUploaded) * 8 / r.Duration.Seconds()
+}
+
+// DownloadThroughput is the download throughput in bits per second.
+func (r *Results) DownloadThroughput() float64 {
+	return float64(r.BytesDownloaded) * 8 / r.Duration.Seconds()
+}
+
+// HandshakesPerSecond is the number of handshakes per second.
+func (r *Results) HandshakesPerSecond() float64 {
+	return float64(r.Handshakes) / r.Duration.Seconds()
+}
+
+// RequestsPerSecond is the number of requests per second.
+func (r *Results) RequestsPerSecond() float64 {
+	return float64(len(r.RequestLatencies)) / r.Duration.Seconds()
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of the sorted latencies, using the nearest-rank method.
+// It returns 0 if there are no latencies.
+func Percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(latencies))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(latencies) {
+		rank = len(latencies) - 1
+	}
+	return latencies[rank]
+}
+
+// Run performs a perf run.
+func Run(ctx context.Context, config *ClientConfig) (*Results, error) {
+	numConns := config.Connections
+	if numConns <= 0 {
+		numConns = 1
+	}
+	numRequests := config.RequestsPerConnection
+	if numRequests <= 0 {
+		numRequests = 1
+	}
+	parallel := config.Parallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var firstErr error
+	res := &Results{}
+	setErr := func(err error) {
+		mutex.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mutex.Unlock()
+		cancel()
+	}
+
+	conns := make(chan struct{}, numConns)
+	for i := 0; i < numConns; i++ {
+		conns <- struct{}{}
+	}
+	close(conns)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < parallel && i < numConns; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range conns {
+				hsStart := time.Now()
+				conn, err := config.Dial(ctx)
+				if err != nil {
+					setErr(err)
+					return
+				}
+				mutex.Lock()
+				res.Handshakes++
+				res.HandshakeLatencies = append(res.HandshakeLatencies, time.Since(hsStart))
+				mutex.Unlock()
+				err = runRequests(ctx, conn, config, numRequests, parallel, func(d time.Duration) {
+					mutex.Lock()
+					res.RequestLatencies = append(res.RequestLatencies, d)
+					res.BytesUploaded += config.UploadBytes
+					res.BytesDownloaded += config.DownloadBytes
+					mutex.Unlock()
+				})
+				conn.CloseWithError(0, "")
+				if err != nil {
+					setErr(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	res.Duration = time.Since(start)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(res.HandshakeLatencies, func(i, j int) bool { return res.HandshakeLatencies[i] < res.HandshakeLatencies[j] })
+	sort.Slice(res.RequestLatencies, func(i, j int) bool { return res.RequestLatencies[i] < res.RequestLatencies[j] })
+	return res, nil
+}
+
+func runRequests(ctx context.Context, conn quic.Connection, config *ClientConfig, num, parallel int, onDone func(time.Duration)) error {
+	reqs := make(chan struct{}, num)
+	for i := 0; i < num; i++ {
+		reqs <- struct{}{}
+	}
+	close(reqs)
+
+	errChan := make(chan error, parallel)
+	for i := 0; i < parallel && i < num; i++ {
+		go func() {
+			for range reqs {
+				d, err := Request(ctx, conn, config.UploadBytes, config.DownloadBytes)
+				if err != nil {
+					errChan <- err
+					return
+				}
+				onDone(d)
+			}
+			errChan <- nil
+		}()
+	}
+	var firstErr error
+	for i := 0; i < parallel && i < num; i++ {
+		if err := <-errChan; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -1,4 +1,4 @@
-package http09
+package perf
 
 import (
 	"testing"
@@ -7,7 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-func TestHttp09(t *testing.T) {
+func TestPerf(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "HTTP/0.9 Suite")
+	RunSpecs(t, "Perf Suite")
 }
@@ -0,0 +1,138 @@
+package perf
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Perf", func() {
+	var (
+		ln         quic.Listener
+		serverDone chan struct{}
+	)
+
+	BeforeEach(func() {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{ALPN}
+		var err error
+		ln, err = quic.ListenAddr("localhost:0", tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		serverDone = make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(serverDone)
+			Expect(RunServer(ln)).To(HaveOccurred())
+		}()
+	})
+
+	AfterEach(func() {
+		Expect(ln.Close()).To(Succeed())
+		Eventually(serverDone).Should(BeClosed())
+	})
+
+	dial := func(ctx context.Context) (quic.Connection, error) {
+		return quic.DialAddrContext(
+			ctx,
+			ln.Addr().String(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{ALPN}},
+			nil,
+		)
+	}
+
+	It("implements the wire format", func() {
+		conn, err := dial(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, 1234)
+		_, err = str.Write(append(b, make([]byte, 5000)...))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(make([]byte, 1234)))
+	})
+
+	It("performs a request", func() {
+		conn, err := dial(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		d, err := Request(context.Background(), conn, 100000, 200000)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).To(BeNumerically(">", 0))
+	})
+
+	It("doesn't wait for the response before uploading", func() {
+		conn, err := dial(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		// larger than the flow control windows
+		_, err = Request(context.Background(), conn, 10<<20, 0)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("runs multiple connections with multiple requests", func() {
+		res, err := Run(context.Background(), &ClientConfig{
+			Dial:                  dial,
+			Connections:           5,
+			RequestsPerConnection: 4,
+			Parallel:              2,
+			UploadBytes:           1000,
+			DownloadBytes:         2000,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Handshakes).To(Equal(5))
+		Expect(res.HandshakeLatencies).To(HaveLen(5))
+		Expect(res.RequestLatencies).To(HaveLen(20))
+		Expect(res.BytesUploaded).To(BeEquivalentTo(20 * 1000))
+		Expect(res.BytesDownloaded).To(BeEquivalentTo(20 * 2000))
+		Expect(res.HandshakesPerSecond()).To(BeNumerically(">", 0))
+		Expect(res.RequestsPerSecond()).To(BeNumerically(">", 0))
+		Expect(res.DownloadThroughput()).To(BeNumerically("~", 2*res.UploadThroughput()))
+		for i := 1; i < len(res.RequestLatencies); i++ {
+			Expect(res.RequestLatencies[i]).To(BeNumerically(">=", res.RequestLatencies[i-1]))
+		}
+	})
+
+	It("returns dial errors", func() {
+		testErr := context.DeadlineExceeded
+		_, err := Run(context.Background(), &ClientConfig{
+			Dial: func(context.Context) (quic.Connection, error) { return nil, testErr },
+		})
+		Expect(err).To(MatchError(testErr))
+	})
+})
+
+var _ = Describe("Percentiles", func() {
+	It("returns 0 if there are no values", func() {
+		Expect(Percentile(nil, 50)).To(BeZero())
+	})
+
+	It("calculates percentiles", func() {
+		var latencies []time.Duration
+		for i := 1; i <= 100; i++ {
+			latencies = append(latencies, time.Duration(i)*time.Millisecond)
+		}
+		Expect(Percentile(latencies, 0)).To(Equal(time.Millisecond))
+		Expect(Percentile(latencies, 50)).To(Equal(50 * time.Millisecond))
+		Expect(Percentile(latencies, 99)).To(Equal(99 * time.Millisecond))
+		Expect(Percentile(latencies, 100)).To(Equal(100 * time.Millisecond))
+	})
+
+	It("uses the nearest rank", func() {
+		latencies := []time.Duration{1, 2, 3}
+		Expect(Percentile(latencies, 50)).To(Equal(time.Duration(2)))
+		Expect(Percentile(latencies, 90)).To(Equal(time.Duration(3)))
+	})
+})
@@ -0,0 +1,80 @@
+// Package perf implements the QUIC performance measurement protocol,
+// see https://datatracker.ietf.org/doc/html/draft-banks-quic-perf.
+//
+// For every request, the client opens a bidirectional stream. It sends the number of bytes it
+// wants to receive as a 64 bit unsigned integer in network byte order, followed by the data it uploads,
+// and then closes the stream. After receiving the FIN, the server sends the requested number of bytes,
+// and closes the stream.
+package perf
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// ALPN is the ALPN used by the perf protocol.
+const ALPN = "perf"
+
+// The data sent and received is not used. We send zeros.
+var zeros [16 << 10]byte
+
+// RunServer accepts connections on the listener, and serves perf requests on them.
+// It returns when the listener is closed.
+func RunServer(ln quic.Listener) error {
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go ServeConn(conn)
+	}
+}
+
+// ServeConn serves perf requests on a connection.
+// It returns when the connection is closed.
+func ServeConn(conn quic.Connection) {
+	for {
+		str, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			if err := handleStream(str); err != nil {
+				str.CancelRead(0)
+				str.CancelWrite(0)
+			}
+		}()
+	}
+}
+
+func handleStream(str quic.Stream) error {
+	var b [8]byte
+	if _, err := io.ReadFull(str, b[:]); err != nil {
+		return err
+	}
+	// discard the data uploaded by the client
+	if _, err := io.Copy(io.Discard, str); err != nil {
+		return err
+	}
+	if err := writeZeros(str, binary.BigEndian.Uint64(b[:])); err != nil {
+		return err
+	}
+	return str.Close()
+}
+
+func writeZeros(w io.Writer, n uint64) error {
+	for n > 0 {
+		l := uint64(len(zeros))
+		if n < l {
+			l = n
+		}
+		if _, err := w.Write(zeros[:l]); err != nil {
+			return err
+		}
+		n -= l
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package quic
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+type pingRequest struct {
+	rtt  time.Duration
+	err  error
+	done chan struct{}
+}
+
+// An ackedPing is a PING frame acknowledged by the ACK frame currently being processed.
+type ackedPing struct {
+	req *pingRequest
+	// the time that passed between sending the packet containing the PING frame and receiving the ACK
+	rtt time.Duration
+}
+
+// The pinger sends PING frames requested by the application,
+// and reports the RTT once the PING frame is acknowledged.
+type pinger struct {
+	mutex       sync.Mutex
+	outstanding map[*pingRequest]struct{}
+	acked       []ackedPing
+	closeErr    error
+
+	queueFrame func(ackhandler.Frame)
+	rttStats   *utils.RTTStats
+}
+
+func newPinger(queueFrame func(ackhandler.Frame), rttStats *utils.RTTStats) *pinger {
+	return &pinger{
+		outstanding: make(map[*pingRequest]struct{}),
+		queueFrame:  queueFrame,
+		rttStats:    rttStats,
+	}
+}
+
+// Ping queues a PING frame, and blocks until it is acknowledged.
+func (p *pinger) Ping(ctx context.Context) (time.Duration, error) {
+	req := &pingRequest{done: make(chan struct{})}
+	p.mutex.Lock()
+	if p.closeErr != nil {
+		p.mutex.Unlock()
+		return 0, p.closeErr
+	}
+	p.outstanding[req] = struct{}{}
+	p.mutex.Unlock()
+	p.queuePing(req)
+
+	select {
+	case <-req.done:
+		return req.rtt, req.err
+	case <-ctx.Done():
+		p.mutex.Lock()
+		delete(p.outstanding, req)
+		p.mutex.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+func (p *pinger) queuePing(req *pingRequest) {
+	// The callbacks are all called from the connection's run loop.
+	var sentTime time.Time
+	p.queueFrame(ackhandler.Frame{
+		Frame:  &wire.PingFrame{},
+		OnSent: func(t time.Time) { sentTime = t },
+		OnLost: func(wire.Frame) {
+			p.mutex.Lock()
+			_, ok := p.outstanding[req]
+			p.mutex.Unlock()
+			if ok {
+				p.queuePing(req)
+			}
+		},
+		OnAcked: func(wire.Frame) {
+			rtt := time.Since(sentTime)
+			p.mutex.Lock()
+			p.acked = append(p.acked, ackedPing{req: req, rtt: rtt})
+			p.mutex.Unlock()
+		},
+	})
+}
+
+// AckProcessed is called after an ACK frame was processed.
+// Unlike the RTT stats, the ack delay is always subtracted from the RTT sample,
+// since the peer is expected to delay the acknowledgement for a packet that only contains a PING frame.
+// The RTT reported is never smaller than the minimum RTT.
+func (p *pinger) AckProcessed(ackDelay time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, a := range p.acked {
+		// The PING frame might have been retransmitted, and both packets acknowledged.
+		if _, ok := p.outstanding[a.req]; !ok {
+			continue
+		}
+		delete(p.outstanding, a.req)
+		a.req.rtt = utils.MaxDuration(a.rtt-ackDelay, p.rttStats.MinRTT())
+		close(a.req.done)
+	}
+	p.acked = p.acked[:0]
+}
+
+func (p *pinger) CloseWithError(e error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closeErr = e
+	for req := range p.outstanding {
+		req.err = e
+		close(req.done)
+	}
+	p.outstanding = nil
+}
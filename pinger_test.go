@@ -0,0 +1,149 @@
+package quic
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pinger", func() {
+	var (
+		p        *pinger
+		queued   chan ackhandler.Frame
+		rttStats *utils.RTTStats
+	)
+
+	type pingResult struct {
+		rtt time.Duration
+		err error
+	}
+
+	ping := func(ctx context.Context) <-chan pingResult {
+		c := make(chan pingResult, 1)
+		go func() {
+			defer GinkgoRecover()
+			rtt, err := p.Ping(ctx)
+			c <- pingResult{rtt: rtt, err: err}
+		}()
+		return c
+	}
+
+	BeforeEach(func() {
+		queued = make(chan ackhandler.Frame, 10)
+		rttStats = &utils.RTTStats{}
+		p = newPinger(func(f ackhandler.Frame) { queued <- f }, rttStats)
+	})
+
+	It("returns the RTT when the PING frame is acknowledged", func() {
+		res := ping(context.Background())
+		var f ackhandler.Frame
+		Eventually(queued).Should(Receive(&f))
+		Expect(f.Frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+		Consistently(res).ShouldNot(Receive())
+		f.OnSent(time.Now().Add(-scaleDuration(100 * time.Millisecond)))
+		f.OnAcked(f.Frame)
+		p.AckProcessed(0)
+		var r pingResult
+		Eventually(res).Should(Receive(&r))
+		Expect(r.err).ToNot(HaveOccurred())
+		Expect(r.rtt).To(BeNumerically(">", scaleDuration(100*time.Millisecond)))
+		Expect(r.rtt).To(BeNumerically("<", scaleDuration(200*time.Millisecond)))
+	})
+
+	It("uses the send time of the packet containing the PING frame", func() {
+		rttStats.UpdateRTT(10*time.Millisecond, 0, time.Now())
+		res := ping(context.Background())
+		var f ackhandler.Frame
+		Eventually(queued).Should(Receive(&f))
+		f.OnSent(time.Now())
+		f.OnAcked(f.Frame)
+		// The latest RTT sample was obtained from a different packet.
+		rttStats.UpdateRTT(time.Hour, 0, time.Now())
+		p.AckProcessed(0)
+		var r pingResult
+		Eventually(res).Should(Receive(&r))
+		Expect(r.err).ToNot(HaveOccurred())
+		Expect(r.rtt).To(Equal(10 * time.Millisecond))
+	})
+
+	It("subtracts the ack delay", func() {
+		res := ping(context.Background())
+		var f ackhandler.Frame
+		Eventually(queued).Should(Receive(&f))
+		f.OnSent(time.Now().Add(-time.Minute))
+		f.OnAcked(f.Frame)
+		p.AckProcessed(20 * time.Second)
+		var r pingResult
+		Eventually(res).Should(Receive(&r))
+		Expect(r.err).ToNot(HaveOccurred())
+		Expect(r.rtt).To(BeNumerically("~", 40*time.Second, time.Second))
+	})
+
+	It("doesn't report an RTT smaller than the min RTT", func() {
+		rttStats.UpdateRTT(time.Hour, 0, time.Now())
+		res := ping(context.Background())
+		var f ackhandler.Frame
+		Eventually(queued).Should(Receive(&f))
+		f.OnSent(time.Now().Add(-time.Minute))
+		f.OnAcked(f.Frame)
+		p.AckProcessed(0)
+		var r pingResult
+		Eventually(res).Should(Receive(&r))
+		Expect(r.err).ToNot(HaveOccurred())
+		Expect(r.rtt).To(Equal(time.Hour))
+	})
+
+	It("retransmits the PING frame when it is lost", func() {
+		res := ping(context.Background())
+		var f ackhandler.Frame
+		Eventually(queued).Should(Receive(&f))
+		f.OnSent(time.Now().Add(-time.Hour))
+		f.OnLost(f.Frame)
+		var retransmission ackhandler.Frame
+		Eventually(queued).Should(Receive(&retransmission))
+		Expect(retransmission.Frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+		retransmission.OnSent(time.Now().Add(-time.Minute))
+		retransmission.OnAcked(retransmission.Frame)
+		p.AckProcessed(0)
+		var r pingResult
+		Eventually(res).Should(Receive(&r))
+		Expect(r.err).ToNot(HaveOccurred())
+		Expect(r.rtt).To(BeNumerically("~", time.Minute, time.Second))
+		// the original packet is acknowledged after it was declared lost
+		f.OnAcked(f.Frame)
+		p.AckProcessed(0)
+	})
+
+	It("returns when the context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		res := ping(ctx)
+		var f ackhandler.Frame
+		Eventually(queued).Should(Receive(&f))
+		cancel()
+		var r pingResult
+		Eventually(res).Should(Receive(&r))
+		Expect(r.err).To(MatchError(context.Canceled))
+		// the PING frame is not retransmitted any more
+		f.OnLost(f.Frame)
+		Consistently(queued).ShouldNot(Receive())
+	})
+
+	It("returns an error when the connection is closed", func() {
+		res := ping(context.Background())
+		Eventually(queued).Should(Receive())
+		p.CloseWithError(errors.New("test error"))
+		var r pingResult
+		Eventually(res).Should(Receive(&r))
+		Expect(r.err).To(MatchError("test error"))
+		_, err := p.Ping(context.Background())
+		Expect(err).To(MatchError("test error"))
+		Expect(queued).To(BeEmpty())
+	})
+})
@@ -0,0 +1,262 @@
+package quicpacket
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// maxClientHelloSize is the maximum size of a ClientHello that is reassembled.
+// This limits the amount of memory used for a single client.
+const maxClientHelloSize = 1 << 16
+
+const (
+	typeClientHello = 1
+
+	extensionServerName = 0
+	extensionALPN       = 16
+)
+
+// An Extension is a TLS extension.
+type Extension struct {
+	Type uint16
+	Data []byte
+}
+
+// The ClientHello contains the fields of the TLS ClientHello sent in the client's Initial packets.
+type ClientHello struct {
+	// ServerName is the value of the server_name extension (SNI).
+	// It is empty if the client didn't send the extension.
+	ServerName string
+	// ALPN is the list of application protocols offered by the client.
+	ALPN []string
+	// CipherSuites are the cipher suites offered by the client.
+	CipherSuites []uint16
+	// Extensions contains all extensions, in the order they were sent.
+	Extensions []Extension
+	// Raw is the raw ClientHello handshake message.
+	Raw []byte
+}
+
+// A ClientHelloReader decrypts the Initial packets sent by a client, and reassembles
+// the ClientHello from the CRYPTO frames, which may span multiple Initial packets.
+// It is not safe for concurrent use.
+// A new ClientHelloReader needs to be used for every client.
+type ClientHelloReader struct {
+	version    VersionNumber
+	destConnID ConnectionID
+	opener     handshake.LongHeaderOpener
+
+	frames   map[uint64][]byte
+	received []byte
+}
+
+// NewClientHelloReader creates a new ClientHelloReader.
+func NewClientHelloReader() *ClientHelloReader {
+	return &ClientHelloReader{frames: make(map[uint64][]byte)}
+}
+
+// AddDatagram processes a UDP datagram sent by the client.
+// Initial packets are decrypted, all other packets contained in the datagram are skipped.
+// The datagram is not modified.
+// Once the ClientHello has been fully received, it is returned.
+// Until then, nil is returned.
+func (r *ClientHelloReader) AddDatagram(data []byte) (*ClientHello, error) {
+	for len(data) > 0 {
+		if !IsLongHeaderPacket(data) {
+			// short header packets can't be coalesced, they always extend to the end of the datagram
+			return nil, nil
+		}
+		hdr, packet, rest, err := wire.ParsePacket(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+		if hdr.Type != protocol.PacketTypeInitial {
+			continue
+		}
+		if err := r.handleInitialPacket(hdr, packet); err != nil {
+			return nil, err
+		}
+		if ch, err := r.parseClientHello(); ch != nil || err != nil {
+			return ch, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *ClientHelloReader) handleInitialPacket(hdr *wire.Header, packet []byte) error {
+	if r.opener == nil {
+		r.version = hdr.Version
+		r.destConnID = hdr.DestConnectionID
+		_, r.opener = handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
+	} else if hdr.Version != r.version {
+		return fmt.Errorf("received Initial packets with different versions: %s and %s", r.version, hdr.Version)
+	} else if !hdr.DestConnectionID.Equal(r.destConnID) {
+		// A Retry was performed. The client now uses a different connection ID.
+		r.destConnID = hdr.DestConnectionID
+		_, r.opener = handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
+	}
+
+	// Removing header protection and decrypting is done in place.
+	// Don't modify the caller's buffer.
+	data := make([]byte, len(packet))
+	copy(data, packet)
+	payload, err := r.open(hdr, data)
+	if err != nil {
+		return err
+	}
+
+	parser := wire.NewFrameParser(false, hdr.Version)
+	br := bytes.NewReader(payload)
+	for {
+		frame, err := parser.ParseNext(br, protocol.EncryptionInitial)
+		if err != nil {
+			return err
+		}
+		if frame == nil {
+			return nil
+		}
+		f, ok := frame.(*wire.CryptoFrame)
+		if !ok {
+			continue
+		}
+		if uint64(f.Offset)+uint64(len(f.Data)) > maxClientHelloSize {
+			//nolint:stylecheck
+			return errors.New("ClientHello too large")
+		}
+		if existing, ok := r.frames[uint64(f.Offset)]; !ok || len(existing) < len(f.Data) {
+			r.frames[uint64(f.Offset)] = f.Data
+		}
+	}
+}
+
+func (r *ClientHelloReader) open(hdr *wire.Header, data []byte) ([]byte, error) {
+	hdrLen := hdr.ParsedLen()
+	if protocol.ByteCount(len(data)) < hdrLen+4+16 {
+		return nil, fmt.Errorf("packet too small. Expected at least 20 bytes after the header, got %d", protocol.ByteCount(len(data))-hdrLen)
+	}
+	// The packet number can be up to 4 bytes long, but we won't know the length until we decrypt it.
+	origPNBytes := make([]byte, 4)
+	copy(origPNBytes, data[hdrLen:hdrLen+4])
+	r.opener.DecryptHeader(data[hdrLen+4:hdrLen+4+16], &data[0], data[hdrLen:hdrLen+4])
+	extHdr, err := hdr.ParseExtended(bytes.NewReader(data), hdr.Version)
+	if err != nil && err != wire.ErrInvalidReservedBits {
+		return nil, err
+	}
+	if extHdr.PacketNumberLen != protocol.PacketNumberLen4 {
+		copy(data[extHdr.ParsedLen():hdrLen+4], origPNBytes[int(extHdr.PacketNumberLen):])
+	}
+	extHdrLen := extHdr.ParsedLen()
+	pn := r.opener.DecodePacketNumber(extHdr.PacketNumber, extHdr.PacketNumberLen)
+	return r.opener.Open(data[extHdrLen:extHdrLen], data[extHdrLen:], pn, data[:extHdrLen])
+}
+
+// parseClientHello returns the ClientHello if it was completely received.
+func (r *ClientHelloReader) parseClientHello() (*ClientHello, error) {
+	// Append all frames that are contiguous with the data received so far.
+	// Frames might overlap with data that was already received.
+	for progress := true; progress; {
+		progress = false
+		for offset, data := range r.frames {
+			received := uint64(len(r.received))
+			if offset > received {
+				continue
+			}
+			delete(r.frames, offset)
+			if end := offset + uint64(len(data)); end > received {
+				r.received = append(r.received, data[received-offset:]...)
+				progress = true
+			}
+		}
+	}
+	if len(r.received) < 4 {
+		return nil, nil
+	}
+	if r.received[0] != typeClientHello {
+		return nil, fmt.Errorf("expected a ClientHello, got handshake message type %d", r.received[0])
+	}
+	msgLen := 4 + (int(r.received[1])<<16 | int(r.received[2])<<8 | int(r.received[3]))
+	if len(r.received) < msgLen {
+		return nil, nil
+	}
+	return ParseClientHello(r.received[:msgLen])
+}
+
+// ParseClientHello parses a TLS ClientHello handshake message.
+func ParseClientHello(data []byte) (*ClientHello, error) {
+	errMalformed := errors.New("malformed ClientHello")
+	ch := &ClientHello{Raw: data}
+	s := cryptobyte.String(data)
+	var msgType uint8
+	var body cryptobyte.String
+	if !s.ReadUint8(&msgType) || msgType != typeClientHello || !s.ReadUint24LengthPrefixed(&body) || !s.Empty() {
+		return nil, errMalformed
+	}
+	var sessionID, cipherSuites, compressionMethods cryptobyte.String
+	if !body.Skip(2) || // legacy_version
+		!body.Skip(32) || // random
+		!body.ReadUint8LengthPrefixed(&sessionID) ||
+		!body.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!body.ReadUint8LengthPrefixed(&compressionMethods) {
+		return nil, errMalformed
+	}
+	for !cipherSuites.Empty() {
+		var suite uint16
+		if !cipherSuites.ReadUint16(&suite) {
+			return nil, errMalformed
+		}
+		ch.CipherSuites = append(ch.CipherSuites, suite)
+	}
+	if body.Empty() {
+		return ch, nil
+	}
+	var extensions cryptobyte.String
+	if !body.ReadUint16LengthPrefixed(&extensions) || !body.Empty() {
+		return nil, errMalformed
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return nil, errMalformed
+		}
+		ch.Extensions = append(ch.Extensions, Extension{Type: extType, Data: extData})
+		switch extType {
+		case extensionServerName:
+			var nameList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&nameList) || nameList.Empty() {
+				return nil, errMalformed
+			}
+			for !nameList.Empty() {
+				var nameType uint8
+				var name cryptobyte.String
+				if !nameList.ReadUint8(&nameType) || !nameList.ReadUint16LengthPrefixed(&name) || name.Empty() {
+					return nil, errMalformed
+				}
+				if nameType == 0 { // host_name
+					ch.ServerName = string(name)
+				}
+			}
+		case extensionALPN:
+			var protoList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&protoList) || protoList.Empty() {
+				return nil, errMalformed
+			}
+			for !protoList.Empty() {
+				var proto cryptobyte.String
+				if !protoList.ReadUint8LengthPrefixed(&proto) || proto.Empty() {
+					return nil, errMalformed
+				}
+				ch.ALPN = append(ch.ALPN, string(proto))
+			}
+		}
+	}
+	return ch, nil
+}
@@ -0,0 +1,148 @@
+package quicpacket
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	"golang.org/x/crypto/cryptobyte"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientHello", func() {
+	composeClientHello := func(serverName string, alpn []string) []byte {
+		var b cryptobyte.Builder
+		b.AddUint8(typeClientHello)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)
+			b.AddBytes(make([]byte, 32))
+			b.AddUint8LengthPrefixed(func(*cryptobyte.Builder) {})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(0x1301)
+				b.AddUint16(0x1303)
+			})
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(extensionServerName)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						b.AddUint8(0)
+						b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(serverName)) })
+					})
+				})
+				b.AddUint16(extensionALPN)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+						for _, proto := range alpn {
+							b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte(proto)) })
+						}
+					})
+				})
+				b.AddUint16(0x1337)
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("foobar")) })
+			})
+		})
+		return b.BytesOrPanic()
+	}
+
+	composeInitial := func(connID protocol.ConnectionID, pn protocol.PacketNumber, frames ...*wire.CryptoFrame) []byte {
+		sealer, _ := handshake.NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version1)
+		payload := &bytes.Buffer{}
+		for _, f := range frames {
+			Expect(f.Write(payload, protocol.Version1)).To(Succeed())
+		}
+		// pad, such that there are enough bytes for the header protection sample
+		payload.Write(make([]byte, 20))
+
+		hdr := &wire.ExtendedHeader{}
+		hdr.IsLongHeader = true
+		hdr.Type = protocol.PacketTypeInitial
+		hdr.Version = protocol.Version1
+		hdr.DestConnectionID = connID
+		hdr.SrcConnectionID = protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}
+		hdr.PacketNumber = pn
+		hdr.PacketNumberLen = protocol.PacketNumberLen2
+		hdr.Length = protocol.ByteCount(hdr.PacketNumberLen) + protocol.ByteCount(payload.Len()+sealer.Overhead())
+		buf := &bytes.Buffer{}
+		Expect(hdr.Write(buf, protocol.Version1)).To(Succeed())
+		payloadOffset := buf.Len()
+		buf.Write(payload.Bytes())
+		raw := make([]byte, buf.Len(), buf.Len()+sealer.Overhead())
+		copy(raw, buf.Bytes())
+		_ = sealer.Seal(raw[payloadOffset:payloadOffset], raw[payloadOffset:], pn, raw[:payloadOffset])
+		raw = raw[:buf.Len()+sealer.Overhead()]
+		pnOffset := payloadOffset - int(hdr.PacketNumberLen)
+		sealer.EncryptHeader(raw[pnOffset+4:pnOffset+4+16], &raw[0], raw[pnOffset:payloadOffset])
+		return raw
+	}
+
+	It("parses a ClientHello", func() {
+		ch, err := ParseClientHello(composeClientHello("quic-go.net", []string{"h3", "hq-interop"}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ch.ServerName).To(Equal("quic-go.net"))
+		Expect(ch.ALPN).To(Equal([]string{"h3", "hq-interop"}))
+		Expect(ch.CipherSuites).To(Equal([]uint16{0x1301, 0x1303}))
+		Expect(ch.Extensions).To(HaveLen(3))
+		Expect(ch.Extensions[2]).To(Equal(Extension{Type: 0x1337, Data: []byte("foobar")}))
+	})
+
+	It("errors on malformed ClientHellos", func() {
+		data := composeClientHello("quic-go.net", []string{"h3"})
+		for i := 0; i < len(data); i++ {
+			_, err := ParseClientHello(data[:i])
+			Expect(err).To(MatchError("malformed ClientHello"))
+		}
+	})
+
+	It("reads the ClientHello from a single Initial packet", func() {
+		data := composeClientHello("quic-go.net", []string{"h3"})
+		connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+		packet := composeInitial(connID, 0, &wire.CryptoFrame{Data: data})
+		orig := make([]byte, len(packet))
+		copy(orig, packet)
+		ch, err := NewClientHelloReader().AddDatagram(packet)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ch).ToNot(BeNil())
+		Expect(ch.ServerName).To(Equal("quic-go.net"))
+		Expect(ch.ALPN).To(Equal([]string{"h3"}))
+		Expect(ch.Raw).To(Equal(data))
+		// the packet is not modified
+		Expect(packet).To(Equal(orig))
+	})
+
+	It("reassembles the ClientHello from multiple Initial packets, received out of order", func() {
+		data := composeClientHello("quic-go.net", []string{"h3"})
+		connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
+		p1 := composeInitial(connID, 0, &wire.CryptoFrame{Data: data[:20]})
+		p2 := composeInitial(connID, 1, &wire.CryptoFrame{Offset: 15, Data: data[15:40]})
+		p3 := composeInitial(connID, 2, &wire.CryptoFrame{Offset: 40, Data: data[40:]})
+		r := NewClientHelloReader()
+		ch, err := r.AddDatagram(p3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ch).To(BeNil())
+		ch, err = r.AddDatagram(p1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ch).To(BeNil())
+		ch, err = r.AddDatagram(p2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ch).ToNot(BeNil())
+		Expect(ch.ServerName).To(Equal("quic-go.net"))
+	})
+
+	It("errors when the packet can't be decrypted", func() {
+		packet := composeInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, 0, &wire.CryptoFrame{Data: []byte("foobar")})
+		packet[len(packet)-1] ^= 0xff
+		_, err := NewClientHelloReader().AddDatagram(packet)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors if the first handshake message is not a ClientHello", func() {
+		packet := composeInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}, 0, &wire.CryptoFrame{Data: []byte{2, 0, 0, 1, 0}})
+		_, err := NewClientHelloReader().AddDatagram(packet)
+		Expect(err).To(MatchError("expected a ClientHello, got handshake message type 2"))
+	})
+})
@@ -0,0 +1,59 @@
+package quicpacket
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// ComposeVersionNegotiation composes a Version Negotiation packet.
+// The connection IDs are those that should be put on the packet,
+// i.e. the destination connection ID is the source connection ID of the packet that triggered the Version Negotiation.
+// A reserved version number is added to the list of versions, as described in section 6.3 of RFC 9000.
+func ComposeVersionNegotiation(destConnID, srcConnID ConnectionID, versions []VersionNumber) []byte {
+	return wire.ComposeVersionNegotiation(destConnID, srcConnID, versions)
+}
+
+// ComposeRetry composes a Retry packet, including the Retry Integrity Tag.
+// The destination connection ID is the source connection ID of the client's Initial packet,
+// and origDestConnID is the destination connection ID of the client's Initial packet.
+func ComposeRetry(version VersionNumber, destConnID, srcConnID, origDestConnID ConnectionID, token []byte) ([]byte, error) {
+	if !protocol.IsSupportedVersion(protocol.SupportedVersions, version) {
+		return nil, ErrUnsupportedVersion
+	}
+	if len(token) == 0 {
+		return nil, errors.New("a Retry packet must contain a token")
+	}
+	hdr := &wire.ExtendedHeader{}
+	hdr.IsLongHeader = true
+	hdr.Type = protocol.PacketTypeRetry
+	hdr.Version = version
+	hdr.DestConnectionID = destConnID
+	hdr.SrcConnectionID = srcConnID
+	hdr.Token = token
+
+	buf := &bytes.Buffer{}
+	if err := hdr.Write(buf, version); err != nil {
+		return nil, err
+	}
+	tag := handshake.GetRetryIntegrityTag(buf.Bytes(), origDestConnID, version)
+	buf.Write(tag[:])
+	return buf.Bytes(), nil
+}
+
+// VerifyRetryIntegrityTag checks the Retry Integrity Tag of a Retry packet.
+// origDestConnID is the destination connection ID of the client's first Initial packet.
+func VerifyRetryIntegrityTag(retry []byte, origDestConnID ConnectionID) (bool, error) {
+	version, err := ParseVersion(retry)
+	if err != nil {
+		return false, err
+	}
+	if len(retry) < 16 {
+		return false, errors.New("packet too short")
+	}
+	tag := handshake.GetRetryIntegrityTag(retry[:len(retry)-16], origDestConnID, version)
+	return bytes.Equal(tag[:], retry[len(retry)-16:]), nil
+}
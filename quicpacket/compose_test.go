@@ -0,0 +1,45 @@
+package quicpacket
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Composing packets", func() {
+	It("composes Retry packets", func() {
+		origDestConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+		data, err := ComposeRetry(
+			protocol.Version1,
+			protocol.ConnectionID{1, 2, 3, 4},
+			protocol.ConnectionID{5, 6, 7, 8},
+			origDestConnID,
+			[]byte("token"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		hdr, _, _, err := ParsePacket(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.Type).To(Equal(PacketTypeRetry))
+		Expect(hdr.Version).To(Equal(protocol.Version1))
+		Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(hdr.SrcConnectionID).To(Equal(protocol.ConnectionID{5, 6, 7, 8}))
+		Expect(hdr.Token).To(Equal([]byte("token")))
+		valid, err := VerifyRetryIntegrityTag(data, origDestConnID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(valid).To(BeTrue())
+		valid, err = VerifyRetryIntegrityTag(data, protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(valid).To(BeFalse())
+	})
+
+	It("refuses to compose Retry packets for unsupported versions", func() {
+		_, err := ComposeRetry(0x1234, protocol.ConnectionID{1}, protocol.ConnectionID{2}, protocol.ConnectionID{3}, []byte("token"))
+		Expect(err).To(MatchError(ErrUnsupportedVersion))
+	})
+
+	It("refuses to compose Retry packets without a token", func() {
+		_, err := ComposeRetry(protocol.Version1, protocol.ConnectionID{1}, protocol.ConnectionID{2}, protocol.ConnectionID{3}, nil)
+		Expect(err).To(MatchError("a Retry packet must contain a token"))
+	})
+})
@@ -0,0 +1,143 @@
+// Package quicpacket parses QUIC packet headers without removing packet protection,
+// and composes the packets that are sent without any packet protection.
+// It is intended to be used by load balancers, proxies and other middleboxes.
+package quicpacket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+type (
+	// A ConnectionID is a QUIC Connection ID.
+	ConnectionID = protocol.ConnectionID
+	// The VersionNumber is the QUIC version.
+	VersionNumber = protocol.VersionNumber
+	// The PacketType is the type of a QUIC packet.
+	PacketType = logging.PacketType
+)
+
+const (
+	// PacketTypeInitial is the packet type of an Initial packet.
+	PacketTypeInitial = logging.PacketTypeInitial
+	// PacketTypeHandshake is the packet type of a Handshake packet.
+	PacketTypeHandshake = logging.PacketTypeHandshake
+	// PacketTypeRetry is the packet type of a Retry packet.
+	PacketTypeRetry = logging.PacketTypeRetry
+	// PacketType0RTT is the packet type of a 0-RTT packet.
+	PacketType0RTT = logging.PacketType0RTT
+	// PacketTypeVersionNegotiation is the packet type of a Version Negotiation packet.
+	PacketTypeVersionNegotiation = logging.PacketTypeVersionNegotiation
+	// PacketType1RTT is the packet type of a packet with a short header.
+	PacketType1RTT = logging.PacketType1RTT
+	// PacketTypeNotDetermined is used for long header packets of an unknown version.
+	PacketTypeNotDetermined = logging.PacketTypeNotDetermined
+)
+
+// ErrUnsupportedVersion is returned when parsing a long header packet of a QUIC version
+// that is not supported by quic-go.
+// The version-independent fields of the Header are still populated.
+var ErrUnsupportedVersion = wire.ErrUnsupportedVersion
+
+// The Header is the part of the QUIC packet header that is not protected by header protection.
+type Header struct {
+	IsLongHeader bool
+	Type         PacketType
+	// Version is 0 for Version Negotiation packets and for packets with a short header.
+	Version VersionNumber
+
+	DestConnectionID ConnectionID
+	// SrcConnectionID is only set for long header packets.
+	SrcConnectionID ConnectionID
+
+	// Token is the token of an Initial or a Retry packet.
+	Token []byte
+}
+
+// IsLongHeaderPacket says if this is a packet with a long header.
+func IsLongHeaderPacket(b []byte) bool {
+	return len(b) > 0 && b[0]&0x80 > 0
+}
+
+// IsVersionNegotiationPacket says if this is a Version Negotiation packet.
+func IsVersionNegotiationPacket(b []byte) bool {
+	return wire.IsVersionNegotiationPacket(b)
+}
+
+// ParseVersion parses the version of a long header packet.
+// It doesn't check if quic-go supports this version.
+func ParseVersion(b []byte) (VersionNumber, error) {
+	if !IsLongHeaderPacket(b) {
+		return 0, errors.New("not a long header packet")
+	}
+	if len(b) < 5 {
+		return 0, errors.New("packet too short")
+	}
+	return VersionNumber(binary.BigEndian.Uint32(b[1:5])), nil
+}
+
+// ParseConnectionID parses the destination connection ID of a packet.
+// Short header packets don't encode the length of the connection ID,
+// so the length used by the receiver of the packet needs to be passed in.
+// The returned connection ID uses the data slice.
+func ParseConnectionID(data []byte, shortHeaderConnIDLen int) (ConnectionID, error) {
+	return wire.ParseConnectionID(data, shortHeaderConnIDLen)
+}
+
+// ParsePacket parses the header of the first QUIC packet in data.
+// Long header packets can be coalesced. If the packet has a long header, the
+// packet is cut according to the length field, and the coalesced packets are returned in rest.
+// If the version of a long header packet is not supported, only the version-independent
+// fields of the header are parsed, and ErrUnsupportedVersion is returned.
+// For short header packets, the length of the connection ID needs to be passed in.
+// Version Negotiation packets can't be coalesced, so the whole packet is returned, and rest is nil.
+// Use ParseVersionNegotiationPacket to parse the list of versions.
+func ParsePacket(data []byte, shortHeaderConnIDLen int) (hdr *Header, packet, rest []byte, err error) {
+	if IsVersionNegotiationPacket(data) {
+		hdr, _, err := ParseVersionNegotiationPacket(data)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return hdr, data, nil, nil
+	}
+	h, packet, rest, err := wire.ParsePacket(data, shortHeaderConnIDLen)
+	if err != nil && err != wire.ErrUnsupportedVersion {
+		return nil, nil, nil, err
+	}
+	hdr = &Header{
+		IsLongHeader:     h.IsLongHeader,
+		Version:          h.Version,
+		DestConnectionID: h.DestConnectionID,
+		SrcConnectionID:  h.SrcConnectionID,
+		Token:            h.Token,
+	}
+	if err == wire.ErrUnsupportedVersion {
+		hdr.Type = PacketTypeNotDetermined
+		return hdr, nil, nil, ErrUnsupportedVersion
+	}
+	hdr.Type = logging.PacketTypeFromHeader(h)
+	return hdr, packet, rest, nil
+}
+
+// ParseVersionNegotiationPacket parses a Version Negotiation packet.
+// It returns the header and the list of versions offered.
+func ParseVersionNegotiationPacket(data []byte) (*Header, []VersionNumber, error) {
+	if !IsVersionNegotiationPacket(data) {
+		return nil, nil, errors.New("not a Version Negotiation packet")
+	}
+	h, versions, err := wire.ParseVersionNegotiationPacket(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Header{
+		IsLongHeader:     true,
+		Type:             PacketTypeVersionNegotiation,
+		DestConnectionID: h.DestConnectionID,
+		SrcConnectionID:  h.SrcConnectionID,
+	}, versions, nil
+}
@@ -0,0 +1,121 @@
+package quicpacket
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Header", func() {
+	writeLongHeader := func(hdr *wire.ExtendedHeader) []byte {
+		hdr.IsLongHeader = true
+		hdr.PacketNumberLen = protocol.PacketNumberLen2
+		buf := &bytes.Buffer{}
+		Expect(hdr.Write(buf, hdr.Version)).To(Succeed())
+		return append(buf.Bytes(), make([]byte, int(hdr.Length)-int(hdr.PacketNumberLen))...)
+	}
+
+	It("parses the version", func() {
+		data := writeLongHeader(&wire.ExtendedHeader{Header: wire.Header{
+			Type:             protocol.PacketTypeHandshake,
+			Version:          protocol.Version1,
+			DestConnectionID: protocol.ConnectionID{1, 2, 3, 4},
+			Length:           10,
+		}})
+		Expect(IsLongHeaderPacket(data)).To(BeTrue())
+		v, err := ParseVersion(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(v).To(Equal(protocol.Version1))
+	})
+
+	It("refuses to parse the version of a short header packet", func() {
+		_, err := ParseVersion([]byte{0x40, 1, 2, 3, 4})
+		Expect(err).To(MatchError("not a long header packet"))
+	})
+
+	It("parses the connection ID of a short header packet", func() {
+		connID, err := ParseConnectionID([]byte{0x40, 1, 2, 3, 4, 5, 6}, 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+	})
+
+	It("parses an Initial packet", func() {
+		data := writeLongHeader(&wire.ExtendedHeader{Header: wire.Header{
+			Type:             protocol.PacketTypeInitial,
+			Version:          protocol.Version1,
+			DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+			SrcConnectionID:  protocol.ConnectionID{8, 7, 6, 5},
+			Token:            []byte("foobar"),
+			Length:           20,
+		}})
+		coalesced := append(data, []byte("coalesced")...)
+		hdr, packet, rest, err := ParsePacket(coalesced, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeTrue())
+		Expect(hdr.Type).To(Equal(PacketTypeInitial))
+		Expect(hdr.Version).To(Equal(protocol.Version1))
+		Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}))
+		Expect(hdr.SrcConnectionID).To(Equal(protocol.ConnectionID{8, 7, 6, 5}))
+		Expect(hdr.Token).To(Equal([]byte("foobar")))
+		Expect(packet).To(Equal(data))
+		Expect(rest).To(Equal([]byte("coalesced")))
+	})
+
+	It("parses a short header packet", func() {
+		hdr, packet, rest, err := ParsePacket([]byte{0x40, 1, 2, 3, 4, 5, 6}, 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeFalse())
+		Expect(hdr.Type).To(Equal(PacketType1RTT))
+		Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(packet).To(HaveLen(7))
+		Expect(rest).To(BeEmpty())
+	})
+
+	It("parses the invariant header of packets with unknown versions", func() {
+		data := []byte{0xc0, 0x1, 0x2, 0x3, 0x4}
+		data = append(data, 4, 1, 2, 3, 4)
+		data = append(data, 2, 5, 6)
+		data = append(data, make([]byte, 100)...)
+		hdr, _, _, err := ParsePacket(data, 0)
+		Expect(err).To(MatchError(ErrUnsupportedVersion))
+		Expect(hdr.Type).To(Equal(PacketTypeNotDetermined))
+		Expect(hdr.Version).To(Equal(protocol.VersionNumber(0x01020304)))
+		Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(hdr.SrcConnectionID).To(Equal(protocol.ConnectionID{5, 6}))
+	})
+
+	It("errors on packets that are too short", func() {
+		_, _, _, err := ParsePacket([]byte{0x40, 1, 2}, 4)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("parses Version Negotiation packets", func() {
+		data := ComposeVersionNegotiation(protocol.ConnectionID{1, 2, 3, 4}, protocol.ConnectionID{5, 6, 7, 8}, []protocol.VersionNumber{protocol.Version1})
+		Expect(IsVersionNegotiationPacket(data)).To(BeTrue())
+		hdr, versions, err := ParseVersionNegotiationPacket(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.Type).To(Equal(PacketTypeVersionNegotiation))
+		Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(hdr.SrcConnectionID).To(Equal(protocol.ConnectionID{5, 6, 7, 8}))
+		// one reserved version is added
+		Expect(versions).To(HaveLen(2))
+		Expect(versions).To(ContainElement(protocol.Version1))
+	})
+
+	It("recognizes Version Negotiation packets when parsing packets", func() {
+		data := ComposeVersionNegotiation(protocol.ConnectionID{1, 2, 3, 4}, protocol.ConnectionID{5, 6, 7, 8}, []protocol.VersionNumber{protocol.Version1})
+		hdr, packet, rest, err := ParsePacket(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeTrue())
+		Expect(hdr.Type).To(Equal(PacketTypeVersionNegotiation))
+		Expect(hdr.Version).To(BeZero())
+		Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+		Expect(hdr.SrcConnectionID).To(Equal(protocol.ConnectionID{5, 6, 7, 8}))
+		Expect(packet).To(Equal(data))
+		Expect(rest).To(BeNil())
+	})
+})
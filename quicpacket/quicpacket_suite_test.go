@@ -0,0 +1,13 @@
+package quicpacket
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuicPacket(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "QUIC Packet Suite")
+}
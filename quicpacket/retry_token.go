@@ -0,0 +1,31 @@
+package quicpacket
+
+import "github.com/lucas-clemente/quic-go/internal/handshake"
+
+// RetryTokenKeyLen is the length of the key used by the RetryTokenProtector.
+const RetryTokenKeyLen = handshake.RetryOffloadTokenKeyLen
+
+// A RetryToken is the content of a Retry token.
+type RetryToken = handshake.RetryOffloadToken
+
+// A RetryTokenProtector creates and decodes Retry tokens using a key that is shared between
+// a Retry service (for example a load balancer) and the servers behind it.
+// This allows offloading the sending of Retry packets, while the server is still able
+// to send the transport parameters that authenticate the Retry to the client.
+// The token format is modeled after the shared-state Retry token of draft-ietf-quic-retry-offload.
+// Servers accept these tokens if the key is configured as quic.Config.RetryOffloadKey.
+type RetryTokenProtector = handshake.RetryOffloadTokenProtector
+
+// NewRetryTokenProtector creates a new RetryTokenProtector.
+// The key needs to be RetryTokenKeyLen bytes long.
+// The key sequence number can be used to rotate keys, it must be smaller than 128.
+func NewRetryTokenProtector(key []byte, keySequence uint8) (*RetryTokenProtector, error) {
+	return handshake.NewRetryOffloadTokenProtector(key, keySequence)
+}
+
+// IsRetryToken says if a token (potentially) is a Retry token issued by a RetryTokenProtector.
+// Tokens issued by quic-go servers can always be distinguished from these tokens.
+// A Retry service should validate these tokens, and forward all other tokens to the server.
+func IsRetryToken(token []byte) bool {
+	return handshake.IsRetryOffloadToken(token)
+}
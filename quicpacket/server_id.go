@@ -0,0 +1,62 @@
+package quicpacket
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// MaxConfigID is the largest config ID that can be encoded in a routable connection ID.
+// The codepoint 0b111 is reserved for unroutable connection IDs.
+const MaxConfigID = 6
+
+// A ServerIDGenerator generates connection IDs that encode the ID of the server.
+// This allows a load balancer to route packets to the server without keeping per-connection state.
+// The connection IDs use the plaintext format of QUIC-LB (draft-ietf-quic-load-balancers):
+// The first byte contains the config ID in the 3 most significant bits, and the length of
+// the connection ID (minus 1) in the 5 least significant bits.
+// It is followed by the server ID. The remaining bytes are random.
+// It implements the quic.ConnectionIDGenerator interface.
+type ServerIDGenerator struct {
+	ServerID  []byte
+	ConfigID  uint8
+	ConnIDLen int
+}
+
+// GenerateConnectionID generates a new connection ID.
+func (g *ServerIDGenerator) GenerateConnectionID() (ConnectionID, error) {
+	if g.ConfigID > MaxConfigID {
+		return nil, fmt.Errorf("invalid config ID: %d", g.ConfigID)
+	}
+	if len(g.ServerID) == 0 || 1+len(g.ServerID) > g.ConnIDLen {
+		return nil, errors.New("server ID doesn't fit into the connection ID")
+	}
+	c := make([]byte, g.ConnIDLen)
+	c[0] = g.ConfigID<<5 | uint8(g.ConnIDLen-1)&0x1f
+	copy(c[1:], g.ServerID)
+	if _, err := rand.Read(c[1+len(g.ServerID):]); err != nil {
+		return nil, err
+	}
+	return ConnectionID(c), nil
+}
+
+// ConnectionIDLen returns the length of the connection IDs generated.
+func (g *ServerIDGenerator) ConnectionIDLen() int {
+	return g.ConnIDLen
+}
+
+// ParseServerID parses the server ID from a connection ID generated by a ServerIDGenerator.
+// It returns an error if the connection ID was not generated using the same config ID,
+// in which case the packet has to be routed by other means.
+func ParseServerID(connID ConnectionID, configID uint8, serverIDLen int) ([]byte, error) {
+	if connID.Len() < 1+serverIDLen {
+		return nil, errors.New("connection ID too short")
+	}
+	if connID[0]>>5 != configID {
+		return nil, errors.New("mismatching config ID")
+	}
+	if int(connID[0]&0x1f) != connID.Len()-1 {
+		return nil, errors.New("mismatching connection ID length")
+	}
+	return connID[1 : 1+serverIDLen], nil
+}
@@ -0,0 +1,50 @@
+package quicpacket
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server ID connection IDs", func() {
+	It("generates connection IDs that encode the server ID", func() {
+		g := &ServerIDGenerator{ServerID: []byte{0xca, 0xfe}, ConfigID: 3, ConnIDLen: 8}
+		Expect(g.ConnectionIDLen()).To(Equal(8))
+		c1, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1).To(HaveLen(8))
+		Expect(c1[0]).To(Equal(uint8(3<<5 | 7)))
+		c2, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c2).ToNot(Equal(c1))
+		serverID, err := ParseServerID(c1, 3, 2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverID).To(Equal([]byte{0xca, 0xfe}))
+	})
+
+	It("refuses to generate connection IDs if the server ID is too long", func() {
+		g := &ServerIDGenerator{ServerID: []byte{1, 2, 3, 4}, ConnIDLen: 4}
+		_, err := g.GenerateConnectionID()
+		Expect(err).To(MatchError("server ID doesn't fit into the connection ID"))
+	})
+
+	It("refuses to generate connection IDs with an invalid config ID", func() {
+		g := &ServerIDGenerator{ServerID: []byte{1}, ConfigID: 7, ConnIDLen: 4}
+		_, err := g.GenerateConnectionID()
+		Expect(err).To(MatchError("invalid config ID: 7"))
+	})
+
+	It("errors when parsing connection IDs with a different config ID", func() {
+		g := &ServerIDGenerator{ServerID: []byte{1}, ConfigID: 1, ConnIDLen: 8}
+		c, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ParseServerID(c, 2, 1)
+		Expect(err).To(MatchError("mismatching config ID"))
+	})
+
+	It("errors when parsing connection IDs with a mismatching length", func() {
+		_, err := ParseServerID(ConnectionID{0x4, 1, 2, 3}, 0, 1)
+		Expect(err).To(MatchError("mismatching connection ID length"))
+		_, err = ParseServerID(ConnectionID{0x1, 1}, 0, 2)
+		Expect(err).To(MatchError("connection ID too short"))
+	})
+})
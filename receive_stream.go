@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sync"
@@ -39,6 +40,7 @@ type receiveStream struct {
 
 	closeForShutdownErr error
 	cancelReadErr       error
+	cancelReadErrorCode qerr.StreamErrorCode
 	resetRemotelyErr    *StreamError
 
 	closedForShutdown bool // set when CloseForShutdown() is called
@@ -50,6 +52,9 @@ type receiveStream struct {
 	readOnce chan struct{} // cap: 1, to protect against concurrent use of Read
 	deadline time.Time
 
+	ctx       context.Context
+	ctxCancel func(cause error)
+
 	flowController flowcontrol.StreamFlowController
 	version        protocol.VersionNumber
 }
@@ -65,7 +70,7 @@ func newReceiveStream(
 	flowController flowcontrol.StreamFlowController,
 	version protocol.VersionNumber,
 ) *receiveStream {
-	return &receiveStream{
+	s := &receiveStream{
 		streamID:       streamID,
 		sender:         sender,
 		flowController: flowController,
@@ -75,6 +80,8 @@ func newReceiveStream(
 		finalOffset:    protocol.MaxByteCount,
 		version:        version,
 	}
+	s.ctx, s.ctxCancel = utils.WithCancelCause(context.Background())
+	return s
 }
 
 func (s *receiveStream) StreamID() protocol.StreamID {
@@ -219,6 +226,8 @@ func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* c
 	}
 	s.canceledRead = true
 	s.cancelReadErr = fmt.Errorf("Read on stream %d canceled with error code %d", s.streamID, errorCode)
+	s.cancelReadErrorCode = errorCode
+	s.ctxCancel(s.cancelReadErr)
 	s.signalRead()
 	s.sender.queueControlFrame(&wire.StopSendingFrame{
 		StreamID:  s.streamID,
@@ -291,6 +300,7 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 		StreamID:  s.streamID,
 		ErrorCode: frame.ErrorCode,
 	}
+	s.ctxCancel(s.resetRemotelyErr)
 	s.signalRead()
 	return newlyRcvdFinalOffset, nil
 }
@@ -299,6 +309,10 @@ func (s *receiveStream) CloseRemote(offset protocol.ByteCount) {
 	s.handleStreamFrame(&wire.StreamFrame{Fin: true, Offset: offset})
 }
 
+func (s *receiveStream) ReadContext() context.Context {
+	return s.ctx
+}
+
 func (s *receiveStream) SetReadDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
@@ -314,10 +328,29 @@ func (s *receiveStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	s.ctxCancel(err)
 	s.mutex.Unlock()
 	s.signalRead()
 }
 
+// reopenFor0RTTReplay is called after 0-RTT was rejected, when the stream is replayed using a new stream ID.
+// The peer can't have sent any data on this stream yet.
+func (s *receiveStream) reopenFor0RTTReplay(id protocol.StreamID, flowController flowcontrol.StreamFlowController) {
+	s.mutex.Lock()
+	s.streamID = id
+	s.flowController = flowController
+	canceledRead := s.canceledRead
+	errorCode := s.cancelReadErrorCode
+	s.mutex.Unlock()
+
+	if canceledRead {
+		s.sender.queueControlFrame(&wire.StopSendingFrame{
+			StreamID:  id,
+			ErrorCode: errorCode,
+		})
+	}
+}
+
 func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
 	return s.flowController.GetWindowUpdate()
 }
@@ -17,18 +17,32 @@ type sconn struct {
 
 	remoteAddr net.Addr
 	info       *packetInfo
+	dscp       uint8
 	oob        []byte
 }
 
 var _ sendConn = &sconn{}
 
-func newSendConn(c rawConn, remote net.Addr, info *packetInfo) sendConn {
-	return &sconn{
+func newSendConn(c rawConn, remote net.Addr, info *packetInfo, dscp uint8) sendConn {
+	sc := &sconn{
 		rawConn:    c,
 		remoteAddr: remote,
 		info:       info,
-		oob:        info.OOB(),
+		dscp:       dscp,
 	}
+	sc.oob = sc.buildOOB(remote)
+	return sc
+}
+
+func (c *sconn) buildOOB(remote net.Addr) []byte {
+	oob := c.info.OOB()
+	if c.dscp == 0 {
+		return oob
+	}
+	if pc, ok := c.rawConn.(OOBCapablePacketConn); ok {
+		oob = append(oob, trafficClassOOB(pc, c.dscp, remote)...)
+	}
+	return oob
 }
 
 func (c *sconn) Write(p []byte) error {
@@ -56,15 +70,35 @@ type spconn struct {
 	net.PacketConn
 
 	remoteAddr net.Addr
+	dscp       uint8
+	// Only set if packets are marked with a DSCP value.
+	// Packets are then sent using WriteMsgUDP.
+	oob []byte
 }
 
 var _ sendConn = &spconn{}
 
-func newSendPconn(c net.PacketConn, remote net.Addr) sendConn {
-	return &spconn{PacketConn: c, remoteAddr: remote}
+func newSendPconn(c net.PacketConn, remote net.Addr, dscp uint8) sendConn {
+	sc := &spconn{PacketConn: c, remoteAddr: remote, dscp: dscp}
+	sc.oob = sc.buildOOB(remote)
+	return sc
+}
+
+func (c *spconn) buildOOB(remote net.Addr) []byte {
+	if c.dscp == 0 {
+		return nil
+	}
+	if pc, ok := c.PacketConn.(OOBCapablePacketConn); ok {
+		return trafficClassOOB(pc, c.dscp, remote)
+	}
+	return nil
 }
 
 func (c *spconn) Write(p []byte) error {
+	if c.oob != nil {
+		_, _, err := c.PacketConn.(OOBCapablePacketConn).WriteMsgUDP(p, c.oob, c.remoteAddr.(*net.UDPAddr))
+		return err
+	}
 	_, err := c.WriteTo(p, c.remoteAddr)
 	return err
 }
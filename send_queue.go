@@ -37,6 +37,7 @@ func (h *sendQueue) Send(p *packetBuffer) {
 	select {
 	case h.queue <- p:
 	case <-h.runStopped:
+		p.Release()
 	default:
 		panic("sendQueue.Send would have blocked")
 	}
@@ -69,6 +70,7 @@ func (h *sendQueue) Run() error {
 				// 2. Path MTU discovery,and
 				// 3. Eventual detection of loss PingFrame.
 				if !isMsgSizeErr(err) {
+					p.Release()
 					return err
 				}
 			}
@@ -86,3 +88,28 @@ func (h *sendQueue) Close() {
 	// wait until the run loop returned
 	<-h.runStopped
 }
+
+// The directSender writes packets on the goroutine that calls Send.
+// It is used for connections driven by an event loop, which don't run a send goroutine.
+type directSender struct {
+	conn    sendConn
+	onError func(error)
+}
+
+var _ sender = &directSender{}
+
+func newDirectSender(conn sendConn, onError func(error)) sender {
+	return &directSender{conn: conn, onError: onError}
+}
+
+func (h *directSender) Send(p *packetBuffer) {
+	if err := h.conn.Write(p.Data); err != nil && !isMsgSizeErr(err) {
+		h.onError(err)
+	}
+	p.Release()
+}
+
+func (h *directSender) Run() error                 { return nil }
+func (h *directSender) WouldBlock() bool           { return false }
+func (h *directSender) Available() <-chan struct{} { return nil }
+func (h *directSender) Close()                     {}
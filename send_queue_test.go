@@ -124,3 +124,33 @@ var _ = Describe("Send Queue", func() {
 		Eventually(closed).Should(BeClosed())
 	})
 })
+
+var _ = Describe("Direct Sender", func() {
+	var (
+		q       sender
+		c       *MockSendConn
+		errChan chan error
+	)
+
+	BeforeEach(func() {
+		c = NewMockSendConn(mockCtrl)
+		errChan = make(chan error, 1)
+		q = newDirectSender(c, func(err error) { errChan <- err })
+	})
+
+	It("sends a packet right away", func() {
+		c.EXPECT().Write([]byte("foobar"))
+		buf := getPacketBuffer()
+		buf.Data = append(buf.Data[:0], []byte("foobar")...)
+		q.Send(buf)
+		Expect(q.WouldBlock()).To(BeFalse())
+		Expect(errChan).ToNot(Receive())
+	})
+
+	It("reports write errors", func() {
+		testErr := errors.New("test error")
+		c.EXPECT().Write(gomock.Any()).Return(testErr)
+		q.Send(getPacketBuffer())
+		Expect(errChan).To(Receive(Equal(testErr)))
+	})
+})
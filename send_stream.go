@@ -21,6 +21,8 @@ type sendStreamI interface {
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	closeForShutdown(error)
 	updateSendWindow(protocol.ByteCount)
+	drop0RTTReplayData()
+	reopenFor0RTTReplay(protocol.StreamID, flowcontrol.StreamFlowController)
 }
 
 type sendStream struct {
@@ -30,15 +32,16 @@ type sendStream struct {
 	retransmissionQueue  []*wire.StreamFrame
 
 	ctx       context.Context
-	ctxCancel context.CancelFunc
+	ctxCancel func(cause error)
 
 	streamID protocol.StreamID
 	sender   streamSender
 
 	writeOffset protocol.ByteCount
 
-	cancelWriteErr      error
-	closeForShutdownErr error
+	cancelWriteErr       error
+	cancelWriteErrorCode qerr.StreamErrorCode
+	closeForShutdownErr  error
 
 	closedForShutdown bool // set when CloseForShutdown() is called
 	finishedWriting   bool // set once Close() is called
@@ -47,7 +50,19 @@ type sendStream struct {
 	completed         bool // set when this stream has been reported to the streamSender as completed
 
 	dataForWriting []byte // during a Write() call, this slice is the part of p that still needs to be sent out
-	nextFrame      *wire.StreamFrame
+	// sendBuffer holds data that was written, but not yet sent out, in STREAM frames of at most one packet.
+	// Write returns as soon as the rest of p fits into the send buffer.
+	sendBuffer     []*wire.StreamFrame
+	sendBufferLen  protocol.ByteCount // the number of bytes in the sendBuffer
+	sendBufferSize protocol.ByteCount
+	coalesceWrites bool // see Config.CoalesceStreamWrites
+	flushing       bool // set while a Flush() call is waiting for the sendBuffer to be sent out
+
+	// If 0-RTT data might need to be replayed, all data sent is recorded, until the handshake completes.
+	record0RTTData bool
+	zeroRTTData    []byte
+	// replayData is the data that is sent again after 0-RTT was rejected, before sending any new data.
+	replayData []byte
 
 	writeChan chan struct{}
 	writeOnce chan struct{}
@@ -75,13 +90,17 @@ func newSendStream(
 		flowController: flowController,
 		writeChan:      make(chan struct{}, 1),
 		writeOnce:      make(chan struct{}, 1), // cap: 1, to protect against concurrent use of Write
+		sendBufferSize: protocol.DefaultStreamSendBufferSize,
 		version:        version,
 	}
-	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	s.ctx, s.ctxCancel = utils.WithCancelCause(context.Background())
 	return s
 }
 
 func (s *sendStream) StreamID() protocol.StreamID {
+	// The stream ID changes when the stream is reopened after 0-RTT was rejected.
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	return s.streamID // same for receiveStream and sendStream
 }
 
@@ -121,25 +140,13 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	for {
 		var copied bool
 		var deadline time.Time
-		// As soon as dataForWriting becomes smaller than a certain size x, we copy all the data to a STREAM frame (s.nextFrame),
+		// As soon as dataForWriting fits into the send buffer, we copy all the data to STREAM frames (s.sendBuffer),
 		// which can the be popped the next time we assemble a packet.
-		// This allows us to return Write() when all data but x bytes have been sent out.
+		// This allows us to return Write() when all data but sendBufferSize bytes have been sent out.
 		// When the user now calls Close(), this is much more likely to happen before we popped that last STREAM frame,
 		// allowing us to set the FIN bit on that frame (instead of sending an empty STREAM frame with FIN).
 		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 {
-			if s.nextFrame == nil {
-				f := wire.GetStreamFrame()
-				f.Offset = s.writeOffset
-				f.StreamID = s.streamID
-				f.DataLenPresent = true
-				f.Data = f.Data[:len(s.dataForWriting)]
-				copy(f.Data, s.dataForWriting)
-				s.nextFrame = f
-			} else {
-				l := len(s.nextFrame.Data)
-				s.nextFrame.Data = s.nextFrame.Data[:l+len(s.dataForWriting)]
-				copy(s.nextFrame.Data[l:], s.dataForWriting)
-			}
+			s.bufferData(s.dataForWriting)
 			s.dataForWriting = nil
 			bytesWritten = len(p)
 			copied = true
@@ -162,9 +169,10 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			}
 		}
 
+		streamID := s.streamID
 		s.mutex.Unlock()
 		if !notifiedSender {
-			s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+			s.sender.onHasStreamData(streamID) // must be called without holding the mutex
 			notifiedSender = true
 		}
 		if copied {
@@ -195,11 +203,88 @@ func (s *sendStream) Write(p []byte) (int, error) {
 }
 
 func (s *sendStream) canBufferStreamFrame() bool {
-	var l protocol.ByteCount
-	if s.nextFrame != nil {
-		l = s.nextFrame.DataLen()
+	return s.sendBufferLen+protocol.ByteCount(len(s.dataForWriting)) <= s.sendBufferSize
+}
+
+// bufferData copies data to the send buffer.
+// The last STREAM frame in the send buffer is filled up before a new frame is allocated.
+// Stream ID and offset of the frames are set when they are popped.
+func (s *sendStream) bufferData(data []byte) {
+	s.sendBufferLen += protocol.ByteCount(len(data))
+	for len(data) > 0 {
+		var f *wire.StreamFrame
+		if l := len(s.sendBuffer); l > 0 && len(s.sendBuffer[l-1].Data) < cap(s.sendBuffer[l-1].Data) {
+			f = s.sendBuffer[l-1]
+		} else {
+			f = wire.GetStreamFrame()
+			f.DataLenPresent = true
+			f.Data = f.Data[:0]
+			s.sendBuffer = append(s.sendBuffer, f)
+		}
+		l := len(f.Data)
+		n := utils.Min(len(data), cap(f.Data)-l)
+		f.Data = f.Data[:l+n]
+		copy(f.Data[l:], data[:n])
+		data = data[n:]
+	}
+}
+
+// Flush blocks until all buffered data has been packed into STREAM frames.
+func (s *sendStream) Flush() error {
+	// Flush must not be used concurrently with Write.
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.sendBuffer) == 0 {
+		return nil
+	}
+	s.flushing = true
+	defer func() { s.flushing = false }()
+
+	var (
+		deadlineTimer  *utils.Timer
+		notifiedSender bool
+	)
+	for len(s.sendBuffer) > 0 {
+		if s.closeForShutdownErr != nil {
+			return s.closeForShutdownErr
+		}
+		if s.canceledWrite {
+			return s.cancelWriteErr
+		}
+		deadline := s.deadline
+		if !deadline.IsZero() {
+			if !time.Now().Before(deadline) {
+				return errDeadline
+			}
+			if deadlineTimer == nil {
+				deadlineTimer = utils.NewTimer()
+				defer deadlineTimer.Stop()
+			}
+			deadlineTimer.Reset(deadline)
+		}
+
+		streamID := s.streamID
+		s.mutex.Unlock()
+		if !notifiedSender {
+			s.sender.onHasStreamData(streamID) // must be called without holding the mutex
+			notifiedSender = true
+		}
+		if deadline.IsZero() {
+			<-s.writeChan
+		} else {
+			select {
+			case <-s.writeChan:
+			case <-deadlineTimer.Chan():
+				deadlineTimer.SetRead()
+			}
+		}
+		s.mutex.Lock()
 	}
-	return l+protocol.ByteCount(len(s.dataForWriting)) <= protocol.MaxPacketBufferSize
+	return nil
 }
 
 // popStreamFrame returns the next STREAM frame that is supposed to be sent on this stream
@@ -235,7 +320,7 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		}
 	}
 
-	if len(s.dataForWriting) == 0 && s.nextFrame == nil {
+	if len(s.dataForWriting) == 0 && len(s.sendBuffer) == 0 && s.replayData == nil {
 		if s.finishedWriting && !s.finSent {
 			s.finSent = true
 			return &wire.StreamFrame{
@@ -248,6 +333,10 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		return nil, false
 	}
 
+	if s.shouldDelaySending(maxBytes) {
+		return nil, false
+	}
+
 	sendWindow := s.flowController.SendWindowSize()
 	if sendWindow == 0 {
 		if isBlocked, offset := s.flowController.IsNewlyBlocked(); isBlocked {
@@ -261,11 +350,17 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 	}
 
 	f, hasMoreData := s.popNewStreamFrame(maxBytes, sendWindow)
+	if f == nil {
+		return nil, hasMoreData
+	}
 	if dataLen := f.DataLen(); dataLen > 0 {
 		s.writeOffset += f.DataLen()
 		s.flowController.AddBytesSent(f.DataLen())
+		if s.record0RTTData {
+			s.zeroRTTData = append(s.zeroRTTData, f.Data...)
+		}
 	}
-	f.Fin = s.finishedWriting && s.dataForWriting == nil && s.nextFrame == nil && !s.finSent
+	f.Fin = s.finishedWriting && s.dataForWriting == nil && len(s.sendBuffer) == 0 && s.replayData == nil && !s.finSent
 	if f.Fin {
 		s.finSent = true
 	}
@@ -273,23 +368,11 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 }
 
 func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount) (*wire.StreamFrame, bool) {
-	if s.nextFrame != nil {
-		nextFrame := s.nextFrame
-		s.nextFrame = nil
-
-		maxDataLen := utils.MinByteCount(sendWindow, nextFrame.MaxDataLen(maxBytes, s.version))
-		if nextFrame.DataLen() > maxDataLen {
-			s.nextFrame = wire.GetStreamFrame()
-			s.nextFrame.StreamID = s.streamID
-			s.nextFrame.Offset = s.writeOffset + maxDataLen
-			s.nextFrame.Data = s.nextFrame.Data[:nextFrame.DataLen()-maxDataLen]
-			s.nextFrame.DataLenPresent = true
-			copy(s.nextFrame.Data, nextFrame.Data[maxDataLen:])
-			nextFrame.Data = nextFrame.Data[:maxDataLen]
-		} else {
-			s.signalWrite()
-		}
-		return nextFrame, s.nextFrame != nil || s.dataForWriting != nil
+	if s.replayData != nil {
+		return s.popReplayStreamFrame(maxBytes, sendWindow)
+	}
+	if len(s.sendBuffer) > 0 {
+		return s.popBufferedStreamFrame(maxBytes, sendWindow)
 	}
 
 	f := wire.GetStreamFrame()
@@ -307,14 +390,74 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 	return f, hasMoreData
 }
 
+func (s *sendStream) popBufferedStreamFrame(maxBytes, sendWindow protocol.ByteCount) (*wire.StreamFrame, bool) {
+	f := s.sendBuffer[0]
+	f.StreamID = s.streamID
+	f.Offset = s.writeOffset
+	f.Fin = false
+	maxDataLen := utils.MinByteCount(sendWindow, f.MaxDataLen(maxBytes, s.version))
+	if maxDataLen == 0 { // a STREAM frame must have at least one byte of data
+		return nil, true
+	}
+	if f.DataLen() > maxDataLen {
+		next := wire.GetStreamFrame()
+		next.DataLenPresent = true
+		next.Data = next.Data[:f.DataLen()-maxDataLen]
+		copy(next.Data, f.Data[maxDataLen:])
+		f.Data = f.Data[:maxDataLen]
+		s.sendBuffer[0] = next
+	} else {
+		s.sendBuffer[0] = nil
+		s.sendBuffer = s.sendBuffer[1:]
+	}
+	s.sendBufferLen -= f.DataLen()
+	// Write (and Flush) might be waiting for space in the send buffer.
+	s.signalWrite()
+	return f, len(s.sendBuffer) > 0 || s.dataForWriting != nil
+}
+
+// shouldDelaySending implements the coalescing of small writes (see Config.CoalesceStreamWrites).
+// Similar to Nagle's algorithm, buffered data that doesn't fill a STREAM frame is held back
+// as long as previously sent data hasn't been acknowledged.
+// It is sent when that data is acknowledged (see frameAcked), or when Flush or Close is called.
+func (s *sendStream) shouldDelaySending(maxBytes protocol.ByteCount) bool {
+	if !s.coalesceWrites || s.flushing || s.finishedWriting || s.numOutstandingFrames == 0 ||
+		s.dataForWriting != nil || s.replayData != nil {
+		return false
+	}
+	f := &wire.StreamFrame{StreamID: s.streamID, Offset: s.writeOffset, DataLenPresent: true}
+	return s.sendBufferLen < f.MaxDataLen(maxBytes, s.version)
+}
+
+func (s *sendStream) popReplayStreamFrame(maxBytes, sendWindow protocol.ByteCount) (*wire.StreamFrame, bool) {
+	f := wire.GetStreamFrame()
+	f.Fin = false
+	f.StreamID = s.streamID
+	f.Offset = s.writeOffset
+	f.DataLenPresent = true
+	maxDataLen := utils.MinByteCount(sendWindow, f.MaxDataLen(maxBytes, s.version))
+	if maxDataLen == 0 { // a STREAM frame must have at least one byte of data
+		f.PutBack()
+		return nil, true
+	}
+	dataLen := utils.MinByteCount(maxDataLen, protocol.ByteCount(len(s.replayData)))
+	f.Data = f.Data[:dataLen]
+	copy(f.Data, s.replayData)
+	s.replayData = s.replayData[dataLen:]
+	if len(s.replayData) == 0 {
+		s.replayData = nil
+	}
+	return f, s.replayData != nil || len(s.sendBuffer) > 0 || s.dataForWriting != nil || s.finishedWriting
+}
+
 func (s *sendStream) popNewStreamFrameWithoutBuffer(f *wire.StreamFrame, maxBytes, sendWindow protocol.ByteCount) bool {
 	maxDataLen := f.MaxDataLen(maxBytes, s.version)
 	if maxDataLen == 0 { // a STREAM frame must have at least one byte of data
-		return s.dataForWriting != nil || s.nextFrame != nil || s.finishedWriting
+		return s.dataForWriting != nil || len(s.sendBuffer) > 0 || s.finishedWriting
 	}
 	s.getDataForWriting(f, utils.MinByteCount(maxDataLen, sendWindow))
 
-	return s.dataForWriting != nil || s.nextFrame != nil || s.finishedWriting
+	return s.dataForWriting != nil || len(s.sendBuffer) > 0 || s.finishedWriting
 }
 
 func (s *sendStream) maybeGetRetransmission(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more retransmissions */) {
@@ -363,11 +506,16 @@ func (s *sendStream) frameAcked(f wire.Frame) {
 		panic("numOutStandingFrames negative")
 	}
 	newlyCompleted := s.isNewlyCompleted()
+	// When coalescing writes, buffered data is held back until all data sent has been acknowledged.
+	sendBuffered := s.coalesceWrites && s.numOutstandingFrames == 0 && len(s.sendBuffer) > 0
 	s.mutex.Unlock()
 
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
+	if sendBuffered {
+		s.sender.onHasStreamData(s.streamID)
+	}
 }
 
 func (s *sendStream) isNewlyCompleted() bool {
@@ -407,16 +555,17 @@ func (s *sendStream) Close() error {
 		s.mutex.Unlock()
 		return fmt.Errorf("close called for canceled stream %d", s.streamID)
 	}
-	s.ctxCancel()
+	s.ctxCancel(nil)
 	s.finishedWriting = true
+	streamID := s.streamID
 	s.mutex.Unlock()
 
-	s.sender.onHasStreamData(s.streamID) // need to send the FIN, must be called without holding the mutex
+	s.sender.onHasStreamData(streamID) // need to send the FIN, must be called without holding the mutex
 	return nil
 }
 
 func (s *sendStream) CancelWrite(errorCode StreamErrorCode) {
-	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.StreamID(), errorCode))
 }
 
 // must be called after locking the mutex
@@ -426,28 +575,32 @@ func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr er
 		s.mutex.Unlock()
 		return
 	}
-	s.ctxCancel()
+	s.ctxCancel(writeErr)
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
+	s.cancelWriteErrorCode = errorCode
+	s.replayData = nil
 	s.numOutstandingFrames = 0
 	s.retransmissionQueue = nil
 	newlyCompleted := s.isNewlyCompleted()
+	streamID := s.streamID
+	finalSize := s.writeOffset
 	s.mutex.Unlock()
 
 	s.signalWrite()
 	s.sender.queueControlFrame(&wire.ResetStreamFrame{
-		StreamID:  s.streamID,
-		FinalSize: s.writeOffset,
+		StreamID:  streamID,
+		FinalSize: finalSize,
 		ErrorCode: errorCode,
 	})
 	if newlyCompleted {
-		s.sender.onStreamCompleted(s.streamID)
+		s.sender.onStreamCompleted(streamID)
 	}
 }
 
 func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
 	s.mutex.Lock()
-	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
+	hasStreamData := s.dataForWriting != nil || len(s.sendBuffer) > 0 || s.replayData != nil
 	s.mutex.Unlock()
 
 	s.flowController.UpdateSendWindow(limit)
@@ -475,12 +628,77 @@ func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+// setSendBuffer configures the send buffer, see Config.StreamSendBufferSize and Config.CoalesceStreamWrites.
+func (s *sendStream) setSendBuffer(size protocol.ByteCount, coalesceWrites bool) {
+	s.mutex.Lock()
+	s.sendBufferSize = size
+	s.coalesceWrites = coalesceWrites
+	s.mutex.Unlock()
+}
+
+// enable0RTTRecording makes the stream keep all data sent, such that it can be replayed if 0-RTT is rejected.
+func (s *sendStream) enable0RTTRecording() {
+	s.mutex.Lock()
+	s.record0RTTData = true
+	s.mutex.Unlock()
+}
+
+func (s *sendStream) drop0RTTReplayData() {
+	s.mutex.Lock()
+	s.record0RTTData = false
+	s.zeroRTTData = nil
+	s.mutex.Unlock()
+}
+
+// reopenFor0RTTReplay is called after 0-RTT was rejected.
+// All data that was written to the stream so far is sent again, using the new stream ID.
+func (s *sendStream) reopenFor0RTTReplay(id protocol.StreamID, flowController flowcontrol.StreamFlowController) {
+	s.mutex.Lock()
+	s.streamID = id
+	s.flowController = flowController
+	// None of the packets sent in 0-RTT will ever be acknowledged.
+	s.numOutstandingFrames = 0
+	for _, f := range s.retransmissionQueue {
+		f.PutBack()
+	}
+	s.retransmissionQueue = nil
+	s.writeOffset = 0
+	s.finSent = false
+	s.replayData = s.zeroRTTData
+	for _, f := range s.sendBuffer {
+		s.replayData = append(s.replayData, f.Data...)
+		f.PutBack()
+	}
+	s.sendBuffer = nil
+	s.sendBufferLen = 0
+	s.record0RTTData = false
+	s.zeroRTTData = nil
+	canceledWrite := s.canceledWrite
+	errorCode := s.cancelWriteErrorCode
+	if canceledWrite {
+		s.replayData = nil
+	}
+	hasData := s.replayData != nil || s.dataForWriting != nil || s.finishedWriting
+	s.mutex.Unlock()
+
+	if canceledWrite {
+		s.sender.queueControlFrame(&wire.ResetStreamFrame{
+			StreamID:  id,
+			ErrorCode: errorCode,
+		})
+		return
+	}
+	if hasData {
+		s.sender.onHasStreamData(id)
+	}
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Write unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
 func (s *sendStream) closeForShutdown(err error) {
 	s.mutex.Lock()
-	s.ctxCancel()
+	s.ctxCancel(err)
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
 	s.mutex.Unlock()
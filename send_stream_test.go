@@ -49,7 +49,7 @@ var _ = Describe("Send Stream", func() {
 	waitForWrite := func() {
 		EventuallyWithOffset(0, func() bool {
 			str.mutex.Lock()
-			hasData := str.dataForWriting != nil || str.nextFrame != nil
+			hasData := str.dataForWriting != nil || len(str.sendBuffer) > 0
 			str.mutex.Unlock()
 			return hasData
 		}).Should(BeTrue())
@@ -649,6 +649,205 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("send buffer", func() {
+		It("returns Write as soon as the data fits into the send buffer", func() {
+			str.setSendBuffer(10000, false)
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err := strWithTimeout.Write(getData(5000))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(5000))
+			Expect(str.sendBufferLen).To(Equal(protocol.ByteCount(5000)))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			var totalBytesSent protocol.ByteCount
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).Do(func(l protocol.ByteCount) { totalBytesSent += l }).AnyTimes()
+			for {
+				frame, hasMoreData := str.popStreamFrame(1100)
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.StreamID).To(Equal(streamID))
+				Expect(f.Length(protocol.VersionWhatever)).To(BeNumerically("<=", 1100))
+				Expect(f.Data).To(Equal(getDataAtOffset(f.Offset, f.DataLen())))
+				if !hasMoreData {
+					break
+				}
+			}
+			Expect(totalBytesSent).To(Equal(protocol.ByteCount(5000)))
+			Expect(str.sendBufferLen).To(BeZero())
+		})
+
+		It("blocks Write until the data fits into the send buffer", func() {
+			str.setSendBuffer(3000, false)
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := strWithTimeout.Write(getData(2000))
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				n, err := str.Write(getDataAtOffset(2000, 2000))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(2000))
+			}()
+			waitForWrite()
+			Consistently(done).ShouldNot(BeClosed())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			frame, hasMoreData := str.popStreamFrame(1200)
+			Expect(hasMoreData).To(BeTrue())
+			Expect(frame.Frame.(*wire.StreamFrame).Offset).To(BeZero())
+			Eventually(done).Should(BeClosed())
+			var data []byte
+			data = append(data, frame.Frame.(*wire.StreamFrame).Data...)
+			for hasMoreData {
+				frame, hasMoreData = str.popStreamFrame(1200)
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Offset).To(Equal(protocol.ByteCount(len(data))))
+				data = append(data, f.Data...)
+			}
+			Expect(data).To(Equal(getData(4000)))
+		})
+
+		Context("flushing", func() {
+			It("returns right away if there's no buffered data", func() {
+				Expect(str.Flush()).To(Succeed())
+			})
+
+			It("blocks until all buffered data has been sent", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(str.Flush()).To(Succeed())
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("respects the write deadline", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+				str.SetWriteDeadline(deadline)
+				err = str.Flush()
+				Expect(err).To(MatchError(errDeadline))
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+			})
+
+			It("returns when the stream is canceled", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(str.Flush()).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				Eventually(done).Should(BeClosed())
+			})
+		})
+
+		Context("coalescing writes", func() {
+			BeforeEach(func() {
+				str.setSendBuffer(protocol.DefaultStreamSendBufferSize, true)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			})
+
+			// writes and sends "foo", such that there's unacknowledged data
+			sendFirstFrame := func() ackhandler.Frame {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+				return *frame
+			}
+
+			It("sends small writes right away if there's no unacknowledged data", func() {
+				sendFirstFrame()
+			})
+
+			It("holds back small writes until the outstanding data is acknowledged", func() {
+				first := sendFirstFrame()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("bar"))
+				Expect(err).ToNot(HaveOccurred())
+				frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+				Expect(hasMoreData).To(BeFalse())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err = strWithTimeout.Write([]byte("baz"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.popStreamFrame(protocol.MaxByteCount)).To(BeNil())
+				// acknowledging the outstanding frame makes the stream send the buffered data
+				mockSender.EXPECT().onHasStreamData(streamID)
+				first.OnAcked(first.Frame)
+				frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Offset).To(Equal(protocol.ByteCount(3)))
+				Expect(f.Data).To(Equal([]byte("barbaz")))
+			})
+
+			It("sends buffered data that fills a STREAM frame", func() {
+				sendFirstFrame()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.popStreamFrame(expectedFrameHeaderLen(3) + 10)).To(BeNil())
+				frame, _ := str.popStreamFrame(expectedFrameHeaderLen(3) + 5)
+				Expect(frame).ToNot(BeNil())
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("fooba")))
+			})
+
+			It("sends buffered data when Flush is called", func() {
+				sendFirstFrame()
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				_, err := strWithTimeout.Write([]byte("bar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.popStreamFrame(protocol.MaxByteCount)).To(BeNil())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(str.Flush()).To(Succeed())
+				}()
+				Eventually(func() *ackhandler.Frame {
+					frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+					return frame
+				}).ShouldNot(BeNil())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("sends buffered data when the stream is closed", func() {
+				sendFirstFrame()
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				_, err := strWithTimeout.Write([]byte("bar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.popStreamFrame(protocol.MaxByteCount)).To(BeNil())
+				Expect(str.Close()).To(Succeed())
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Data).To(Equal([]byte("bar")))
+				Expect(f.Fin).To(BeTrue())
+			})
+		})
+	})
+
 	Context("handling MAX_STREAM_DATA frames", func() {
 		It("informs the flow controller", func() {
 			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1337))
@@ -998,6 +1197,85 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("replaying 0-RTT data", func() {
+		It("resends all data after the stream is reopened", func() {
+			str.enable0RTTRecording()
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2) // once for Write, once for Close
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			frame, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+			Expect(str.Close()).To(Succeed())
+
+			newFC := mocks.NewMockStreamFlowController(mockCtrl)
+			mockSender.EXPECT().onHasStreamData(protocol.StreamID(4))
+			str.reopenFor0RTTReplay(4, newFC)
+			Expect(str.StreamID()).To(Equal(protocol.StreamID(4)))
+			newFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(4))
+			newFC.EXPECT().AddBytesSent(protocol.ByteCount(4))
+			frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			Expect(hasMoreData).To(BeTrue())
+			f := frame.Frame.(*wire.StreamFrame)
+			Expect(f.StreamID).To(Equal(protocol.StreamID(4)))
+			Expect(f.Offset).To(BeZero())
+			Expect(f.Data).To(Equal([]byte("foob")))
+			Expect(f.Fin).To(BeFalse())
+			newFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			newFC.EXPECT().AddBytesSent(protocol.ByteCount(2))
+			frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			f = frame.Frame.(*wire.StreamFrame)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(4)))
+			Expect(f.Data).To(Equal([]byte("ar")))
+			Expect(f.Fin).To(BeTrue())
+		})
+
+		It("doesn't record data once the replay data was dropped", func() {
+			str.enable0RTTRecording()
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+			_, _ = str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+			Expect(str.zeroRTTData).To(Equal([]byte("foo")))
+			str.drop0RTTReplayData()
+			Expect(str.zeroRTTData).To(BeNil())
+			_, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(str.zeroRTTData).To(BeNil())
+		})
+
+		It("resets the stream when it is reopened after writing was canceled", func() {
+			str.enable0RTTRecording()
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+				StreamID:  streamID,
+				FinalSize: 6,
+				ErrorCode: 1234,
+			})
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+
+			mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+				StreamID:  4,
+				ErrorCode: 1234,
+			})
+			str.reopenFor0RTTReplay(4, mocks.NewMockStreamFlowController(mockCtrl))
+			frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).To(BeNil())
+		})
+	})
+
 	Context("determining when a stream is completed", func() {
 		BeforeEach(func() {
 			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
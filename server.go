@@ -70,8 +70,14 @@ type baseServer struct {
 	createdPacketConn bool
 
 	tokenGenerator *handshake.TokenGenerator
+	// retryOffloadTokens is used to decode Retry tokens issued by a Retry offload service.
+	// It is nil if Retry offload is not configured.
+	retryOffloadTokens *handshake.RetryOffloadTokenProtector
 
 	connHandler packetHandlerManager
+	// eventLoop drives the connections, if the event loop mode is enabled.
+	// It is nil otherwise.
+	eventLoop *eventLoop
 
 	receivedPackets chan *receivedPacket
 
@@ -89,6 +95,7 @@ type baseServer struct {
 		*tls.Config,
 		*handshake.TokenGenerator,
 		bool, /* enable 0-RTT */
+		*eventLoop,
 		logging.ConnectionTracer,
 		uint64,
 		utils.Logger,
@@ -180,7 +187,7 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
-	if err := validateConfig(config); err != nil {
+	if err := validateServerConfig(config); err != nil {
 		return nil, err
 	}
 	config = populateServerConfig(config)
@@ -198,23 +205,34 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 	if err != nil {
 		return nil, err
 	}
+	var retryOffloadTokens *handshake.RetryOffloadTokenProtector
+	if len(config.RetryOffloadKey) > 0 {
+		retryOffloadTokens, err = handshake.NewRetryOffloadTokenProtector(config.RetryOffloadKey, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
 	c, err := wrapConn(conn)
 	if err != nil {
 		return nil, err
 	}
 	s := &baseServer{
-		conn:             c,
-		tlsConf:          tlsConf,
-		config:           config,
-		tokenGenerator:   tokenGenerator,
-		connHandler:      connHandler,
-		connQueue:        make(chan quicConn),
-		errorChan:        make(chan struct{}),
-		running:          make(chan struct{}),
-		receivedPackets:  make(chan *receivedPacket, protocol.MaxServerUnprocessedPackets),
-		newConn:          newConnection,
-		logger:           utils.DefaultLogger.WithPrefix("server"),
-		acceptEarlyConns: acceptEarly,
+		conn:               c,
+		tlsConf:            tlsConf,
+		config:             config,
+		tokenGenerator:     tokenGenerator,
+		retryOffloadTokens: retryOffloadTokens,
+		connHandler:        connHandler,
+		connQueue:          make(chan quicConn),
+		errorChan:          make(chan struct{}),
+		running:            make(chan struct{}),
+		receivedPackets:    make(chan *receivedPacket, protocol.MaxServerUnprocessedPackets),
+		newConn:            newConnection,
+		logger:             utils.DefaultLogger.WithPrefix("server"),
+		acceptEarlyConns:   acceptEarly,
+	}
+	if config.EventLoopWorkers > 0 {
+		s.eventLoop = newEventLoop(config.EventLoopWorkers)
 	}
 	go s.run()
 	connHandler.SetServer(s)
@@ -298,6 +316,10 @@ func (s *baseServer) Close() error {
 
 	<-s.running
 	s.connHandler.CloseServer()
+	// All connections have been closed now.
+	if s.eventLoop != nil {
+		s.eventLoop.Close()
+	}
 	if createdPacketConn {
 		return s.connHandler.Destroy()
 	}
@@ -328,6 +350,7 @@ func (s *baseServer) handlePacket(p *receivedPacket) {
 		if s.config.Tracer != nil {
 			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
 		}
+		p.buffer.Release()
 	}
 }
 
@@ -421,6 +444,16 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 				origDestConnID = c.OriginalDestConnectionID
 				retrySrcConnID = &c.RetrySrcConnectionID
 			}
+		} else if s.retryOffloadTokens != nil {
+			if c, err := s.retryOffloadTokens.DecodeToken(hdr.Token); err == nil {
+				token = &Token{
+					IsRetryToken: true,
+					RemoteAddr:   c.ClientIP.String(),
+					SentTime:     c.SentTime,
+				}
+				origDestConnID = c.OriginalDestConnectionID
+				retrySrcConnID = &c.RetrySrcConnectionID
+			}
 		}
 	}
 	if !s.config.AcceptToken(p.remoteAddr, token) {
@@ -450,8 +483,9 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		return nil
 	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := s.config.generateConnectionID()
 	if err != nil {
+		p.buffer.Release()
 		return err
 	}
 	s.logger.Debugf("Changing connection ID to %s.", connID)
@@ -472,7 +506,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			)
 		}
 		conn = s.newConn(
-			newSendConn(s.conn, p.remoteAddr, p.info),
+			newSendConn(s.conn, p.remoteAddr, p.info, s.config.DSCP),
 			s.connHandler,
 			origDestConnID,
 			retrySrcConnID,
@@ -484,6 +518,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			s.tlsConf,
 			s.tokenGenerator,
 			s.acceptEarlyConns,
+			s.eventLoop,
 			tracer,
 			tracingID,
 			s.logger,
@@ -492,6 +527,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		conn.handlePacket(p)
 		return conn
 	}); !added {
+		p.buffer.Release()
 		return nil
 	}
 	go conn.run()
@@ -535,7 +571,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header, info *pack
 	// Log the Initial packet now.
 	// If no Retry is sent, the packet will be logged by the connection.
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
-	srcConnID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	srcConnID, err := s.config.generateConnectionID()
 	if err != nil {
 		return err
 	}
@@ -37,8 +37,9 @@ func areServersRunning() bool {
 
 var _ = Describe("Server", func() {
 	var (
-		conn    *MockPacketConn
-		tlsConf *tls.Config
+		conn       *MockPacketConn
+		connClosed chan struct{}
+		tlsConf    *tls.Config
 	)
 
 	getPacket := func(hdr *wire.Header, p []byte) *receivedPacket {
@@ -76,7 +77,6 @@ var _ = Describe("Server", func() {
 			Version:          protocol.VersionTLS,
 		}
 		p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
-		p.buffer = getPacketBuffer()
 		p.remoteAddr = senderAddr
 		return p
 	}
@@ -98,15 +98,25 @@ var _ = Describe("Server", func() {
 	BeforeEach(func() {
 		conn = NewMockPacketConn(mockCtrl)
 		conn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
-		conn.EXPECT().ReadFrom(gomock.Any()).Do(func(_ []byte) { <-(make(chan struct{})) }).MaxTimes(1)
+		closed := make(chan struct{})
+		connClosed = closed
+		conn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(func(_ []byte) (int, net.Addr, error) {
+			<-closed
+			return 0, nil, net.ErrClosed
+		}).MaxTimes(1)
 		tlsConf = testdata.GetTLSConfig()
 		tlsConf.NextProtos = []string{"proto1"}
 	})
 
 	AfterEach(func() {
 		Eventually(areServersRunning).Should(BeFalse())
+		// unblock the ReadFrom call, so that the packet buffer is released
+		close(connClosed)
+		Eventually(areConnsMultiplexed).Should(BeFalse())
 	})
 
+	expectNoBufferLeaks()
+
 	It("errors when no tls.Config is given", func() {
 		_, err := ListenAddr("localhost:0", nil, nil)
 		Expect(err).To(HaveOccurred())
@@ -157,6 +167,17 @@ var _ = Describe("Server", func() {
 		Expect(ln.Close()).To(Succeed())
 	})
 
+	It("closes the event loop when it is closed", func() {
+		ln, err := Listen(conn, tlsConf, &Config{EventLoopWorkers: 2})
+		Expect(err).ToNot(HaveOccurred())
+		server := ln.(*baseServer)
+		Expect(server.eventLoop).ToNot(BeNil())
+		Expect(ln.Close()).To(Succeed())
+		server.eventLoop.mutex.Lock()
+		defer server.eventLoop.mutex.Unlock()
+		Expect(server.eventLoop.closed).To(BeTrue())
+	})
+
 	It("listens on a given address", func() {
 		addr := "127.0.0.1:13579"
 		ln, err := ListenAddr(addr, tlsConf, &Config{})
@@ -337,6 +358,7 @@ var _ = Describe("Server", func() {
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
 					enable0RTT bool,
+					_ *eventLoop,
 					_ logging.ConnectionTracer,
 					_ uint64,
 					_ utils.Logger,
@@ -352,7 +374,7 @@ var _ = Describe("Server", func() {
 					Expect(srcConnID).ToNot(Equal(hdr.SrcConnectionID))
 					Expect(srcConnID).To(Equal(newConnID))
 					Expect(tokenP).To(Equal(token))
-					conn.EXPECT().handlePacket(p)
+					conn.EXPECT().handlePacket(p).Do(func(p *receivedPacket) { p.buffer.Release() })
 					conn.EXPECT().run().Do(func() { close(run) })
 					conn.EXPECT().Context().Return(context.Background())
 					conn.EXPECT().HandshakeComplete().Return(context.Background())
@@ -373,6 +395,103 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("creates a connection when a Retry offload token is accepted", func() {
+				key := []byte("0123456789abcdef")
+				var err error
+				serv.retryOffloadTokens, err = handshake.NewRetryOffloadTokenProtector(key, 0)
+				Expect(err).ToNot(HaveOccurred())
+				var acceptedToken *Token
+				serv.config.AcceptToken = func(_ net.Addr, token *Token) bool {
+					acceptedToken = token
+					return true
+				}
+				// the token is created by the Retry offload service, e.g. a load balancer
+				p, err := handshake.NewRetryOffloadTokenProtector(key, 0)
+				Expect(err).ToNot(HaveOccurred())
+				retryToken, err := p.NewToken(
+					&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)},
+					protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde},
+					protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+					Token:            retryToken,
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				run := make(chan struct{})
+				var token protocol.StatelessResetToken
+				rand.Read(token[:])
+
+				var newConnID protocol.ConnectionID
+				phm.EXPECT().AddWithConnID(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, gomock.Any(), gomock.Any()).DoAndReturn(func(_, c protocol.ConnectionID, fn func() packetHandler) bool {
+					newConnID = c
+					phm.EXPECT().GetStatelessResetToken(gomock.Any()).DoAndReturn(func(c protocol.ConnectionID) protocol.StatelessResetToken {
+						newConnID = c
+						return token
+					})
+					fn()
+					return true
+				})
+				tracer.EXPECT().TracerForConnection(gomock.Any(), protocol.PerspectiveServer, protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde})
+				conn := NewMockQuicConn(mockCtrl)
+				serv.newConn = func(
+					_ sendConn,
+					_ connRunner,
+					origDestConnID protocol.ConnectionID,
+					retrySrcConnID *protocol.ConnectionID,
+					clientDestConnID protocol.ConnectionID,
+					destConnID protocol.ConnectionID,
+					srcConnID protocol.ConnectionID,
+					tokenP protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					enable0RTT bool,
+					_ *eventLoop,
+					_ logging.ConnectionTracer,
+					_ uint64,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicConn {
+					Expect(enable0RTT).To(BeFalse())
+					Expect(origDestConnID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde}))
+					Expect(retrySrcConnID).To(Equal(&protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}))
+					Expect(clientDestConnID).To(Equal(hdr.DestConnectionID))
+					Expect(destConnID).To(Equal(hdr.SrcConnectionID))
+					// make sure we're using a server-generated connection ID
+					Expect(srcConnID).ToNot(Equal(hdr.DestConnectionID))
+					Expect(srcConnID).ToNot(Equal(hdr.SrcConnectionID))
+					Expect(srcConnID).To(Equal(newConnID))
+					Expect(tokenP).To(Equal(token))
+					conn.EXPECT().handlePacket(packet).Do(func(p *receivedPacket) { p.buffer.Release() })
+					conn.EXPECT().run().Do(func() { close(run) })
+					conn.EXPECT().Context().Return(context.Background())
+					conn.EXPECT().HandshakeComplete().Return(context.Background())
+					return conn
+				}
+
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					serv.handlePacket(packet)
+					// the Handshake packet is written by the connection.
+					// Make sure there are no Write calls on the packet conn.
+					time.Sleep(50 * time.Millisecond)
+					close(done)
+				}()
+				// make sure we're using a server-generated connection ID
+				Eventually(run).Should(BeClosed())
+				Eventually(done).Should(BeClosed())
+				Expect(acceptedToken).ToNot(BeNil())
+				Expect(acceptedToken.IsRetryToken).To(BeTrue())
+				Expect(acceptedToken.RemoteAddr).To(Equal("192.168.0.1"))
+			})
+
 			It("sends a Version Negotiation Packet for unsupported versions", func() {
 				srcConnID := protocol.ConnectionID{1, 2, 3, 4, 5}
 				destConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6}
@@ -615,6 +734,7 @@ var _ = Describe("Server", func() {
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
 					enable0RTT bool,
+					_ *eventLoop,
 					_ logging.ConnectionTracer,
 					_ uint64,
 					_ utils.Logger,
@@ -630,7 +750,7 @@ var _ = Describe("Server", func() {
 					Expect(srcConnID).ToNot(Equal(hdr.SrcConnectionID))
 					Expect(srcConnID).To(Equal(newConnID))
 					Expect(tokenP).To(Equal(token))
-					conn.EXPECT().handlePacket(p)
+					conn.EXPECT().handlePacket(p).Do(func(p *receivedPacket) { p.buffer.Release() })
 					conn.EXPECT().run().Do(func() { close(run) })
 					conn.EXPECT().Context().Return(context.Background())
 					conn.EXPECT().HandshakeComplete().Return(context.Background())
@@ -675,6 +795,7 @@ var _ = Describe("Server", func() {
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
 					_ bool,
+					_ *eventLoop,
 					_ logging.ConnectionTracer,
 					_ uint64,
 					_ utils.Logger,
@@ -683,7 +804,7 @@ var _ = Describe("Server", func() {
 					<-acceptConn
 					atomic.AddUint32(&counter, 1)
 					conn := NewMockQuicConn(mockCtrl)
-					conn.EXPECT().handlePacket(gomock.Any()).MaxTimes(1)
+					conn.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) { p.buffer.Release() }).MaxTimes(1)
 					conn.EXPECT().run().MaxTimes(1)
 					conn.EXPECT().Context().Return(context.Background()).MaxTimes(1)
 					conn.EXPECT().HandshakeComplete().Return(context.Background()).MaxTimes(1)
@@ -729,6 +850,7 @@ var _ = Describe("Server", func() {
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
 					_ bool,
+					_ *eventLoop,
 					_ logging.ConnectionTracer,
 					_ uint64,
 					_ utils.Logger,
@@ -760,13 +882,14 @@ var _ = Describe("Server", func() {
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
 					_ bool,
+					_ *eventLoop,
 					_ logging.ConnectionTracer,
 					_ uint64,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicConn {
 					conn := NewMockQuicConn(mockCtrl)
-					conn.EXPECT().handlePacket(gomock.Any())
+					conn.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) { p.buffer.Release() })
 					conn.EXPECT().run()
 					conn.EXPECT().Context().Return(context.Background())
 					ctx, cancel := context.WithCancel(context.Background())
@@ -832,12 +955,13 @@ var _ = Describe("Server", func() {
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
 					_ bool,
+					_ *eventLoop,
 					_ logging.ConnectionTracer,
 					_ uint64,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicConn {
-					conn.EXPECT().handlePacket(p)
+					conn.EXPECT().handlePacket(p).Do(func(p *receivedPacket) { p.buffer.Release() })
 					conn.EXPECT().run()
 					conn.EXPECT().Context().Return(ctx)
 					ctx, cancel := context.WithCancel(context.Background())
@@ -944,12 +1068,13 @@ var _ = Describe("Server", func() {
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
 					_ bool,
+					_ *eventLoop,
 					_ logging.ConnectionTracer,
 					_ uint64,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicConn {
-					conn.EXPECT().handlePacket(gomock.Any())
+					conn.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) { p.buffer.Release() })
 					conn.EXPECT().HandshakeComplete().Return(ctx)
 					conn.EXPECT().run().Do(func() {})
 					conn.EXPECT().Context().Return(context.Background())
@@ -1018,13 +1143,14 @@ var _ = Describe("Server", func() {
 				_ *tls.Config,
 				_ *handshake.TokenGenerator,
 				enable0RTT bool,
+				_ *eventLoop,
 				_ logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicConn {
 				Expect(enable0RTT).To(BeTrue())
-				conn.EXPECT().handlePacket(gomock.Any())
+				conn.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) { p.buffer.Release() })
 				conn.EXPECT().run().Do(func() {})
 				conn.EXPECT().earlyConnReady().Return(ready)
 				conn.EXPECT().Context().Return(context.Background())
@@ -1061,6 +1187,7 @@ var _ = Describe("Server", func() {
 				_ *tls.Config,
 				_ *handshake.TokenGenerator,
 				_ bool,
+				_ *eventLoop,
 				_ logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
@@ -1069,7 +1196,7 @@ var _ = Describe("Server", func() {
 				ready := make(chan struct{})
 				close(ready)
 				conn := NewMockQuicConn(mockCtrl)
-				conn.EXPECT().handlePacket(gomock.Any())
+				conn.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) { p.buffer.Release() })
 				conn.EXPECT().run()
 				conn.EXPECT().earlyConnReady().Return(ready)
 				conn.EXPECT().Context().Return(context.Background())
@@ -1125,12 +1252,13 @@ var _ = Describe("Server", func() {
 				_ *tls.Config,
 				_ *handshake.TokenGenerator,
 				_ bool,
+				_ *eventLoop,
 				_ logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicConn {
-				conn.EXPECT().handlePacket(p)
+				conn.EXPECT().handlePacket(p).Do(func(p *receivedPacket) { p.buffer.Release() })
 				conn.EXPECT().run()
 				conn.EXPECT().earlyConnReady()
 				conn.EXPECT().Context().Return(ctx)
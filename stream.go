@@ -62,6 +62,8 @@ type streamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	updateSendWindow(protocol.ByteCount)
+	drop0RTTReplayData()
+	reopenFor0RTTReplay(protocol.StreamID, flowcontrol.StreamFlowController)
 }
 
 var (
@@ -140,6 +142,11 @@ func (s *stream) closeForShutdown(err error) {
 	s.receiveStream.closeForShutdown(err)
 }
 
+func (s *stream) reopenFor0RTTReplay(id protocol.StreamID, flowController flowcontrol.StreamFlowController) {
+	s.receiveStream.reopenFor0RTTReplay(id, flowController)
+	s.sendStream.reopenFor0RTTReplay(id, flowController)
+}
+
 // checkIfCompleted is called from the uniStreamSender, when one of the stream halves is completed.
 // It makes sure that the onStreamCompleted callback is only called if both receive and send side have completed.
 func (s *stream) checkIfCompleted() {
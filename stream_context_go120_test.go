@@ -0,0 +1,90 @@
+//go:build go1.20
+// +build go1.20
+
+package quic
+
+import (
+	"context"
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream context causes", func() {
+	const streamID protocol.StreamID = 1337
+
+	var (
+		mockFC     *mocks.MockStreamFlowController
+		mockSender *MockStreamSender
+	)
+
+	BeforeEach(func() {
+		mockSender = NewMockStreamSender(mockCtrl)
+		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
+	})
+
+	Context("send stream", func() {
+		var str *sendStream
+
+		BeforeEach(func() {
+			str = newSendStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		})
+
+		It("uses the StreamError when a STOP_SENDING frame is received", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(gomock.Any())
+			str.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+			Expect(context.Cause(str.Context())).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+		})
+
+		It("uses the error the stream was closed for shutdown with", func() {
+			testErr := errors.New("test error")
+			str.closeForShutdown(testErr)
+			Expect(context.Cause(str.Context())).To(MatchError(testErr))
+		})
+
+		It("uses context.Canceled when the stream is closed", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			Expect(context.Cause(str.Context())).To(MatchError(context.Canceled))
+		})
+	})
+
+	Context("receive stream", func() {
+		var str *receiveStream
+
+		BeforeEach(func() {
+			str = newReceiveStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		})
+
+		It("uses the StreamError when a RESET_STREAM frame is received", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+			mockFC.EXPECT().Abandon()
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+				StreamID:  streamID,
+				FinalSize: 42,
+				ErrorCode: 1234,
+			})).To(Succeed())
+			Expect(context.Cause(str.ReadContext())).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+		})
+
+		It("uses the error the stream was closed for shutdown with", func() {
+			testErr := errors.New("test error")
+			str.closeForShutdown(testErr)
+			Expect(context.Cause(str.ReadContext())).To(MatchError(testErr))
+		})
+
+		It("uses the read error when reading is canceled", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			str.CancelRead(1234)
+			Expect(context.Cause(str.ReadContext())).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+		})
+	})
+})
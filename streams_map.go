@@ -10,6 +10,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -54,6 +55,14 @@ type streamsMap struct {
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
 
+	// replay0RTT decides which streams opened during 0-RTT are replayed, if 0-RTT is rejected.
+	// Data sent on outgoing streams is recorded as long as record0RTT is set.
+	replay0RTT func(protocol.StreamID) bool
+	record0RTT utils.AtomicBool
+
+	sendBufferSize protocol.ByteCount
+	coalesceWrites bool
+
 	mutex               sync.Mutex
 	outgoingBidiStreams *outgoingBidiStreamsMap
 	outgoingUniStreams  *outgoingUniStreamsMap
@@ -69,10 +78,15 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	replay0RTT func(protocol.StreamID) bool,
+	sendBufferSize protocol.ByteCount,
+	coalesceWrites bool,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) streamManager {
 	m := &streamsMap{
+		sendBufferSize:         sendBufferSize,
+		coalesceWrites:         coalesceWrites,
 		perspective:            perspective,
 		newFlowController:      newFlowController,
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
@@ -80,33 +94,26 @@ func newStreamsMap(
 		sender:                 sender,
 		version:                version,
 	}
+	if perspective == protocol.PerspectiveClient && replay0RTT != nil {
+		m.replay0RTT = replay0RTT
+		m.record0RTT.Set(true)
+	}
 	m.initMaps()
 	return m
 }
 
 func (m *streamsMap) initMaps() {
-	m.outgoingBidiStreams = newOutgoingBidiStreamsMap(
-		func(num protocol.StreamNum) streamI {
-			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
-		},
-		m.sender.queueControlFrame,
-	)
+	m.initOutgoingMaps()
 	m.incomingBidiStreams = newIncomingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite())
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
+			str := newStream(id, m.sender, m.newFlowController(id), m.version)
+			str.setSendBuffer(m.sendBufferSize, m.coalesceWrites)
+			return str
 		},
 		m.maxIncomingBidiStreams,
 		m.sender.queueControlFrame,
 	)
-	m.outgoingUniStreams = newOutgoingUniStreamsMap(
-		func(num protocol.StreamNum) sendStreamI {
-			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
-			return newSendStream(id, m.sender, m.newFlowController(id), m.version)
-		},
-		m.sender.queueControlFrame,
-	)
 	m.incomingUniStreams = newIncomingUniStreamsMap(
 		func(num protocol.StreamNum) receiveStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective.Opposite())
@@ -117,6 +124,33 @@ func (m *streamsMap) initMaps() {
 	)
 }
 
+func (m *streamsMap) initOutgoingMaps() {
+	m.outgoingBidiStreams = newOutgoingBidiStreamsMap(
+		func(num protocol.StreamNum) streamI {
+			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
+			str := newStream(id, m.sender, m.newFlowController(id), m.version)
+			str.setSendBuffer(m.sendBufferSize, m.coalesceWrites)
+			if m.record0RTT.Get() {
+				str.enable0RTTRecording()
+			}
+			return str
+		},
+		m.sender.queueControlFrame,
+	)
+	m.outgoingUniStreams = newOutgoingUniStreamsMap(
+		func(num protocol.StreamNum) sendStreamI {
+			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
+			str := newSendStream(id, m.sender, m.newFlowController(id), m.version)
+			str.setSendBuffer(m.sendBufferSize, m.coalesceWrites)
+			if m.record0RTT.Get() {
+				str.enable0RTTRecording()
+			}
+			return str
+		},
+		m.sender.queueControlFrame,
+	)
+}
+
 func (m *streamsMap) OpenStream() (Stream, error) {
 	m.mutex.Lock()
 	reset := m.reset
@@ -302,12 +336,54 @@ func (m *streamsMap) CloseWithError(err error) {
 // 2. reset to their initial state, such that we can immediately process new incoming stream data.
 // Afterwards, calls to Open{Uni}Stream{Sync} / Accept{Uni}Stream will continue to return the error,
 // until UseResetMaps() has been called.
+// If 0-RTT data is replayed, only the outgoing streams that are not replayed are closed,
+// and the replayed streams are reopened as soon as the new stream limits allow.
 func (m *streamsMap) ResetFor0RTT() {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
-	m.reset = true
-	m.CloseWithError(Err0RTTRejected)
-	m.initMaps()
+
+	if m.replay0RTT == nil {
+		m.reset = true
+		m.CloseWithError(Err0RTTRejected)
+		m.initMaps()
+		return
+	}
+
+	m.record0RTT.Set(false)
+	bidiStreams := m.outgoingBidiStreams.RemoveStreamsForReplay(func(str streamI) bool { return m.replay0RTT(str.StreamID()) })
+	uniStreams := m.outgoingUniStreams.RemoveStreamsForReplay(func(str sendStreamI) bool { return m.replay0RTT(str.StreamID()) })
+	m.outgoingBidiStreams.CloseWithError(Err0RTTRejected)
+	m.outgoingUniStreams.CloseWithError(Err0RTTRejected)
+	m.initOutgoingMaps()
+	for _, str := range bidiStreams {
+		str := str
+		m.outgoingBidiStreams.ReopenStream(str, func(num protocol.StreamNum) {
+			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
+			str.reopenFor0RTTReplay(id, m.newFlowController(id))
+		})
+	}
+	for _, str := range uniStreams {
+		str := str
+		m.outgoingUniStreams.ReopenStream(str, func(num protocol.StreamNum) {
+			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
+			str.reopenFor0RTTReplay(id, m.newFlowController(id))
+		})
+	}
+}
+
+// Drop0RTTReplayData is called when the handshake completes.
+// At this point, 0-RTT has either been accepted, or it was rejected before, and data doesn't need to be replayed any more.
+func (m *streamsMap) Drop0RTTReplayData() {
+	if m.replay0RTT == nil {
+		return
+	}
+	m.record0RTT.Set(false)
+	m.mutex.Lock()
+	bidiStreams := m.outgoingBidiStreams
+	uniStreams := m.outgoingUniStreams
+	m.mutex.Unlock()
+	bidiStreams.Drop0RTTReplayData()
+	uniStreams.Drop0RTTReplayData()
 }
 
 func (m *streamsMap) UseResetMaps() {
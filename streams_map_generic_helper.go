@@ -13,6 +13,7 @@ type item interface {
 	generic.Type
 	updateSendWindow(protocol.ByteCount)
 	closeForShutdown(error)
+	drop0RTTReplayData()
 }
 
 const streamTypeGeneric protocol.StreamType = protocol.StreamTypeUni
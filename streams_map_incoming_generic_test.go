@@ -18,9 +18,10 @@ import (
 type mockGenericStream struct {
 	num protocol.StreamNum
 
-	closed     bool
-	closeErr   error
-	sendWindow protocol.ByteCount
+	closed            bool
+	closeErr          error
+	sendWindow        protocol.ByteCount
+	dropped0RTTReplay bool
 }
 
 func (s *mockGenericStream) closeForShutdown(err error) {
@@ -32,6 +33,10 @@ func (s *mockGenericStream) updateSendWindow(limit protocol.ByteCount) {
 	s.sendWindow = limit
 }
 
+func (s *mockGenericStream) drop0RTTReplayData() {
+	s.dropped0RTTReplay = true
+}
+
 var _ = Describe("Streams Map (incoming)", func() {
 	var (
 		m              *incomingItemsMap
@@ -23,12 +23,20 @@ type outgoingItemsMap struct {
 	maxStream   protocol.StreamNum // the maximum stream ID we're allowed to open
 	blockedSent bool               // was a STREAMS_BLOCKED sent for the current maxStream
 
+	// streams opened during 0-RTT that are reopened after 0-RTT was rejected
+	reopenQueue []reopenedItem
+
 	newStream            func(protocol.StreamNum) item
 	queueStreamIDBlocked func(*wire.StreamsBlockedFrame)
 
 	closeErr error
 }
 
+type reopenedItem struct {
+	str    item
+	reopen func(protocol.StreamNum)
+}
+
 func newOutgoingItemsMap(
 	newStream func(protocol.StreamNum) item,
 	queueControlFrame func(wire.Frame),
@@ -51,8 +59,8 @@ func (m *outgoingItemsMap) OpenStream() (item, error) {
 		return nil, m.closeErr
 	}
 
-	// if there are OpenStreamSync calls waiting, return an error here
-	if len(m.openQueue) > 0 || m.nextStream > m.maxStream {
+	// if there are OpenStreamSync calls or reopened streams waiting, return an error here
+	if len(m.openQueue) > 0 || len(m.reopenQueue) > 0 || m.nextStream > m.maxStream {
 		m.maybeSendBlockedFrame()
 		return nil, streamOpenErr{errTooManyOpenStreams}
 	}
@@ -71,7 +79,7 @@ func (m *outgoingItemsMap) OpenStreamSync(ctx context.Context) (item, error) {
 		return nil, err
 	}
 
-	if len(m.openQueue) == 0 && m.nextStream <= m.maxStream {
+	if len(m.openQueue) == 0 && len(m.reopenQueue) == 0 && m.nextStream <= m.maxStream {
 		return m.openStream(), nil
 	}
 
@@ -172,12 +180,65 @@ func (m *outgoingItemsMap) SetMaxStream(num protocol.StreamNum) {
 	}
 	m.maxStream = num
 	m.blockedSent = false
-	if m.maxStream < m.nextStream-1+protocol.StreamNum(len(m.openQueue)) {
+	m.maybeReopenStreams()
+	if m.maxStream < m.nextStream-1+protocol.StreamNum(len(m.openQueue)+len(m.reopenQueue)) {
 		m.maybeSendBlockedFrame()
 	}
 	m.unblockOpenSync()
 }
 
+// RemoveStreamsForReplay is called when 0-RTT is rejected.
+// It removes the streams that are replayed, and returns them in the order they were opened.
+func (m *outgoingItemsMap) RemoveStreamsForReplay(replay func(item) bool) []item {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var strs []item
+	for num := protocol.StreamNum(1); num < m.nextStream; num++ {
+		str, ok := m.streams[num]
+		if !ok || !replay(str) {
+			continue
+		}
+		delete(m.streams, num)
+		strs = append(strs, str)
+	}
+	return strs
+}
+
+// ReopenStream reopens a stream that was opened during 0-RTT, after 0-RTT was rejected.
+// Streams are reopened in the order this function is called, as soon as the stream limit allows.
+// Reopening streams takes precedence over opening new streams.
+func (m *outgoingItemsMap) ReopenStream(str item, reopen func(protocol.StreamNum)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closeErr != nil {
+		str.closeForShutdown(m.closeErr)
+		return
+	}
+	m.reopenQueue = append(m.reopenQueue, reopenedItem{str: str, reopen: reopen})
+	m.maybeReopenStreams()
+}
+
+func (m *outgoingItemsMap) maybeReopenStreams() {
+	for len(m.reopenQueue) > 0 && m.nextStream <= m.maxStream {
+		r := m.reopenQueue[0]
+		m.reopenQueue = m.reopenQueue[1:]
+		r.reopen(m.nextStream)
+		m.streams[m.nextStream] = r.str
+		m.nextStream++
+	}
+}
+
+// Drop0RTTReplayData is called when data sent in 0-RTT doesn't need to be replayed any more.
+func (m *outgoingItemsMap) Drop0RTTReplayData() {
+	m.mutex.Lock()
+	for _, str := range m.streams {
+		str.drop0RTTReplayData()
+	}
+	m.mutex.Unlock()
+}
+
 // UpdateSendWindow is called when the peer's transport parameters are received.
 // Only in the case of a 0-RTT handshake will we have open streams at this point.
 // We might need to update the send window, in case the server increased it.
@@ -215,6 +276,10 @@ func (m *outgoingItemsMap) CloseWithError(err error) {
 	for _, str := range m.streams {
 		str.closeForShutdown(err)
 	}
+	for _, r := range m.reopenQueue {
+		r.str.closeForShutdown(err)
+	}
+	m.reopenQueue = nil
 	for _, c := range m.openQueue {
 		if c != nil {
 			close(c)
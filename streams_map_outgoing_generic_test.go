@@ -403,6 +403,76 @@ var _ = Describe("Streams Map (outgoing)", func() {
 		})
 	})
 
+	Context("reopening streams for 0-RTT replay", func() {
+		It("removes streams that are replayed", func() {
+			m.SetMaxStream(4)
+			for i := 0; i < 4; i++ {
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+			}
+			strs := m.RemoveStreamsForReplay(func(str item) bool { return str.(*mockGenericStream).num != 2 })
+			Expect(strs).To(HaveLen(3))
+			Expect(strs[0].(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			Expect(strs[1].(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+			Expect(strs[2].(*mockGenericStream).num).To(Equal(protocol.StreamNum(4)))
+			str, err := m.GetStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).ToNot(BeNil())
+			str, err = m.GetStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(BeNil())
+		})
+
+		It("reopens streams when the stream limit allows it, before opening new streams", func() {
+			str1 := &mockGenericStream{num: 3}
+			str2 := &mockGenericStream{num: 5}
+			var reopened []protocol.StreamNum
+			m.ReopenStream(str1, func(num protocol.StreamNum) { reopened = append(reopened, num) })
+			m.ReopenStream(str2, func(num protocol.StreamNum) { reopened = append(reopened, num) })
+			Expect(reopened).To(BeEmpty())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			_, err := m.OpenStream()
+			expectTooManyStreamsError(err)
+			mockSender.EXPECT().queueControlFrame(&wire.StreamsBlockedFrame{Type: protocol.StreamTypeUni, StreamLimit: 1})
+			m.SetMaxStream(1)
+			Expect(reopened).To(Equal([]protocol.StreamNum{1}))
+			str, err := m.GetStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(str1))
+			m.SetMaxStream(3)
+			Expect(reopened).To(Equal([]protocol.StreamNum{1, 2}))
+			str, err = m.GetStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(str2))
+			str, err = m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+		})
+
+		It("closes streams waiting to be reopened", func() {
+			str := &mockGenericStream{num: 3}
+			m.ReopenStream(str, func(protocol.StreamNum) { Fail("didn't expect the stream to be reopened") })
+			testErr := errors.New("test err")
+			m.CloseWithError(testErr)
+			Expect(str.closed).To(BeTrue())
+			Expect(str.closeErr).To(MatchError(testErr))
+			str = &mockGenericStream{num: 5}
+			m.ReopenStream(str, func(protocol.StreamNum) { Fail("didn't expect the stream to be reopened") })
+			Expect(str.closed).To(BeTrue())
+		})
+
+		It("drops the 0-RTT replay data", func() {
+			m.SetMaxStream(2)
+			str1, err := m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			str2, err := m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			m.Drop0RTTReplayData()
+			Expect(str1.(*mockGenericStream).dropped0RTTReplay).To(BeTrue())
+			Expect(str2.(*mockGenericStream).dropped0RTTReplay).To(BeTrue())
+		})
+	})
+
 	Context("randomized tests", func() {
 		It("opens streams", func() {
 			rand.Seed(GinkgoRandomSeed())
@@ -25,12 +25,20 @@ type outgoingUniStreamsMap struct {
 	maxStream   protocol.StreamNum // the maximum stream ID we're allowed to open
 	blockedSent bool               // was a STREAMS_BLOCKED sent for the current maxStream
 
+	// streams opened during 0-RTT that are reopened after 0-RTT was rejected
+	reopenQueue []reopenedUniStream
+
 	newStream            func(protocol.StreamNum) sendStreamI
 	queueStreamIDBlocked func(*wire.StreamsBlockedFrame)
 
 	closeErr error
 }
 
+type reopenedUniStream struct {
+	str    sendStreamI
+	reopen func(protocol.StreamNum)
+}
+
 func newOutgoingUniStreamsMap(
 	newStream func(protocol.StreamNum) sendStreamI,
 	queueControlFrame func(wire.Frame),
@@ -53,8 +61,8 @@ func (m *outgoingUniStreamsMap) OpenStream() (sendStreamI, error) {
 		return nil, m.closeErr
 	}
 
-	// if there are OpenStreamSync calls waiting, return an error here
-	if len(m.openQueue) > 0 || m.nextStream > m.maxStream {
+	// if there are OpenStreamSync calls or reopened streams waiting, return an error here
+	if len(m.openQueue) > 0 || len(m.reopenQueue) > 0 || m.nextStream > m.maxStream {
 		m.maybeSendBlockedFrame()
 		return nil, streamOpenErr{errTooManyOpenStreams}
 	}
@@ -73,7 +81,7 @@ func (m *outgoingUniStreamsMap) OpenStreamSync(ctx context.Context) (sendStreamI
 		return nil, err
 	}
 
-	if len(m.openQueue) == 0 && m.nextStream <= m.maxStream {
+	if len(m.openQueue) == 0 && len(m.reopenQueue) == 0 && m.nextStream <= m.maxStream {
 		return m.openStream(), nil
 	}
 
@@ -174,12 +182,65 @@ func (m *outgoingUniStreamsMap) SetMaxStream(num protocol.StreamNum) {
 	}
 	m.maxStream = num
 	m.blockedSent = false
-	if m.maxStream < m.nextStream-1+protocol.StreamNum(len(m.openQueue)) {
+	m.maybeReopenStreams()
+	if m.maxStream < m.nextStream-1+protocol.StreamNum(len(m.openQueue)+len(m.reopenQueue)) {
 		m.maybeSendBlockedFrame()
 	}
 	m.unblockOpenSync()
 }
 
+// RemoveStreamsForReplay is called when 0-RTT is rejected.
+// It removes the streams that are replayed, and returns them in the order they were opened.
+func (m *outgoingUniStreamsMap) RemoveStreamsForReplay(replay func(sendStreamI) bool) []sendStreamI {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var strs []sendStreamI
+	for num := protocol.StreamNum(1); num < m.nextStream; num++ {
+		str, ok := m.streams[num]
+		if !ok || !replay(str) {
+			continue
+		}
+		delete(m.streams, num)
+		strs = append(strs, str)
+	}
+	return strs
+}
+
+// ReopenStream reopens a stream that was opened during 0-RTT, after 0-RTT was rejected.
+// Streams are reopened in the order this function is called, as soon as the stream limit allows.
+// Reopening streams takes precedence over opening new streams.
+func (m *outgoingUniStreamsMap) ReopenStream(str sendStreamI, reopen func(protocol.StreamNum)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closeErr != nil {
+		str.closeForShutdown(m.closeErr)
+		return
+	}
+	m.reopenQueue = append(m.reopenQueue, reopenedUniStream{str: str, reopen: reopen})
+	m.maybeReopenStreams()
+}
+
+func (m *outgoingUniStreamsMap) maybeReopenStreams() {
+	for len(m.reopenQueue) > 0 && m.nextStream <= m.maxStream {
+		r := m.reopenQueue[0]
+		m.reopenQueue = m.reopenQueue[1:]
+		r.reopen(m.nextStream)
+		m.streams[m.nextStream] = r.str
+		m.nextStream++
+	}
+}
+
+// Drop0RTTReplayData is called when data sent in 0-RTT doesn't need to be replayed any more.
+func (m *outgoingUniStreamsMap) Drop0RTTReplayData() {
+	m.mutex.Lock()
+	for _, str := range m.streams {
+		str.drop0RTTReplayData()
+	}
+	m.mutex.Unlock()
+}
+
 // UpdateSendWindow is called when the peer's transport parameters are received.
 // Only in the case of a 0-RTT handshake will we have open streams at this point.
 // We might need to update the send window, in case the server increased it.
@@ -217,6 +278,10 @@ func (m *outgoingUniStreamsMap) CloseWithError(err error) {
 	for _, str := range m.streams {
 		str.closeForShutdown(err)
 	}
+	for _, r := range m.reopenQueue {
+		r.str.closeForShutdown(err)
+	}
+	m.reopenQueue = nil
 	for _, c := range m.openQueue {
 		if c != nil {
 			close(c)
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/golang/mock/gomock"
 
@@ -88,7 +89,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, nil, protocol.DefaultStreamSendBufferSize, false, perspective, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -115,6 +116,25 @@ var _ = Describe("Streams Map", func() {
 					Expect(str).To(BeAssignableToTypeOf(&sendStream{}))
 					Expect(str.StreamID()).To(Equal(ids.firstOutgoingUniStream + 4))
 				})
+
+				It("configures the send buffer", func() {
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, nil, 1234, true, perspective, protocol.VersionWhatever).(*streamsMap)
+					allowUnlimitedStreams()
+					str, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.(*stream).sendBufferSize).To(Equal(protocol.ByteCount(1234)))
+					Expect(str.(*stream).coalesceWrites).To(BeTrue())
+					ustr, err := m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(ustr.(*sendStream).sendBufferSize).To(Equal(protocol.ByteCount(1234)))
+					Expect(ustr.(*sendStream).coalesceWrites).To(BeTrue())
+					_, err = m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					istr, err := m.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(istr.(*stream).sendBufferSize).To(Equal(protocol.ByteCount(1234)))
+					Expect(istr.(*stream).coalesceWrites).To(BeTrue())
+				})
 			})
 
 			Context("accepting", func() {
@@ -493,6 +513,43 @@ var _ = Describe("Streams Map", func() {
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("too many open streams"))
 				})
+
+				It("replays streams opened during 0-RTT", func() {
+					replay := func(id protocol.StreamID) bool { return id != ids.firstOutgoingBidiStream }
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, replay, protocol.DefaultStreamSendBufferSize, false, perspective, protocol.VersionWhatever).(*streamsMap)
+					mockSender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
+					allowUnlimitedStreams()
+					str1, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					str2, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					ustr, err := m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().onHasStreamData(ids.firstOutgoingBidiStream + 4)
+					_, err = str2.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+
+					m.ResetFor0RTT()
+					_, err = str1.Write([]byte("foobar"))
+					Expect(err).To(MatchError(Err0RTTRejected))
+					// the incoming streams maps are not reset
+					ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Millisecond))
+					defer cancel()
+					_, err = m.AcceptStream(ctx)
+					Expect(err).To(MatchError(context.DeadlineExceeded))
+
+					// the replayed streams are reopened as soon as the stream limit allows
+					mockSender.EXPECT().onHasStreamData(ids.firstOutgoingBidiStream)
+					m.UpdateLimits(&wire.TransportParameters{MaxBidiStreamNum: 2, MaxUniStreamNum: 1})
+					Expect(str2.StreamID()).To(Equal(ids.firstOutgoingBidiStream))
+					Expect(ustr.StreamID()).To(Equal(ids.firstOutgoingUniStream))
+					sstr, err := m.GetOrOpenSendStream(ids.firstOutgoingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sstr).To(Equal(str2))
+					str, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream + 4))
+				})
 			}
 		})
 	}
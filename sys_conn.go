@@ -61,6 +61,7 @@ func (c *basicConn) ReadPacket() (*receivedPacket, error) {
 	buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
 	n, addr, err := c.PacketConn.ReadFrom(buffer.Data)
 	if err != nil {
+		buffer.Release()
 		return nil, err
 	}
 	return &receivedPacket{
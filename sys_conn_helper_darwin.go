@@ -7,6 +7,9 @@ import "golang.org/x/sys/unix"
 
 const msgTypeIPTOS = unix.IP_RECVTOS
 
+// The size of the data of an IP_TOS control message used for sending packets.
+const ipv4TOSDataLen = 4
+
 const (
 	ipv4RECVPKTINFO = unix.IP_RECVPKTINFO
 	ipv6RECVPKTINFO = 0x3d
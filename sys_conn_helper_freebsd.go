@@ -9,6 +9,9 @@ const (
 	msgTypeIPTOS = unix.IP_RECVTOS
 )
 
+// The size of the data of an IP_TOS control message used for sending packets.
+const ipv4TOSDataLen = 1
+
 const (
 	ipv4RECVPKTINFO = 0x7
 	ipv6RECVPKTINFO = 0x24
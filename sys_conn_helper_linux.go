@@ -7,6 +7,9 @@ import "golang.org/x/sys/unix"
 
 const msgTypeIPTOS = unix.IP_TOS
 
+// The size of the data of an IP_TOS control message used for sending packets.
+const ipv4TOSDataLen = 1
+
 const (
 	ipv4RECVPKTINFO = unix.IP_PKTINFO
 	ipv6RECVPKTINFO = unix.IPV6_RECVPKTINFO
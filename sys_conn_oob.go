@@ -10,6 +10,7 @@ import (
 	"net"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
@@ -61,7 +62,8 @@ type oobConn struct {
 	readPos uint8
 	// Packets received from the kernel, but not yet returned by ReadPacket().
 	messages []ipv4.Message
-	buffers  [batchSize]*packetBuffer
+	// All messages of a batch are read into the same buffer.
+	buffer *packetBuffer
 }
 
 var _ rawConn = &oobConn{}
@@ -138,27 +140,35 @@ func newConn(c OOBCapablePacketConn) (*oobConn, error) {
 func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 	if len(c.messages) == int(c.readPos) { // all messages read. Read the next batch of messages.
 		c.messages = c.messages[:batchSize]
-		// replace buffers data buffers up to the packet that has been consumed during the last ReadBatch call
-		for i := uint8(0); i < c.readPos; i++ {
-			buffer := getPacketBuffer()
-			buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
-			c.buffers[i] = buffer
-			c.messages[i].Buffers = [][]byte{c.buffers[i].Data}
+		buffer := getPacketBufferWithSize(batchSize * protocol.MaxPacketBufferSize)
+		buffer.Data = buffer.Data[:cap(buffer.Data)]
+		msgSize := len(buffer.Data) / batchSize
+		for i := range c.messages {
+			c.messages[i].Buffers = [][]byte{buffer.Data[i*msgSize : (i+1)*msgSize : (i+1)*msgSize]}
 		}
 		c.readPos = 0
 
 		n, err := c.batchConn.ReadBatch(c.messages, 0)
 		if n == 0 || err != nil {
+			buffer.Release()
+			c.readPos = batchSize
 			return nil, err
 		}
 		c.messages = c.messages[:n]
+		// Every packet holds a reference to the buffer.
+		// It is put back into the pool once all packets of the batch were released.
+		for i := 1; i < n; i++ {
+			buffer.Split()
+		}
+		c.buffer = buffer
 	}
 
 	msg := c.messages[c.readPos]
-	buffer := c.buffers[c.readPos]
+	buffer := c.buffer
 	c.readPos++
 	ctrlMsgs, err := unix.ParseSocketControlMessage(msg.OOB[:msg.NN])
 	if err != nil {
+		buffer.Release()
 		return nil, err
 	}
 	var ecn protocol.ECN
@@ -255,3 +265,54 @@ func (info *packetInfo) OOB() []byte {
 	}
 	return nil
 }
+
+// trafficClassOOB returns the control message that sets the traffic class of packets sent on c to remote.
+// The traffic class consists of the DSCP value and the ECN bits that were set on the socket
+// using the IP_TOS or IPV6_TCLASS socket option.
+// It returns nil if dscp is 0, in which case the packets are sent with the traffic class set on the socket.
+func trafficClassOOB(c OOBCapablePacketConn, dscp uint8, remote net.Addr) []byte {
+	if dscp == 0 {
+		return nil
+	}
+	udpAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	isIPv4 := udpAddr.IP.To4() != nil
+	var ecn protocol.ECN
+	if rawConn, err := c.SyscallConn(); err == nil {
+		rawConn.Control(func(fd uintptr) {
+			var tos int
+			var err error
+			if isIPv4 {
+				tos, err = unix.GetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS)
+			} else {
+				tos, err = unix.GetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_TCLASS)
+			}
+			if err == nil {
+				ecn = protocol.ECN(tos & ecnMask)
+			}
+		})
+	}
+	tos := dscp<<2 | uint8(ecn)
+	if isIPv4 {
+		return appendTrafficClassMsg(nil, unix.IPPROTO_IP, unix.IP_TOS, ipv4TOSDataLen, tos)
+	}
+	return appendTrafficClassMsg(nil, unix.IPPROTO_IPV6, unix.IPV6_TCLASS, 4, tos)
+}
+
+func appendTrafficClassMsg(b []byte, level, typ, dataLen int, tos uint8) []byte {
+	startLen := len(b)
+	b = append(b, make([]byte, unix.CmsgSpace(dataLen))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = int32(level)
+	h.Type = int32(typ)
+	h.SetLen(unix.CmsgLen(dataLen))
+	data := b[startLen+unix.CmsgSpace(0):]
+	if dataLen == 1 {
+		data[0] = tos
+	} else {
+		*(*int32)(unsafe.Pointer(&data[0])) = int32(tos)
+	}
+	return b
+}
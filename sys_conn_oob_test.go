@@ -4,8 +4,10 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/ipv4"
@@ -202,13 +204,127 @@ var _ = Describe("OOB Conn Test", func() {
 		})
 	})
 
+	Context("DSCP marking", func() {
+		// runTOSReceiver returns the traffic class (DSCP and ECN bits) of every packet received.
+		runTOSReceiver := func(network, address string) (*net.UDPConn, <-chan uint8) {
+			addr, err := net.ResolveUDPAddr(network, address)
+			Expect(err).ToNot(HaveOccurred())
+			conn, err := net.ListenUDP(network, addr)
+			Expect(err).ToNot(HaveOccurred())
+			rawConn, err := conn.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawConn.Control(func(fd uintptr) {
+				if network == "udp4" {
+					Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_RECVTOS, 1)).To(Succeed())
+				} else {
+					Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_RECVTCLASS, 1)).To(Succeed())
+				}
+			})).To(Succeed())
+
+			tosChan := make(chan uint8, 10)
+			go func() {
+				defer GinkgoRecover()
+				b := make([]byte, 1500)
+				oob := make([]byte, oobBufferSize)
+				for {
+					_, oobn, _, _, err := conn.ReadMsgUDP(b, oob)
+					if err != nil {
+						return
+					}
+					ctrlMsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+					Expect(err).ToNot(HaveOccurred())
+					for _, ctrlMsg := range ctrlMsgs {
+						if (ctrlMsg.Header.Level == unix.IPPROTO_IP && ctrlMsg.Header.Type == msgTypeIPTOS) ||
+							(ctrlMsg.Header.Level == unix.IPPROTO_IPV6 && ctrlMsg.Header.Type == unix.IPV6_TCLASS) {
+							tosChan <- ctrlMsg.Data[0]
+						}
+					}
+				}
+			}()
+			return conn, tosChan
+		}
+
+		It("marks packets sent on IPv4", func() {
+			conn, tosChan := runTOSReceiver("udp4", "localhost:0")
+			defer conn.Close()
+
+			udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			c := newSendPconn(udpConn, conn.LocalAddr(), 46)
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(tosChan).Should(Receive(Equal(uint8(46 << 2))))
+		})
+
+		It("marks packets sent on IPv6", func() {
+			conn, tosChan := runTOSReceiver("udp6", "[::1]:0")
+			defer conn.Close()
+
+			udpConn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			oobConn, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			c := newSendConn(oobConn, conn.LocalAddr(), nil, 10)
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(tosChan).Should(Receive(Equal(uint8(10 << 2))))
+		})
+
+		It("combines the DSCP with the ECN bits set on the socket", func() {
+			conn, tosChan := runTOSReceiver("udp4", "localhost:0")
+			defer conn.Close()
+
+			udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			rawConn, err := udpConn.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawConn.Control(func(fd uintptr) {
+				Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, int(protocol.ECT0))).To(Succeed())
+			})).To(Succeed())
+			oobConn, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			c := newSendConn(oobConn, conn.LocalAddr(), nil, 46)
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(tosChan).Should(Receive(Equal(uint8(46<<2 | protocol.ECT0))))
+		})
+
+		It("uses the traffic class of the socket if no DSCP is set", func() {
+			conn, tosChan := runTOSReceiver("udp4", "localhost:0")
+			defer conn.Close()
+
+			udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			rawConn, err := udpConn.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rawConn.Control(func(fd uintptr) {
+				Expect(unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, 8<<2|int(protocol.ECT1))).To(Succeed())
+			})).To(Succeed())
+			c := newSendPconn(udpConn, conn.LocalAddr(), 0)
+			Expect(c.Write([]byte("foobar"))).To(Succeed())
+			Eventually(tosChan).Should(Receive(Equal(uint8(8<<2 | protocol.ECT1))))
+		})
+	})
+
 	Context("Batch Reading", func() {
 		var batchConn *MockBatchConn
 
+		expectNoBufferLeaks()
+
 		BeforeEach(func() {
 			batchConn = NewMockBatchConn(mockCtrl)
 		})
 
+		newOOBConn := func() *oobConn {
+			udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			oobConn, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			oobConn.batchConn = batchConn
+			return oobConn
+		}
+
 		It("reads multiple messages in one batch", func() {
 			const numMsgRead = batchSize/2 + 1
 			var counter int
@@ -216,7 +332,7 @@ var _ = Describe("OOB Conn Test", func() {
 				Expect(ms).To(HaveLen(batchSize))
 				for i := 0; i < numMsgRead; i++ {
 					Expect(ms[i].Buffers).To(HaveLen(1))
-					Expect(ms[i].Buffers[0]).To(HaveLen(int(protocol.MaxPacketBufferSize)))
+					Expect(len(ms[i].Buffers[0])).To(BeNumerically(">=", protocol.MaxPacketBufferSize))
 					data := []byte(fmt.Sprintf("message %d", counter))
 					counter++
 					ms[i].Buffers[0] = data
@@ -225,19 +341,55 @@ var _ = Describe("OOB Conn Test", func() {
 				return numMsgRead, nil
 			}).Times(2)
 
-			addr, err := net.ResolveUDPAddr("udp", "localhost:0")
-			Expect(err).ToNot(HaveOccurred())
-			udpConn, err := net.ListenUDP("udp", addr)
-			Expect(err).ToNot(HaveOccurred())
-			oobConn, err := newConn(udpConn)
-			Expect(err).ToNot(HaveOccurred())
-			oobConn.batchConn = batchConn
+			oobConn := newOOBConn()
+			defer oobConn.Close()
+			for i := 0; i < 2*numMsgRead; i++ {
+				p, err := oobConn.ReadPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(p.data)).To(Equal(fmt.Sprintf("message %d", i)))
+				p.buffer.Release()
+			}
+		})
+
+		It("reads all messages of a batch into the same buffer", func() {
+			const numMsgRead = batchSize
+			batchConn.EXPECT().ReadBatch(gomock.Any(), gomock.Any()).DoAndReturn(func(ms []ipv4.Message, flags int) (int, error) {
+				for i := 0; i < numMsgRead; i++ {
+					ms[i].N = copy(ms[i].Buffers[0], fmt.Sprintf("message %d", i))
+				}
+				return numMsgRead, nil
+			})
 
-			for i := 0; i < batchSize+1; i++ {
+			oobConn := newOOBConn()
+			defer oobConn.Close()
+			var packets []*receivedPacket
+			for i := 0; i < numMsgRead; i++ {
 				p, err := oobConn.ReadPacket()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(string(p.data)).To(Equal(fmt.Sprintf("message %d", i)))
+				Expect(cap(p.data)).To(BeNumerically(">=", protocol.MaxPacketBufferSize))
+				if i > 0 {
+					Expect(p.buffer).To(BeIdenticalTo(packets[0].buffer))
+				}
+				packets = append(packets, p)
 			}
+			// The buffer is only put back into the pool once all packets were released.
+			numBuffers := atomic.LoadInt64(&numBuffersInUse)
+			for i, p := range packets {
+				p.buffer.Release()
+				if i < len(packets)-1 {
+					Expect(atomic.LoadInt64(&numBuffersInUse)).To(Equal(numBuffers))
+				}
+			}
+			Expect(atomic.LoadInt64(&numBuffersInUse)).To(Equal(numBuffers - 1))
+		})
+
+		It("releases the buffer when reading fails", func() {
+			batchConn.EXPECT().ReadBatch(gomock.Any(), gomock.Any()).Return(0, errors.New("read failed"))
+			oobConn := newOOBConn()
+			defer oobConn.Close()
+			_, err := oobConn.ReadPacket()
+			Expect(err).To(MatchError("read failed"))
 		})
 	})
 })
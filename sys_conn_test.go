@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"net"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 )
 
 var _ = Describe("Basic Conn Test", func() {
+	expectNoBufferLeaks()
+
 	It("reads a packet", func() {
 		c := NewMockPacketConn(mockCtrl)
 		addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
@@ -29,5 +32,16 @@ var _ = Describe("Basic Conn Test", func() {
 		Expect(p.data).To(Equal([]byte("foobar")))
 		Expect(p.rcvTime).To(BeTemporally("~", time.Now(), scaleDuration(100*time.Millisecond)))
 		Expect(p.remoteAddr).To(Equal(addr))
+		p.buffer.Release()
+	})
+
+	It("releases the buffer when reading fails", func() {
+		c := NewMockPacketConn(mockCtrl)
+		c.EXPECT().ReadFrom(gomock.Any()).Return(0, nil, errors.New("read failed"))
+
+		conn, err := wrapConn(c)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = conn.ReadPacket()
+		Expect(err).To(MatchError("read failed"))
 	})
 })
@@ -38,3 +38,5 @@ func inspectReadBuffer(c net.PacketConn) (int, error) {
 }
 
 func (i *packetInfo) OOB() []byte { return nil }
+
+func trafficClassOOB(OOBCapablePacketConn, uint8, net.Addr) []byte { return nil }
@@ -0,0 +1,338 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// HolePunchConfig configures the packets sent by Transport.HolePunch.
+type HolePunchConfig struct {
+	// NumPackets is the number of punch packets sent to the peer.
+	// If zero, 5 packets are sent.
+	NumPackets int
+	// Interval is the time between two punch packets.
+	// If zero, 100ms is used.
+	Interval time.Duration
+	// Payload is the payload of the punch packets.
+	// If empty, a single byte is sent, which is dropped by the peer's QUIC stack.
+	Payload []byte
+	// PublicAddr is the address of this peer, as it is known to the other peer,
+	// i.e. the address that the other peer passes to HolePunch.
+	// Both peers compare it to the address of the other peer to agree on which connection to keep.
+	// If nil, the local address of the socket is used, which only works if this peer is not behind a NAT.
+	PublicAddr net.Addr
+}
+
+func (c *HolePunchConfig) numPackets() int {
+	if c == nil || c.NumPackets == 0 {
+		return 5
+	}
+	return c.NumPackets
+}
+
+func (c *HolePunchConfig) interval() time.Duration {
+	if c == nil || c.Interval == 0 {
+		return 100 * time.Millisecond
+	}
+	return c.Interval
+}
+
+func (c *HolePunchConfig) payload() []byte {
+	if c == nil || len(c.Payload) == 0 {
+		return []byte{0}
+	}
+	return c.Payload
+}
+
+func (c *HolePunchConfig) publicAddr(conn net.PacketConn) net.Addr {
+	if c == nil || c.PublicAddr == nil {
+		return conn.LocalAddr()
+	}
+	return c.PublicAddr
+}
+
+// A Transport uses a single net.PacketConn for both incoming and outgoing QUIC connections.
+// Sharing the socket is required for NAT hole punching:
+// the NAT binding created by sending packets to the peer is only valid for the socket that sent them.
+type Transport struct {
+	conn   net.PacketConn
+	config *Config
+	ln     EarlyListener
+
+	mutex sync.Mutex
+	// incoming connections from peers that HolePunch is currently called for, by remote address.
+	// The channel is set to nil once an incoming connection was passed to HolePunch.
+	punching map[string]chan<- EarlyConnection
+
+	acceptQueue        []EarlyConnection
+	acceptQueueChanged chan struct{}
+	closed             chan struct{}
+	closeErr           error
+
+	logger utils.Logger
+}
+
+// NewTransport creates a new Transport.
+// It listens for incoming connections on conn, using tlsConf and config.
+// The config is also used for outgoing connections.
+// Closing the Transport does not close conn.
+func NewTransport(conn net.PacketConn, tlsConf *tls.Config, config *Config) (*Transport, error) {
+	ln, err := ListenEarly(conn, tlsConf, config)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transport{
+		conn:               conn,
+		config:             config,
+		ln:                 ln,
+		punching:           make(map[string]chan<- EarlyConnection),
+		acceptQueueChanged: make(chan struct{}, 1),
+		closed:             make(chan struct{}),
+		logger:             utils.DefaultLogger.WithPrefix("transport"),
+	}
+	go t.runAcceptLoop()
+	return t, nil
+}
+
+func (t *Transport) runAcceptLoop() {
+	for {
+		conn, err := t.ln.Accept(context.Background())
+		if err != nil {
+			t.mutex.Lock()
+			t.closeErr = err
+			t.mutex.Unlock()
+			close(t.closed)
+			return
+		}
+		t.mutex.Lock()
+		if c, ok := t.punching[conn.RemoteAddr().String()]; ok {
+			if c == nil {
+				// HolePunch already got an incoming connection from this peer.
+				t.mutex.Unlock()
+				t.logger.Debugf("Closing duplicate connection from %s", conn.RemoteAddr())
+				conn.CloseWithError(0, "duplicate connection")
+				continue
+			}
+			c <- conn
+			t.punching[conn.RemoteAddr().String()] = nil
+			t.mutex.Unlock()
+			continue
+		}
+		if len(t.acceptQueue) >= protocol.MaxAcceptQueueSize {
+			t.mutex.Unlock()
+			t.logger.Debugf("Rejecting connection from %s: accept queue full", conn.RemoteAddr())
+			conn.CloseWithError(0, "accept queue full")
+			continue
+		}
+		t.acceptQueue = append(t.acceptQueue, conn)
+		t.mutex.Unlock()
+		select {
+		case t.acceptQueueChanged <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Accept returns the next incoming connection.
+// Incoming connections from a peer that HolePunch is called for are not returned by Accept.
+func (t *Transport) Accept(ctx context.Context) (EarlyConnection, error) {
+	for {
+		t.mutex.Lock()
+		if len(t.acceptQueue) > 0 {
+			conn := t.acceptQueue[0]
+			t.acceptQueue = t.acceptQueue[1:]
+			t.mutex.Unlock()
+			return conn, nil
+		}
+		t.mutex.Unlock()
+
+		select {
+		case <-t.acceptQueueChanged:
+		case <-t.closed:
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			return nil, t.closeErr
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Dial establishes a new QUIC connection to addr, using the Transport's socket.
+// The host parameter is used for SNI.
+func (t *Transport) Dial(ctx context.Context, addr net.Addr, host string, tlsConf *tls.Config) (Connection, error) {
+	return DialContext(ctx, t.conn, addr, host, tlsConf, t.config)
+}
+
+// HolePunch establishes a connection to a peer behind a NAT.
+// Both peers are expected to call HolePunch at roughly the same time, using each other's candidate address.
+// It sends punch packets to addr, to create a binding for the peer on our NAT (and the peer's NAT, if the peer is behind a NAT).
+// At the same time, it dials addr, and waits for the peer to establish a connection to us.
+//
+// Since both peers dial each other, it is possible that both connections complete the handshake.
+// To make sure that both peers keep the same connection, the connection dialed by the peer with the lower
+// address (see HolePunchConfig.PublicAddr) is kept, and the other connection is closed.
+// Only if that connection fails, the connection dialed by the peer with the higher address is used.
+//
+// HolePunch returns when a connection was established, or when ctx is canceled.
+func (t *Transport) HolePunch(ctx context.Context, addr net.Addr, host string, tlsConf *tls.Config, conf *HolePunchConfig) (Connection, error) {
+	key := addr.String()
+	incoming := make(chan EarlyConnection, 1)
+	t.mutex.Lock()
+	if _, ok := t.punching[key]; ok {
+		t.mutex.Unlock()
+		return nil, fmt.Errorf("quic: already hole punching to %s", addr)
+	}
+	t.punching[key] = incoming
+	// The peer might have started hole punching earlier, and its connections might already be queued.
+	var duplicates []EarlyConnection
+	acceptQueue := t.acceptQueue[:0]
+	for _, conn := range t.acceptQueue {
+		switch {
+		case conn.RemoteAddr().String() != key:
+			acceptQueue = append(acceptQueue, conn)
+		case t.punching[key] != nil:
+			incoming <- conn
+			t.punching[key] = nil
+		default:
+			duplicates = append(duplicates, conn)
+		}
+	}
+	t.acceptQueue = acceptQueue
+	t.mutex.Unlock()
+	for _, conn := range duplicates {
+		conn.CloseWithError(0, "duplicate connection")
+	}
+	defer func() {
+		t.mutex.Lock()
+		delete(t.punching, key)
+		t.mutex.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Make sure that at least one punch packet is sent before the handshake starts.
+	payload := conf.payload()
+	if _, err := t.conn.WriteTo(payload, addr); err != nil {
+		return nil, err
+	}
+	go t.sendPunchPackets(ctx, addr, payload, conf.numPackets()-1, conf.interval())
+
+	results := make(chan holePunchResult, 2)
+	go func() {
+		conn, err := t.Dial(ctx, addr, host, tlsConf)
+		results <- holePunchResult{conn: conn, err: err, outgoing: true}
+	}()
+	go func() {
+		select {
+		case conn := <-incoming:
+			select {
+			case <-conn.HandshakeComplete().Done():
+				results <- holePunchResult{conn: conn}
+			case <-conn.Context().Done():
+				results <- holePunchResult{err: fmt.Errorf("quic: incoming connection from %s failed", addr)}
+			case <-ctx.Done():
+				conn.CloseWithError(0, "")
+				results <- holePunchResult{err: ctx.Err()}
+			}
+		case <-ctx.Done():
+			results <- holePunchResult{err: ctx.Err()}
+		}
+	}()
+
+	// Both peers keep the connection dialed by the peer with the lower address.
+	keepOutgoing := addrLess(conf.publicAddr(t.conn), addr)
+	keep := <-results
+	var other *holePunchResult
+	if keep.outgoing != keepOutgoing {
+		// Hold on to the other connection, in case the connection we're supposed to keep fails.
+		o := keep
+		other = &o
+		keep = <-results
+	}
+	if keep.err == nil {
+		if other == nil {
+			// Abort the other connection attempt.
+			cancel()
+			go closeDuplicateConn(results)
+		} else if other.err == nil {
+			other.conn.CloseWithError(0, "duplicate connection")
+		}
+		return keep.conn, nil
+	}
+	// The connection we're supposed to keep failed. Fall back to the other one.
+	if other == nil {
+		o := <-results
+		other = &o
+	}
+	if other.err == nil {
+		return other.conn, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, keep.err
+}
+
+type holePunchResult struct {
+	conn     Connection
+	err      error
+	outgoing bool
+}
+
+func closeDuplicateConn(results <-chan holePunchResult) {
+	if r := <-results; r.err == nil {
+		r.conn.CloseWithError(0, "duplicate connection")
+	}
+}
+
+// addrLess reports whether a sorts before b.
+// UDP addresses are compared by IP and port, other addresses by their string representation.
+func addrLess(a, b net.Addr) bool {
+	ua, ok1 := a.(*net.UDPAddr)
+	ub, ok2 := b.(*net.UDPAddr)
+	if !ok1 || !ok2 {
+		return a.String() < b.String()
+	}
+	if c := bytes.Compare(ua.IP.To16(), ub.IP.To16()); c != 0 {
+		return c < 0
+	}
+	return ua.Port < ub.Port
+}
+
+func (t *Transport) sendPunchPackets(ctx context.Context, addr net.Addr, payload []byte, num int, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 0; i < num; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := t.conn.WriteTo(payload, addr); err != nil {
+			t.logger.Debugf("Sending punch packet to %s failed: %s", addr, err)
+			return
+		}
+	}
+}
+
+// Addr returns the local address of the Transport's socket.
+func (t *Transport) Addr() net.Addr {
+	return t.ln.Addr()
+}
+
+// Close closes the listener, and all incoming connections.
+// Outgoing connections are not closed.
+func (t *Transport) Close() error {
+	return t.ln.Close()
+}
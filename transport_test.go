@@ -0,0 +1,241 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport", func() {
+	var closers []func()
+
+	AfterEach(func() {
+		// close in reverse order, such that the transports are closed before their packet conns
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+		closers = nil
+		Eventually(areConnsMultiplexed).Should(BeFalse())
+	})
+
+	newUDPConn := func() *net.UDPConn {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		closers = append(closers, func() { conn.Close() })
+		return conn
+	}
+
+	newTransport := func() *Transport {
+		conn := newUDPConn()
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"transport"}
+		tr, err := NewTransport(conn, tlsConf, &Config{HandshakeIdleTimeout: time.Second})
+		Expect(err).ToNot(HaveOccurred())
+		closers = append(closers, func() { tr.Close() })
+		return tr
+	}
+
+	getClientTLSConfig := func() *tls.Config {
+		return &tls.Config{
+			RootCAs:    testdata.GetRootCA(),
+			NextProtos: []string{"transport"},
+		}
+	}
+
+	getPerspective := func(conn Connection) protocol.Perspective {
+		return conn.(*connection).perspective
+	}
+
+	It("accepts connections", func() {
+		tr := newTransport()
+		conn := newUDPConn()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		peer, err := DialContext(ctx, conn, tr.Addr(), "localhost", getClientTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer peer.CloseWithError(0, "")
+		c, err := tr.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.RemoteAddr().String()).To(Equal(conn.LocalAddr().String()))
+	})
+
+	It("keeps the connection dialed by the peer with the lower address", func() {
+		tr1 := newTransport()
+		tr2 := newTransport()
+		if !addrLess(tr1.Addr(), tr2.Addr()) {
+			tr1, tr2 = tr2, tr1
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		connChan := make(chan Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := tr2.HolePunch(ctx, tr1.Addr(), "localhost", getClientTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			connChan <- conn
+		}()
+		c1, err := tr1.HolePunch(ctx, tr2.Addr(), "localhost", getClientTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		var c2 Connection
+		Eventually(connChan).Should(Receive(&c2))
+		Expect(getPerspective(c1)).To(Equal(protocol.PerspectiveClient))
+		Expect(getPerspective(c2)).To(Equal(protocol.PerspectiveServer))
+		m1, err := c1.ExportKeyingMaterial("test", nil, 16)
+		Expect(err).ToNot(HaveOccurred())
+		m2, err := c2.ExportKeyingMaterial("test", nil, 16)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m1).To(Equal(m2))
+	})
+
+	It("uses the connection dialed by the peer with the higher address, if the other connection fails", func() {
+		tr := newTransport()
+		// The peer doesn't accept incoming connections, so dialing it fails.
+		conn := newUDPConn()
+		publicAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+		Expect(addrLess(publicAddr, conn.LocalAddr())).To(BeTrue())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		connChan := make(chan Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			c, err := tr.HolePunch(ctx, conn.LocalAddr(), "localhost", getClientTLSConfig(), &HolePunchConfig{PublicAddr: publicAddr})
+			Expect(err).ToNot(HaveOccurred())
+			connChan <- c
+		}()
+		peer, err := DialContext(ctx, conn, tr.Addr(), "localhost", getClientTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer peer.CloseWithError(0, "")
+		// The connection is only returned once dialing failed.
+		Consistently(connChan, 500*time.Millisecond).ShouldNot(Receive())
+		var c Connection
+		Eventually(connChan, 2*time.Second).Should(Receive(&c))
+		Expect(getPerspective(c)).To(Equal(protocol.PerspectiveServer))
+		Expect(c.RemoteAddr().String()).To(Equal(conn.LocalAddr().String()))
+	})
+
+	It("errors when already hole punching to the same address", func() {
+		tr := newTransport()
+		conn := newUDPConn()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := tr.HolePunch(ctx, conn.LocalAddr(), "localhost", getClientTLSConfig(), nil)
+			errChan <- err
+		}()
+		Eventually(func() int {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			return len(tr.punching)
+		}).Should(Equal(1))
+		_, err := tr.HolePunch(context.Background(), conn.LocalAddr(), "localhost", getClientTLSConfig(), nil)
+		Expect(err).To(MatchError("quic: already hole punching to " + conn.LocalAddr().String()))
+		cancel()
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+	})
+
+	It("closes additional incoming connections from the peer", func() {
+		tr := newTransport()
+		conn := newUDPConn()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		connChan := make(chan Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			c, err := tr.HolePunch(ctx, conn.LocalAddr(), "localhost", getClientTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			connChan <- c
+		}()
+		Eventually(func() int {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			return len(tr.punching)
+		}).Should(Equal(1))
+
+		// The peer dials twice, using the same socket.
+		type dialResult struct {
+			conn Connection
+			err  error
+		}
+		dialResults := make(chan dialResult, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				conn, err := DialContext(ctx, conn, tr.Addr(), "localhost", getClientTLSConfig(), nil)
+				dialResults <- dialResult{conn: conn, err: err}
+			}()
+		}
+		var peers []dialResult
+		for i := 0; i < 2; i++ {
+			var r dialResult
+			Eventually(dialResults, 2*time.Second).Should(Receive(&r))
+			if r.err == nil {
+				defer r.conn.CloseWithError(0, "")
+			}
+			peers = append(peers, r)
+		}
+		var c Connection
+		Eventually(connChan, 2*time.Second).Should(Receive(&c))
+		Expect(c.RemoteAddr().String()).To(Equal(conn.LocalAddr().String()))
+		// Only one of the peer's connections is kept.
+		Eventually(func() int {
+			var numClosed int
+			for _, r := range peers {
+				if r.err != nil {
+					numClosed++
+					continue
+				}
+				select {
+				case <-r.conn.Context().Done():
+					numClosed++
+				default:
+				}
+			}
+			return numClosed
+		}).Should(Equal(1))
+		tr.mutex.Lock()
+		defer tr.mutex.Unlock()
+		Expect(tr.acceptQueue).To(BeEmpty())
+	})
+
+	It("stops hole punching when the context is canceled", func() {
+		tr := newTransport()
+		conn := newUDPConn()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err := tr.HolePunch(ctx, conn.LocalAddr(), "localhost", getClientTLSConfig(), nil)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("orders addresses", func() {
+		Expect(addrLess(
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 1000},
+		)).To(BeTrue())
+		Expect(addrLess(
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1000},
+		)).To(BeFalse())
+		// The same IPv4 address might be represented by 4 or 16 bytes.
+		Expect(addrLess(
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 1000},
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To16(), Port: 1234},
+		)).To(BeTrue())
+		Expect(addrLess(
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To16(), Port: 1234},
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 1000},
+		)).To(BeFalse())
+		Expect(addrLess(&net.IPAddr{IP: net.IPv4(1, 1, 1, 1)}, &net.IPAddr{IP: net.IPv4(2, 2, 2, 2)})).To(BeTrue())
+	})
+})